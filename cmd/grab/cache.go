@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/epilande/codegrab/internal/config"
+	"github.com/epilande/codegrab/internal/git"
+)
+
+const cacheUsageText = `Usage:
+  grab cache prune [options]
+
+  Remove cached clones of remote repositories.
+
+  Options:
+    --older-than <age>       Only remove clones not used within this age (e.g., "30d", "12h").
+                             Removes all cached clones by default.`
+
+// newCloneCache builds the clone cache from the config file settings
+func newCloneCache(cfg config.CloneCacheConfig) (*git.CloneCache, error) {
+	dir := cfg.Dir
+	if dir == "" {
+		defaultDir, err := git.DefaultCloneCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = defaultDir
+	}
+
+	cache := git.NewCloneCache(dir)
+	if cfg.MaxAgeDays > 0 {
+		cache.MaxAge = time.Duration(cfg.MaxAgeDays) * 24 * time.Hour
+	}
+	if cfg.MaxEntries > 0 {
+		cache.MaxEntries = cfg.MaxEntries
+	}
+	return cache, nil
+}
+
+// parseAge parses a duration that may also be given in days (e.g. "30d")
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	return age, nil
+}
+
+// runCacheCommand implements the "grab cache" subcommand
+func runCacheCommand(args []string) error {
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+		fmt.Fprintln(os.Stderr, cacheUsageText)
+		return flag.ErrHelp
+	}
+	if len(args) == 0 || args[0] != "prune" {
+		return fmt.Errorf("unknown cache command\n\n%s", cacheUsageText)
+	}
+
+	fs := flag.NewFlagSet("cache prune", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, cacheUsageText) }
+	olderThan := fs.String("older-than", "", "Only remove clones not used within this age")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var maxAge time.Duration
+	if *olderThan != "" {
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			return err
+		}
+		if age == 0 {
+			return fmt.Errorf("--older-than must be greater than zero")
+		}
+		maxAge = age
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	cache, err := newCloneCache(cfg.CloneCache)
+	if err != nil {
+		return err
+	}
+
+	removed, err := cache.Prune(maxAge)
+	for _, entry := range removed {
+		label := entry.URL
+		if label == "" {
+			label = entry.Path
+		} else if entry.Ref != "" {
+			label += "@" + entry.Ref
+		}
+		fmt.Printf("Removed %s\n", label)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🧹 Pruned %d cached clone(s) from %s\n", len(removed), cache.Dir)
+	return nil
+}
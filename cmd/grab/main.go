@@ -1,21 +1,31 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
+	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/epilande/codegrab/internal/config"
 	"github.com/epilande/codegrab/internal/dependencies"
 	"github.com/epilande/codegrab/internal/filesystem"
 	"github.com/epilande/codegrab/internal/generator"
 	"github.com/epilande/codegrab/internal/generator/formats"
 	"github.com/epilande/codegrab/internal/git"
+	"github.com/epilande/codegrab/internal/keymap"
 	"github.com/epilande/codegrab/internal/model"
+	"github.com/epilande/codegrab/internal/project"
+	"github.com/epilande/codegrab/internal/symbols"
 	"github.com/epilande/codegrab/internal/ui"
 	"github.com/epilande/codegrab/internal/ui/themes"
 	"github.com/epilande/codegrab/internal/utils"
@@ -32,7 +42,51 @@ func (s *stringSliceFlag) Set(value string) error {
 	return nil
 }
 
+// nonInteractiveOptions holds the settings used by runNonInteractive
+type nonInteractiveOptions struct {
+	filterMgr     *filesystem.FilterManager
+	statusOut     io.Writer
+	fileList      []string
+	fromFileList  bool
+	lineRanges    map[string][]symbols.LineRange
+	rootPath      string
+	grepPattern   string
+	outputPath    string
+	formatName    string
+	maxDepth      int
+	historyDepth  int
+	maxFileSize   int64
+	useTempFile   bool
+	useStdout     bool
+	withMetadata  bool
+	lineNumbers   bool
+	historyDiff   bool
+	skipRedaction bool
+	resolveDeps   bool
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		var run func(args []string) error
+		switch os.Args[1] {
+		case "cache":
+			run = runCacheCommand
+		case "stats":
+			run = runStatsCommand
+		case "mcp":
+			run = runMCPCommand
+		case "serve":
+			run = runServeCommand
+		}
+		if run != nil {
+			// Asking a subcommand for help prints its usage, which is not an error
+			if err := run(os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		}
+	}
+
 	themes.Initialize()
 
 	var err error
@@ -42,6 +96,7 @@ func main() {
 	var nonInteractive bool
 	var outputPath string
 	var useTempFile bool
+	var useStdout bool
 	var themeName string
 	var formatName string
 	var skipRedaction bool
@@ -50,6 +105,18 @@ func main() {
 	var maxFileSizeStr string
 	var showIcons bool
 	var showTokenCount bool
+	var noMouse bool
+	var sortName string
+	var filesFrom string
+	var grepPattern string
+	var withMetadata bool
+	var lineNumbers bool
+	var historyDepth int
+	var historyDiff bool
+	var cloneRef string
+	var cloneSubdir string
+	var forceRemote bool
+	var useCloneCache bool
 
 	flag.BoolVar(&showHelp, "help", false, "Display help information")
 	flag.BoolVar(&showHelp, "h", false, "Display help information (shorthand)")
@@ -66,6 +133,8 @@ func main() {
 	flag.StringVar(&outputPath, "output", "", "Output file path (default: current directory)")
 	flag.StringVar(&outputPath, "o", "", "Output file path (shorthand)")
 
+	flag.BoolVar(&useStdout, "stdout", false, "Write the output to stdout (same as -o -)")
+
 	flag.BoolVar(&useTempFile, "temp", false, "Use system temporary directory for output file")
 	flag.BoolVar(&useTempFile, "t", false, "Use system temporary directory for output file (shorthand)")
 
@@ -78,6 +147,15 @@ func main() {
 	flag.StringVar(&formatName, "format", "markdown", formatUsage)
 	flag.StringVar(&formatName, "f", "markdown", formatUsage+" (shorthand)")
 
+	flag.BoolVar(&forceRemote, "remote", false, "Treat the argument as a Git remote and clone it, even if it is not recognized as a Git URL")
+	flag.BoolVar(&useCloneCache, "cache", false, "Reuse and update a cached clone of Git URLs between runs")
+	flag.StringVar(&cloneRef, "ref", "", "Branch, tag or commit to check out when cloning a Git URL")
+	flag.StringVar(&cloneSubdir, "subdir", "", "Only check out and grab this subdirectory when cloning a Git URL")
+
+	flag.StringVar(&filesFrom, "files-from", "", "Read the list of files to select from a file, or from stdin with \"-\" (newline or NUL separated)")
+
+	flag.StringVar(&grepPattern, "grep", "", "Only select files whose contents match a regular expression (matched literally if invalid)")
+
 	flag.BoolVar(&resolveDeps, "deps", false, "Automatically include direct dependencies (Go, TS/JS, Python)")
 
 	flag.IntVar(&maxDepth, "max-depth", 1, "Maximum depth for dependency resolution (-1 for unlimited)")
@@ -88,16 +166,33 @@ func main() {
 	maxFileSizeUsage := "Maximum file size to include (e.g., 50kb, 2MB). No limit by default."
 	flag.StringVar(&maxFileSizeStr, "max-file-size", "", maxFileSizeUsage)
 
+	flag.BoolVar(&withMetadata, "metadata", false, "Include a metadata header (git revision, timestamp, filters) in the output")
+
+	flag.BoolVar(&lineNumbers, "line-numbers", false, "Prefix each line of file content with its line number")
+
+	flag.IntVar(&historyDepth, "history", 0, "Include the last N commits that touched each file (git repositories only)")
+	flag.BoolVar(&historyDiff, "history-diff", false, "Include the diff of each commit in the file history (requires --history)")
+
 	flag.BoolVar(&showIcons, "icons", false, "Display Nerd Font icons")
 
 	flag.BoolVar(&showTokenCount, "show-tokens", false, "Show the number of tokens for each file")
 
+	sortUsage := fmt.Sprintf("Sort order of the file tree (available: %s)", strings.Join(model.SortModeNames(), ", "))
+	flag.StringVar(&sortName, "sort", "name", sortUsage)
+
+	flag.BoolVar(&noMouse, "no-mouse", false, "Disable mouse support, keeping the native text selection of the terminal")
+
 	flag.Parse()
 
 	if showHelp {
+		// Show the keys as configured, falling back to the defaults if the config is invalid
+		keys := keymap.Default()
+		if cfg, err := config.Load(); err == nil {
+			keys, _ = keymap.New(cfg.Keys)
+		}
 		fmt.Println(ui.UsageText)
 		fmt.Println()
-		fmt.Println(ui.HelpText)
+		fmt.Println(ui.HelpText(keys))
 		os.Exit(0)
 	}
 
@@ -112,6 +207,36 @@ func main() {
 		}
 	}
 
+	sortMode, err := model.ParseSortMode(sortName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v, sorting by name\n", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+	git.AddForgeHosts(cfg.ForgeHosts...)
+	if !isFlagSet("cache") {
+		useCloneCache = cfg.CloneCache.Enabled
+	}
+
+	if outputPath == generator.StdoutPath {
+		useStdout = true
+	}
+	if useStdout && useTempFile {
+		log.Fatalf("Error: --stdout cannot be combined with --temp")
+	}
+	if historyDepth < 0 {
+		log.Fatalf("Error: --history must not be negative")
+	}
+
+	// Progress and status messages go to stderr when stdout carries the output
+	var statusOut io.Writer = os.Stdout
+	if useStdout {
+		statusOut = os.Stderr
+	}
+
 	if maxDepth < 0 {
 		maxDepth = math.MaxInt
 	}
@@ -133,11 +258,18 @@ func main() {
 	if flag.NArg() > 0 {
 		arg := flag.Arg(0)
 
-		// Check if the argument is a Git URL
-		if git.IsGitURL(arg) {
-			fmt.Printf("🔄 Cloning repository: %s\n", arg)
+		// Check if the argument is a Git URL or a local bare repository
+		if forceRemote || git.IsGitURL(arg) || git.IsBareRepository(arg) {
+			fmt.Fprintf(statusOut, "🔄 Cloning repository: %s\n", arg)
 
-			clonedPath, cleanupFunc, err := git.CloneRepository(arg)
+			cloneOpts := git.CloneOptions{Ref: cloneRef, Subdir: cloneSubdir, ForceRemote: forceRemote}
+			if useCloneCache {
+				cloneOpts.Cache, err = newCloneCache(cfg.CloneCache)
+				if err != nil {
+					log.Fatalf("Error setting up clone cache: %v", err)
+				}
+			}
+			clonedPath, cleanupFunc, err := git.CloneRepository(arg, cloneOpts)
 			if err != nil {
 				log.Fatalf("Error cloning repository: %v", err)
 			}
@@ -146,12 +278,16 @@ func main() {
 			cleanup = cleanupFunc
 			isGitRepo = true
 
-			fmt.Printf("✅ Repository cloned to: %s\n", root)
+			fmt.Fprintf(statusOut, "✅ Repository cloned to: %s\n", root)
 		} else {
 			root = arg
 		}
 	}
 
+	if !isGitRepo && (cloneRef != "" || cloneSubdir != "") {
+		log.Fatalf("Error: --ref and --subdir can only be used with a Git URL or --remote")
+	}
+
 	// Set up cleanup for Git repositories
 	if cleanup != nil {
 		defer cleanup()
@@ -177,6 +313,16 @@ func main() {
 		log.Fatalf("Error: %q is not a directory\n", root)
 	}
 
+	var fileList []string
+	var lineRanges map[string][]symbols.LineRange
+	if filesFrom != "" {
+		fileList, err = filesystem.ReadFileListFrom(filesFrom)
+		if err != nil {
+			log.Fatalf("Error reading file list: %v", err)
+		}
+		fileList, lineRanges = parseFileList(root, fileList)
+	}
+
 	filterMgr := filesystem.NewFilterManager()
 
 	for _, pattern := range globPatterns {
@@ -187,13 +333,52 @@ func main() {
 	}
 
 	if nonInteractive {
-		runNonInteractive(root, filterMgr, outputPath, useTempFile, formatName, skipRedaction, resolveDeps, maxDepth, maxFileSize)
+		runNonInteractive(nonInteractiveOptions{
+			filterMgr:     filterMgr,
+			statusOut:     statusOut,
+			fileList:      fileList,
+			fromFileList:  filesFrom != "",
+			lineRanges:    lineRanges,
+			rootPath:      root,
+			grepPattern:   grepPattern,
+			outputPath:    outputPath,
+			formatName:    formatName,
+			maxDepth:      maxDepth,
+			historyDepth:  historyDepth,
+			maxFileSize:   maxFileSize,
+			useTempFile:   useTempFile,
+			useStdout:     useStdout,
+			withMetadata:  withMetadata,
+			lineNumbers:   lineNumbers,
+			historyDiff:   historyDiff,
+			skipRedaction: skipRedaction,
+			resolveDeps:   resolveDeps,
+		})
 	} else {
+		// Conflicting keys still leave a usable keymap, as with --help, so only a
+		// misnamed action is fatal. Conflicts are shown in the footer, since the
+		// alternate screen would hide a warning printed here.
+		keys, err := keymap.New(cfg.Keys)
+		if errors.Is(err, keymap.ErrUnknownAction) {
+			log.Fatalf("Error in key bindings of the config file: %v", err)
+		}
+		var startupWarning string
+		if err != nil {
+			startupWarning = "Key bindings of the config file: " + strings.ReplaceAll(err.Error(), "\n", "; ")
+		}
+
 		config := model.Config{
+			Keymap:         keys,
 			RootPath:       root,
+			StartupWarning: startupWarning,
 			FilterMgr:      filterMgr,
 			OutputPath:     outputPath,
 			UseTempFile:    useTempFile,
+			UseStdout:      useStdout,
+			WithMetadata:   withMetadata,
+			LineNumbers:    lineNumbers,
+			HistoryDepth:   historyDepth,
+			HistoryDiff:    historyDiff,
 			Format:         formatName,
 			SkipRedaction:  skipRedaction,
 			ResolveDeps:    resolveDeps,
@@ -201,10 +386,25 @@ func main() {
 			ShowTokenCount: showTokenCount,
 			MaxDepth:       maxDepth,
 			MaxFileSize:    maxFileSize,
+			SortMode:       sortMode,
+			InitialFiles:   fileList,
+			LineRanges:     lineRanges,
 		}
 
 		m := model.NewModel(config)
-		p := tea.NewProgram(m, tea.WithAltScreen())
+		programOpts := []tea.ProgramOption{tea.WithAltScreen()}
+		if !noMouse {
+			programOpts = append(programOpts, tea.WithMouseCellMotion())
+		}
+		if useStdout {
+			// Keep stdout clean for the generated output
+			programOpts = append(programOpts, tea.WithOutput(os.Stderr))
+		}
+		if filesFrom == "-" {
+			// Stdin carried the file list, so read keys from the terminal instead
+			programOpts = append(programOpts, tea.WithInputTTY())
+		}
+		p := tea.NewProgram(m, programOpts...)
 
 		if _, err := p.Run(); err != nil {
 			log.Fatalf("Error running program: %v\n", err)
@@ -213,109 +413,170 @@ func main() {
 }
 
 // runNonInteractive processes files and generates output without user interaction
-func runNonInteractive(rootPath string, filterMgr *filesystem.FilterManager, outputPath string, useTempFile bool, formatName string, skipRedaction bool, resolveDeps bool, maxDepth int, maxFileSize int64) {
+func runNonInteractive(opts nonInteractiveOptions) {
+	rootPath := opts.rootPath
+	maxDepth := opts.maxDepth
+	maxFileSize := opts.maxFileSize
+	statusOut := opts.statusOut
+
+	// Interrupting cancels the walk, the content search, the dependency resolution and
+	// the generation, removing a partially written output file
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	gitIgnoreMgr, err := filesystem.NewGitIgnoreManager(rootPath)
 	if err != nil {
 		log.Fatalf("Error reading .gitignore: %v\n", err)
 	}
 
-	files, err := filesystem.WalkDirectory(rootPath, gitIgnoreMgr, filterMgr, true, false, maxFileSize)
-	if err != nil {
-		log.Fatalf("Error walking directory: %v\n", err)
+	// Show the progress on stderr, which stays clean for the output with --stdout
+	progress := &progressPrinter{out: os.Stderr}
+	var onWalkProgress func(found int)
+	if isTerminal(os.Stderr) {
+		onWalkProgress = progress.walked
 	}
 
-	// Automatically select all non-directory files
 	selectedFiles := make(map[string]bool)
-	for _, file := range files {
-		if !file.IsDir {
-			selectedFiles[file.Path] = true
+	if opts.fromFileList {
+		// Select only the explicitly listed files, never falling back to the whole
+		// directory when the list is empty
+		accepted, skipped, err := filesystem.ValidateFileList(ctx, rootPath, opts.fileList, gitIgnoreMgr, true, false, maxFileSize)
+		if err != nil {
+			log.Fatalf("Error reading the file list: %v\n", err)
 		}
-	}
-
-	if resolveDeps {
-		fmt.Println("ℹ️ Resolving dependencies...")
-		projectModuleName := dependencies.ReadGoModFile(rootPath)
-
-		queue := make([]model.QueuedDep, 0, len(selectedFiles))
-		processed := make(map[string]bool)
-
-		for path := range selectedFiles {
-			queue = append(queue, model.QueuedDep{Path: path, Depth: 0})
-			processed[path] = true
+		for _, s := range skipped {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %s\n", s.Path, s.Reason)
+		}
+		if len(accepted) == 0 {
+			log.Fatalf("Error: the file list selects no files\n")
+		}
+		for _, path := range accepted {
+			selectedFiles[path] = true
+		}
+	} else {
+		files, err := filesystem.WalkDirectoryWithProgress(ctx, rootPath, gitIgnoreMgr, opts.filterMgr, true, false, maxFileSize, onWalkProgress)
+		progress.clear()
+		if err != nil {
+			log.Fatalf("Error walking directory: %v\n", err)
 		}
 
-		i := 0
-		for i < len(queue) {
-			currentItem := queue[i]
-			i++
-
-			filePath := currentItem.Path
-			currentDepth := currentItem.Depth
-
-			if currentDepth >= maxDepth {
-				continue
-			}
-
-			resolver := dependencies.GetResolver(filePath)
-			if resolver == nil {
-				continue
+		// Automatically select all non-directory files
+		for _, file := range files {
+			if !file.IsDir {
+				selectedFiles[file.Path] = true
 			}
+		}
+	}
 
-			content, err := os.ReadFile(filepath.Join(rootPath, filePath))
+	if opts.grepPattern != "" {
+		re, err := filesystem.CompileGrepPattern(opts.grepPattern, false)
+		if err != nil {
+			re, err = filesystem.CompileGrepPattern(opts.grepPattern, true)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: cannot read %s for dep resolution: %v\n", filePath, err)
-				continue
+				log.Fatalf("Error: %v\n", err)
 			}
+		}
 
-			deps, err := resolver.Resolve(content, filePath, rootPath, projectModuleName)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: cannot resolve deps for %s: %v\n", filePath, err)
-				continue
-			}
+		paths := make([]string, 0, len(selectedFiles))
+		for path := range selectedFiles {
+			paths = append(paths, path)
+		}
 
-			for _, depPath := range deps {
-				depPath = filepath.ToSlash(filepath.Clean(depPath))
+		matches, err := filesystem.GrepFiles(ctx, rootPath, paths, re)
+		if err != nil {
+			log.Fatalf("Error searching file contents: %v\n", err)
+		}
+		selectedFiles = make(map[string]bool)
+		for _, match := range matches {
+			selectedFiles[match.Path] = true
+		}
+		fmt.Fprintf(statusOut, "ℹ️ Found %d files matching %q\n", len(selectedFiles), opts.grepPattern)
+	}
 
-				depFullPath := filepath.Join(rootPath, depPath)
-				depInfo, statErr := os.Stat(depFullPath)
-				if statErr != nil || depInfo.IsDir() ||
-					(gitIgnoreMgr.IsIgnored(depFullPath)) ||
-					(utils.IsHiddenPath(depPath)) ||
-					(depInfo.Size() > maxFileSize) {
-					continue
-				}
+	if opts.resolveDeps {
+		fmt.Fprintln(statusOut, "ℹ️ Resolving dependencies...")
+		projectModuleName := dependencies.ReadGoModFile(rootPath)
 
-				if !processed[depPath] {
-					fmt.Printf("Adding dependency: %s (depth %d, required by %s)\n", depPath, currentDepth+1, filePath)
-					selectedFiles[depPath] = true
-					processed[depPath] = true
+		resolver := dependencies.NewConcurrentResolver(rootPath, projectModuleName)
 
-					queue = append(queue, model.QueuedDep{Path: depPath, Depth: currentDepth + 1})
-				}
-			}
+		paths := make([]string, 0, len(selectedFiles))
+		for path := range selectedFiles {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		rules := project.DependencyRules{
+			GitIgnore:    gitIgnoreMgr,
+			MaxFileSize:  maxFileSize,
+			UseGitIgnore: true,
+		}
+		deps, err := project.ResolveDependencies(ctx, resolver, rootPath, paths, maxDepth, rules, func(path string, err error) {
+			fmt.Fprintf(os.Stderr, "Warning: cannot resolve deps for %s: %v\n", path, err)
+		})
+		if err != nil {
+			log.Fatalf("Error resolving dependencies: %v\n", err)
+		}
+		for _, dep := range deps {
+			fmt.Fprintf(statusOut, "Adding dependency: %s (depth %d, required by %s)\n", dep.Path, dep.Depth, dep.RequiredBy)
+			selectedFiles[dep.Path] = true
 		}
-		fmt.Printf("ℹ️ Dependency resolution complete. Total files selected: %d\n", len(selectedFiles))
+		fmt.Fprintf(statusOut, "ℹ️ Dependency resolution complete. Total files selected: %d\n", len(selectedFiles))
 	}
 
-	gen := generator.NewGenerator(rootPath, gitIgnoreMgr, filterMgr, outputPath, useTempFile)
-	format := formats.GetFormat(formatName)
-	gen.SetFormat(format)
-	gen.SetRedactionMode(!skipRedaction)
+	gen, err := generator.NewGenerator(rootPath, gitIgnoreMgr, opts.filterMgr, opts.outputPath, opts.useTempFile)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	gen.SetFormatFunc(formats.Constructor(opts.formatName))
+	gen.SetRedactionMode(!opts.skipRedaction)
+	gen.UseStdout = opts.useStdout
+	gen.IncludeMetadata = opts.withMetadata
+	gen.LineNumbers = opts.lineNumbers
+	gen.HistoryDepth = opts.historyDepth
+	gen.HistoryDiff = opts.historyDiff
 
 	gen.SelectedFiles = selectedFiles
+	gen.LineRanges = opts.lineRanges
+
+	if isTerminal(os.Stderr) {
+		gen.OnProgress = progress.update
+	}
 
-	outputFilePath, tokenCount, secretCount, err := gen.Generate()
+	outputFilePath, tokenCount, secretCount, err := gen.Generate(ctx)
+	progress.clear()
 	if err != nil {
 		log.Fatalf("Error generating output: %v\n", err)
 	}
 
-	fmt.Printf("✅ Generated %s (%d tokens)\n", outputFilePath, tokenCount)
+	fmt.Fprintf(statusOut, "✅ Generated %s (%d tokens)\n", outputFilePath, tokenCount)
 
-	if secretCount > 0 && skipRedaction {
+	if secretCount > 0 && opts.skipRedaction {
 		fmt.Fprintf(os.Stderr, "⚠️ WARNING: %d secrets detected in the output and redaction was skipped!\n", secretCount)
-	} else if secretCount > 0 && !skipRedaction {
+	} else if secretCount > 0 && !opts.skipRedaction {
 		fmt.Fprintf(os.Stderr, "🛡️ INFO: %d secrets detected and redacted in the output.\n", secretCount)
 	} else {
-		fmt.Println("🛡️ No secrets detected in the output.")
+		fmt.Fprintln(statusOut, "🛡️ No secrets detected in the output.")
+	}
+}
+
+// isFlagSet reports whether the named flag was passed on the command line
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// parseFileList splits the entries of a file list into the paths to select and the
+// line ranges that partial entries (path:120-180, path#FuncName) narrow them to,
+// warning about the entries that cannot be resolved
+func parseFileList(root string, entries []string) ([]string, map[string][]symbols.LineRange) {
+	paths, lineRanges, skipped := symbols.ResolveEntries(root, entries)
+	for _, s := range skipped {
+		fmt.Fprintf(os.Stderr, "Warning: skipping %s: %s\n", s.Entry, s.Reason)
 	}
+	return paths, lineRanges
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/epilande/codegrab/internal/mcp"
+	"github.com/epilande/codegrab/internal/utils"
+)
+
+const mcpUsageText = `Usage:
+  grab mcp [options] [directory]
+
+  Run a Model Context Protocol server over stdio, exposing the project as tools:
+  list_files, read_file, resolve_dependencies, scan_secrets and generate_bundle.
+  Secrets are redacted from everything the tools return.
+
+  Options:
+    --max-file-size <size>   Maximum file size the tools include (e.g., "50kb", "2MB"). No limit by default.
+    -S, --skip-redaction     Return file contents without redacting secrets (WARNING: this may expose secrets).`
+
+// runMCPCommand implements the "grab mcp" subcommand
+func runMCPCommand(args []string) error {
+	fs := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, mcpUsageText) }
+	var skipRedaction bool
+	fs.BoolVar(&skipRedaction, "skip-redaction", false, "Return file contents without redacting secrets")
+	fs.BoolVar(&skipRedaction, "S", false, "Return file contents without redacting secrets (shorthand)")
+	maxFileSizeStr := fs.String("max-file-size", "", "Maximum file size the tools include")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("too many arguments\n\n%s", mcpUsageText)
+	}
+
+	root := "."
+	if fs.NArg() == 1 {
+		root = fs.Arg(0)
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for %q: %w", root, err)
+	}
+	if stat, err := os.Stat(root); err != nil {
+		return fmt.Errorf("failed to access %q: %w", root, err)
+	} else if !stat.IsDir() {
+		return fmt.Errorf("%q is not a directory", root)
+	}
+
+	server, err := mcp.NewServer(root, !skipRedaction)
+	if err != nil {
+		return err
+	}
+	if *maxFileSizeStr != "" {
+		server.MaxFileSize, err = utils.ParseSizeString(*maxFileSizeStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse max file size %q: %w", *maxFileSizeStr, err)
+		}
+	}
+	if skipRedaction {
+		fmt.Fprintln(os.Stderr, "⚠️ WARNING: secret redaction is disabled, the tools may expose secrets!")
+	}
+
+	// Stdout carries the protocol, so status messages go to stderr
+	fmt.Fprintf(os.Stderr, "🔌 Serving %s over MCP on stdio\n", root)
+	return server.Serve(os.Stdin, os.Stdout)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/epilande/codegrab/internal/generator"
+	"github.com/epilande/codegrab/internal/ui"
+	"github.com/epilande/codegrab/internal/utils"
+)
+
+// progressRedrawInterval limits how often the progress line is redrawn
+const progressRedrawInterval = 100 * time.Millisecond
+
+// progressPrinter shows the progress of a walk or a generation on a single line that
+// is redrawn in place
+type progressPrinter struct {
+	out   io.Writer
+	last  time.Time
+	drawn bool
+}
+
+// update redraws the progress line, at most every progressRedrawInterval until the
+// last file
+func (p *progressPrinter) update(progress generator.Progress) {
+	now := time.Now()
+	if progress.FilesScanned < progress.TotalFiles && now.Sub(p.last) < progressRedrawInterval {
+		return
+	}
+	p.last = now
+	p.drawn = true
+	fmt.Fprintf(p.out, "\r\033[K⏳ %s %d/%d files | %s | %d secrets",
+		ui.ProgressBar(progress.FilesScanned, progress.TotalFiles, 20),
+		progress.FilesScanned, progress.TotalFiles, utils.FormatSize(progress.BytesRead), progress.SecretsFound)
+}
+
+// walked redraws the number of files found so far by the directory walk, at most
+// every progressRedrawInterval
+func (p *progressPrinter) walked(found int) {
+	now := time.Now()
+	if now.Sub(p.last) < progressRedrawInterval {
+		return
+	}
+	p.last = now
+	p.drawn = true
+	fmt.Fprintf(p.out, "\r\033[K⏳ Walking directory: %d files found", found)
+}
+
+// clear erases the progress line
+func (p *progressPrinter) clear() {
+	if p.drawn {
+		fmt.Fprint(p.out, "\r\033[K")
+		p.drawn = false
+	}
+}
+
+// isTerminal reports whether f is a terminal, where a redrawn progress line can be shown
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/epilande/codegrab/internal/httpapi"
+	"github.com/epilande/codegrab/internal/utils"
+)
+
+const serveUsageText = `Usage:
+  grab serve [options] [directory]
+
+  Serve a JSON API over HTTP for editor integrations, with endpoints for the file tree,
+  token counts, dependency resolution and bundle generation. File contents are cached
+  in memory between requests, and secrets are redacted from everything returned.
+
+  Options:
+    --addr <host:port>       Address to listen on (default: "127.0.0.1:8787").
+    --token <token>          Require "Authorization: Bearer <token>" on every request.
+                             Defaults to the CODEGRAB_TOKEN environment variable.
+    --max-file-size <size>   Maximum file size the API includes (e.g., "50kb", "2MB"). No limit by default.
+    -S, --skip-redaction     Return file contents without redacting secrets (WARNING: this may expose secrets).`
+
+// runServeCommand implements the "grab serve" subcommand
+func runServeCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, serveUsageText) }
+	addr := fs.String("addr", "127.0.0.1:8787", "Address to listen on")
+	token := fs.String("token", os.Getenv("CODEGRAB_TOKEN"), "Bearer token required on every request")
+	maxFileSizeStr := fs.String("max-file-size", "", "Maximum file size the API includes")
+	var skipRedaction bool
+	fs.BoolVar(&skipRedaction, "skip-redaction", false, "Return file contents without redacting secrets")
+	fs.BoolVar(&skipRedaction, "S", false, "Return file contents without redacting secrets (shorthand)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("too many arguments\n\n%s", serveUsageText)
+	}
+
+	root := "."
+	if fs.NArg() == 1 {
+		root = fs.Arg(0)
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for %q: %w", root, err)
+	}
+	if stat, err := os.Stat(root); err != nil {
+		return fmt.Errorf("failed to access %q: %w", root, err)
+	} else if !stat.IsDir() {
+		return fmt.Errorf("%q is not a directory", root)
+	}
+
+	server, err := httpapi.NewServer(root, !skipRedaction)
+	if err != nil {
+		return err
+	}
+	server.Token = *token
+	server.Addr = *addr
+	if *maxFileSizeStr != "" {
+		server.MaxFileSize, err = utils.ParseSizeString(*maxFileSizeStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse max file size %q: %w", *maxFileSizeStr, err)
+		}
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", *addr, err)
+	}
+	if skipRedaction {
+		fmt.Fprintln(os.Stderr, "⚠️ WARNING: secret redaction is disabled, the API may expose secrets!")
+	}
+	if host, _, err := net.SplitHostPort(listener.Addr().String()); err == nil && server.Token == "" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			fmt.Fprintf(os.Stderr, "Warning: listening on %s without a token, anyone on the network can read the project\n", listener.Addr())
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Warm the file cache in the background, so that the first requests are fast too
+	go func() {
+		if _, err := server.Warm(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to warm the file cache: %v\n", err)
+		}
+	}()
+
+	httpServer := &http.Server{
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "🌐 Serving %s on http://%s\n", root, listener.Addr())
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server failed: %w", err)
+	}
+	return nil
+}
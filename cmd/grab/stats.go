@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/epilande/codegrab/internal/filesystem"
+	"github.com/epilande/codegrab/internal/stats"
+	"github.com/epilande/codegrab/internal/utils"
+)
+
+const statsUsageText = `Usage:
+  grab stats [options] [directory]
+
+  Report file counts, bytes and estimated tokens by language, by top-level directory
+  and by selected versus unselected files, the largest files, and what the filters exclude.
+
+  Options:
+    -g, --glob <pattern>     Include/exclude files using glob patterns. Can be used multiple times.
+    --max-file-size <size>   Maximum file size to include (e.g., "50kb", "2MB"). No limit by default.
+    --files-from <file|->    Count the files listed in a file, or on stdin with "-", as selected.
+                             All files are selected by default, as in non-interactive mode.
+    --top <n>                Number of largest files to list (default: 10).
+    --json                   Print the report as JSON instead of a table.`
+
+// runStatsCommand implements the "grab stats" subcommand
+func runStatsCommand(args []string) error {
+	var globPatterns stringSliceFlag
+
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, statsUsageText) }
+	fs.Var(&globPatterns, "glob", "Include/exclude files using glob patterns")
+	fs.Var(&globPatterns, "g", "Include/exclude files using glob patterns (shorthand)")
+	maxFileSizeStr := fs.String("max-file-size", "", "Maximum file size to include")
+	filesFrom := fs.String("files-from", "", "Count the listed files as selected")
+	top := fs.Int("top", stats.DefaultTopFiles, "Number of largest files to list")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("too many arguments\n\n%s", statsUsageText)
+	}
+
+	root := "."
+	if fs.NArg() == 1 {
+		root = fs.Arg(0)
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for %q: %w", root, err)
+	}
+	if stat, err := os.Stat(root); err != nil {
+		return fmt.Errorf("failed to access %q: %w", root, err)
+	} else if !stat.IsDir() {
+		return fmt.Errorf("%q is not a directory", root)
+	}
+
+	var maxFileSize int64 = math.MaxInt64
+	if *maxFileSizeStr != "" {
+		maxFileSize, err = utils.ParseSizeString(*maxFileSizeStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse max file size %q: %w", *maxFileSizeStr, err)
+		}
+	}
+
+	gitIgnoreMgr, err := filesystem.NewGitIgnoreManager(root)
+	if err != nil {
+		return fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+
+	filterMgr := filesystem.NewFilterManager()
+	for _, pattern := range globPatterns {
+		if normalizedPattern, isValid := utils.NormalizeGlobPattern(pattern, root); isValid {
+			filterMgr.AddGlobPattern(normalizedPattern)
+		}
+	}
+
+	files, err := filesystem.WalkDirectory(context.Background(), root, gitIgnoreMgr, filterMgr, true, false, maxFileSize)
+	if err != nil {
+		return fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	selected := make(map[string]bool)
+	if *filesFrom != "" {
+		fileList, err := filesystem.ReadFileListFrom(*filesFrom)
+		if err != nil {
+			return fmt.Errorf("failed to read file list: %w", err)
+		}
+		// Statistics count whole files, even for entries narrowed to some lines
+		fileList, _ = parseFileList(root, fileList)
+		accepted, skipped, err := filesystem.ValidateFileList(context.Background(), root, fileList, gitIgnoreMgr, true, false, maxFileSize)
+		if err != nil {
+			return fmt.Errorf("failed to read file list: %w", err)
+		}
+		for _, s := range skipped {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %s\n", s.Path, s.Reason)
+		}
+		for _, path := range accepted {
+			selected[path] = true
+		}
+	} else {
+		for _, file := range files {
+			if !file.IsDir {
+				selected[file.Path] = true
+			}
+		}
+	}
+
+	report := stats.Compute(root, files, selected, *top)
+	report.Excluded, err = stats.Excluded(root, files, stats.Filters{
+		GitIgnore:    gitIgnoreMgr,
+		Filter:       filterMgr,
+		UseGitIgnore: true,
+		MaxFileSize:  maxFileSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return report.WriteJSON(os.Stdout)
+	}
+	return report.WriteTable(os.Stdout)
+}
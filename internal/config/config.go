@@ -0,0 +1,76 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// EnvConfigPath is the environment variable that overrides the config file location
+const EnvConfigPath = "CODEGRAB_CONFIG"
+
+// Config holds the user settings read from the config file
+type Config struct {
+	// ForgeHosts lists hostnames of self-hosted Git forges (e.g. "git.example.com")
+	// whose HTTPS URLs are cloned like GitHub, GitLab and Bitbucket URLs
+	ForgeHosts []string `json:"forgeHosts"`
+	// CloneCache configures the cache of cloned remote repositories
+	CloneCache CloneCacheConfig `json:"cloneCache"`
+	// Keys rebinds actions of the interactive mode, by action name (e.g. "select_visible").
+	// An empty list unbinds the action.
+	Keys map[string][]string `json:"keys"`
+}
+
+// CloneCacheConfig configures the cache of cloned remote repositories.
+// Zero values fall back to the defaults.
+type CloneCacheConfig struct {
+	Enabled    bool   `json:"enabled"`
+	Dir        string `json:"dir"`
+	MaxAgeDays int    `json:"maxAgeDays"`
+	MaxEntries int    `json:"maxEntries"`
+}
+
+// Path returns the location of the config file: $CODEGRAB_CONFIG if set,
+// otherwise codegrab/config.json in the user config directory
+func Path() (string, error) {
+	if path := os.Getenv(EnvConfigPath); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user config directory: %w", err)
+	}
+	return filepath.Join(dir, "codegrab", "config.json"), nil
+}
+
+// Load reads the config file from its default location.
+// A missing config file is not an error and yields an empty Config.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return &Config{}, err
+	}
+	return LoadFrom(path)
+}
+
+// LoadFrom reads the config file at path.
+// A missing config file is not an error and yields an empty Config.
+func LoadFrom(path string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return &Config{}, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadFrom(t *testing.T) {
+	tempDir := t.TempDir()
+
+	cfg, err := LoadFrom(filepath.Join(tempDir, "missing.json"))
+	if err != nil {
+		t.Fatalf("Expected a missing config file to be ignored, got %v", err)
+	}
+	if len(cfg.ForgeHosts) != 0 {
+		t.Errorf("Expected an empty config, got %+v", cfg)
+	}
+
+	path := filepath.Join(tempDir, "config.json")
+	content := `{"forgeHosts": ["git.example.com", "gitea.internal:3000"], "cloneCache": {"enabled": true, "maxEntries": 5}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err = LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	expected := []string{"git.example.com", "gitea.internal:3000"}
+	if !reflect.DeepEqual(cfg.ForgeHosts, expected) {
+		t.Errorf("Expected forge hosts %v, got %v", expected, cfg.ForgeHosts)
+	}
+	if !cfg.CloneCache.Enabled || cfg.CloneCache.MaxEntries != 5 || cfg.CloneCache.MaxAgeDays != 0 {
+		t.Errorf("Unexpected clone cache config: %+v", cfg.CloneCache)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"forgeHosts": "not a list"}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := LoadFrom(path); err == nil {
+		t.Error("Expected an error for a malformed config file")
+	}
+}
+
+func TestPathFromEnv(t *testing.T) {
+	t.Setenv(EnvConfigPath, "/custom/config.json")
+
+	path, err := Path()
+	if err != nil {
+		t.Fatalf("Path failed: %v", err)
+	}
+	if path != "/custom/config.json" {
+		t.Errorf("Expected %s to override the config path, got %q", EnvConfigPath, path)
+	}
+}
@@ -0,0 +1,180 @@
+package dependencies
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+const (
+	benchmarkPackages        = 100
+	benchmarkFilesPerPackage = 100
+	benchmarkModule          = "example.com/bench"
+)
+
+// BenchmarkResolveAll resolves the files of a synthetic 10k-file project with a pool
+// of workers
+func BenchmarkResolveAll(b *testing.B) {
+	tmpDir, err := os.MkdirTemp("", "deps-benchmark-")
+	if err != nil {
+		b.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	paths := setupBenchmarkProject(b, tmpDir)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		resolver := NewConcurrentResolver(tmpDir, benchmarkModule)
+		for _, result := range resolver.ResolveAll(context.Background(), paths) {
+			if result.Err != nil {
+				b.Fatalf("ResolveAll(%q) failed: %v", result.Path, result.Err)
+			}
+		}
+	}
+}
+
+// BenchmarkConcurrentVsSequential compares resolving with pooled parsers, compiled
+// queries and a pool of workers against the original one file at a time resolution
+func BenchmarkConcurrentVsSequential(b *testing.B) {
+	tmpDir, err := os.MkdirTemp("", "deps-concurrent-vs-sequential-")
+	if err != nil {
+		b.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	paths := setupBenchmarkProject(b, tmpDir)
+
+	b.Run("Concurrent", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			resolver := NewConcurrentResolver(tmpDir, benchmarkModule)
+			for _, result := range resolver.ResolveAll(context.Background(), paths) {
+				if result.Err != nil {
+					b.Fatalf("Concurrent ResolveAll(%q) failed: %v", result.Path, result.Err)
+				}
+			}
+		}
+	})
+
+	b.Run("Original", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := resolveOriginal(context.Background(), tmpDir, paths); err != nil {
+				b.Fatalf("Original resolution failed: %v", err)
+			}
+		}
+	})
+}
+
+// BenchmarkResolveAllMemoized resolves files that were all resolved before, as when the
+// dependencies of a selection are resolved again
+func BenchmarkResolveAllMemoized(b *testing.B) {
+	tmpDir, err := os.MkdirTemp("", "deps-memoized-")
+	if err != nil {
+		b.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	paths := setupBenchmarkProject(b, tmpDir)
+	resolver := NewConcurrentResolver(tmpDir, benchmarkModule)
+	resolver.ResolveAll(context.Background(), paths)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, result := range resolver.ResolveAll(context.Background(), paths) {
+			if result.Err != nil {
+				b.Fatalf("ResolveAll(%q) failed: %v", result.Path, result.Err)
+			}
+		}
+	}
+}
+
+// resolveOriginal resolves files one at a time the way it was done before parsers were
+// pooled and queries compiled once: every file gets a new parser and compiles its
+// import query again, and nothing is memoized
+func resolveOriginal(ctx context.Context, root string, paths []string) error {
+	for _, path := range paths {
+		content, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			return err
+		}
+
+		var shared *language
+		resolver := GetResolver(path).(interface {
+			resolve(ctx context.Context, l *language, fileContent []byte, filePath string, projectRoot string, projectModuleName string) ([]string, error)
+		})
+		switch resolver.(type) {
+		case *GoResolver:
+			shared = goLanguage
+		case *JSResolver:
+			shared = jsLanguage
+		case *PyResolver:
+			shared = pyLanguage
+		}
+
+		// Every parser made for the file is closed with it, whether or not the pool kept it
+		fresh := newLanguage(shared.name, shared.lang, shared.querySource)
+		var parsers []*sitter.Parser
+		newParser := fresh.parsers.New
+		fresh.parsers.New = func() any {
+			parser := newParser().(*sitter.Parser)
+			parsers = append(parsers, parser)
+			return parser
+		}
+
+		_, err = resolver.resolve(ctx, fresh, content, path, root, benchmarkModule)
+		if fresh.query != nil {
+			fresh.query.Close()
+		}
+		for _, parser := range parsers {
+			parser.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("resolve %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// setupBenchmarkProject creates a project of Go, TypeScript and Python packages
+// that each import the next package, returning the paths of its files
+func setupBenchmarkProject(b *testing.B, baseDir string) []string {
+	b.Helper()
+
+	paths := make([]string, 0, benchmarkPackages*benchmarkFilesPerPackage)
+	for pkg := 0; pkg < benchmarkPackages; pkg++ {
+		next := (pkg + 1) % benchmarkPackages
+		for file := 0; file < benchmarkFilesPerPackage; file++ {
+			var path, content string
+			switch pkg % 3 {
+			case 0:
+				path = fmt.Sprintf("go/pkg%d/file%d.go", pkg, file)
+				content = fmt.Sprintf("package pkg%d\n\nimport (\n\t\"fmt\"\n\n\t\"%s/go/pkg%d\"\n)\n\nfunc F%d() {\n\tfmt.Println(pkg%d.F%d)\n}\n",
+					pkg, benchmarkModule, next, file, next, file)
+			case 1:
+				path = fmt.Sprintf("web/mod%d/file%d.ts", pkg, file)
+				content = fmt.Sprintf("import { value } from '../mod%d/file%d';\nimport { other } from './file%d';\n\nexport const value%d = value + other;\n",
+					next, file, (file+1)%benchmarkFilesPerPackage, file)
+			default:
+				path = fmt.Sprintf("py/pkg%d/mod%d.py", pkg, file)
+				content = fmt.Sprintf("import os\nfrom . import mod%d\nfrom ..pkg%d import mod%d\n\n\ndef run():\n    return os.getcwd()\n",
+					(file+1)%benchmarkFilesPerPackage, next, file)
+			}
+
+			fullPath := filepath.Join(baseDir, filepath.FromSlash(path))
+			if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+				b.Fatalf("Failed to create dir for %s: %v", path, err)
+			}
+			if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+				b.Fatalf("Failed to write %s: %v", path, err)
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,135 @@
+package dependencies
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Result holds the direct dependencies of a file, or the error resolving them.
+type Result struct {
+	Err  error
+	Path string
+	Deps []string
+}
+
+// ConcurrentResolver resolves the dependencies of many files with a pool of
+// workers, memoizing them by file path and content hash. It is safe for
+// concurrent use.
+type ConcurrentResolver struct {
+	memo        map[memoKey][]string
+	ProjectRoot string
+	ModuleName  string
+	Workers     int
+	mu          sync.Mutex
+}
+
+type memoKey struct {
+	path string
+	hash [sha256.Size]byte
+}
+
+// NewConcurrentResolver creates a resolver for the files of the project at
+// projectRoot, using one worker per CPU.
+func NewConcurrentResolver(projectRoot, moduleName string) *ConcurrentResolver {
+	return &ConcurrentResolver{
+		memo:        make(map[memoKey][]string),
+		ProjectRoot: projectRoot,
+		ModuleName:  moduleName,
+		Workers:     runtime.NumCPU(),
+	}
+}
+
+// Resolve returns the direct dependencies of the file at filePath, relative to
+// the project root, given its content. They are sorted, and nil for files of a
+// language without a resolver.
+func (r *ConcurrentResolver) Resolve(ctx context.Context, filePath string, content []byte) ([]string, error) {
+	resolver := GetResolver(filePath)
+	if resolver == nil {
+		return nil, nil
+	}
+
+	key := memoKey{path: filePath, hash: sha256.Sum256(content)}
+	r.mu.Lock()
+	deps, ok := r.memo[key]
+	r.mu.Unlock()
+	if ok {
+		return deps, nil
+	}
+
+	resolved, err := resolver.Resolve(ctx, content, filePath, r.ProjectRoot, r.ModuleName)
+	if err != nil {
+		return nil, err
+	}
+
+	deps = make([]string, 0, len(resolved))
+	for _, depPath := range resolved {
+		depPath = filepath.ToSlash(filepath.Clean(depPath))
+		if depPath != "." && !filepath.IsAbs(depPath) {
+			deps = append(deps, depPath)
+		}
+	}
+	sort.Strings(deps)
+
+	r.mu.Lock()
+	r.memo[key] = deps
+	r.mu.Unlock()
+	return deps, nil
+}
+
+// Reset forgets the memoized dependencies, which go stale when files are added
+// to or removed from the project.
+func (r *ConcurrentResolver) Reset() {
+	r.mu.Lock()
+	r.memo = make(map[memoKey][]string)
+	r.mu.Unlock()
+}
+
+// ResolveAll reads and resolves the files at paths concurrently, returning their
+// results in the same order.
+func (r *ConcurrentResolver) ResolveAll(ctx context.Context, paths []string) []Result {
+	results := make([]Result, len(paths))
+	workers := min(max(r.Workers, 1), len(paths))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = r.resolveFile(ctx, paths[i])
+			}
+		}()
+	}
+
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func (r *ConcurrentResolver) resolveFile(ctx context.Context, filePath string) Result {
+	if err := ctx.Err(); err != nil {
+		return Result{Path: filePath, Err: err}
+	}
+	if GetResolver(filePath) == nil {
+		return Result{Path: filePath}
+	}
+
+	content, err := os.ReadFile(filepath.Join(r.ProjectRoot, filePath))
+	if err != nil {
+		return Result{Path: filePath, Err: fmt.Errorf("cannot read file %s for dependency resolution: %w", filePath, err)}
+	}
+
+	deps, err := r.Resolve(ctx, filePath, content)
+	return Result{Path: filePath, Deps: deps, Err: err}
+}
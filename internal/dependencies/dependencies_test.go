@@ -1,8 +1,11 @@
 package dependencies
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -33,3 +36,114 @@ func setupTestEnv(t *testing.T, files map[string]string) (string, func()) {
 
 	return tempDir, func() { os.RemoveAll(tempDir) }
 }
+
+func TestResolveCancelled(t *testing.T) {
+	tempDir, cleanup := setupTestEnv(t, map[string]string{
+		"main.go":  "package main\n\nimport \"./util\"\n",
+		"index.ts": "import { run } from './util';\n",
+		"main.py":  "import util\n",
+	})
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, path := range []string{"main.go", "index.ts", "main.py"} {
+		content, err := os.ReadFile(filepath.Join(tempDir, path))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		if _, err := GetResolver(path).Resolve(ctx, content, path, tempDir, ""); !errors.Is(err, context.Canceled) {
+			t.Errorf("Resolve(%q) error = %v, want context.Canceled", path, err)
+		}
+	}
+}
+
+func TestConcurrentResolver(t *testing.T) {
+	tempDir, cleanup := setupTestEnv(t, map[string]string{
+		"main.go":      "package main\n\nimport \"example.com/app/util\"\n",
+		"util/util.go": "package util\n",
+		"util/b.go":    "package util\n",
+		"index.ts":     "import { run } from './lib';\n",
+		"lib.ts":       "export const run = 1;\n",
+		"README.md":    "# App\n",
+	})
+	defer cleanup()
+
+	resolver := NewConcurrentResolver(tempDir, "example.com/app")
+	results := resolver.ResolveAll(context.Background(), []string{"main.go", "index.ts", "README.md", "missing.ts"})
+
+	want := []Result{
+		{Path: "main.go", Deps: []string{"util/b.go", "util/util.go"}},
+		{Path: "index.ts", Deps: []string{"lib.ts"}},
+		{Path: "README.md"},
+	}
+	for i, w := range want {
+		got := results[i]
+		if got.Path != w.Path || got.Err != nil || !reflect.DeepEqual(got.Deps, w.Deps) {
+			t.Errorf("results[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+	if results[3].Path != "missing.ts" || results[3].Err == nil {
+		t.Errorf("results[3] = %+v, want a read error for missing.ts", results[3])
+	}
+}
+
+func TestConcurrentResolverMemoizesByContent(t *testing.T) {
+	tempDir, cleanup := setupTestEnv(t, map[string]string{
+		"index.ts": "import { run } from './lib';\n",
+		"lib.ts":   "export const run = 1;\n",
+		"other.ts": "export const other = 1;\n",
+	})
+	defer cleanup()
+
+	resolver := NewConcurrentResolver(tempDir, "")
+	content := []byte("import { run } from './lib';\n")
+	if _, err := resolver.Resolve(context.Background(), "index.ts", content); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	// The memoized dependencies are returned for unchanged content even though
+	// lib.ts no longer exists
+	if err := os.Remove(filepath.Join(tempDir, "lib.ts")); err != nil {
+		t.Fatalf("Failed to remove lib.ts: %v", err)
+	}
+	deps, err := resolver.Resolve(context.Background(), "index.ts", content)
+	if err != nil || !reflect.DeepEqual(deps, []string{"lib.ts"}) {
+		t.Errorf("Resolve() = %v, %v, want memoized [lib.ts]", deps, err)
+	}
+
+	deps, err = resolver.Resolve(context.Background(), "index.ts", []byte("import { other } from './other';\n"))
+	if err != nil || !reflect.DeepEqual(deps, []string{"other.ts"}) {
+		t.Errorf("Resolve() with new content = %v, %v, want [other.ts]", deps, err)
+	}
+
+	resolver.Reset()
+	deps, err = resolver.Resolve(context.Background(), "index.ts", content)
+	if err != nil || len(deps) != 0 {
+		t.Errorf("Resolve() after Reset = %v, %v, want no dependencies", deps, err)
+	}
+}
+
+func TestConcurrentResolverCancelled(t *testing.T) {
+	tempDir, cleanup := setupTestEnv(t, map[string]string{
+		"a.py": "import b\n",
+		"b.py": "x = 1\n",
+	})
+	defer cleanup()
+
+	resolver := NewConcurrentResolver(tempDir, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, result := range resolver.ResolveAll(ctx, []string{"a.py", "b.py"}) {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("ResolveAll(%q) error = %v, want context.Canceled", result.Path, result.Err)
+		}
+	}
+
+	results := resolver.ResolveAll(context.Background(), []string{"a.py"})
+	if results[0].Err != nil || !reflect.DeepEqual(results[0].Deps, []string{"b.py"}) {
+		t.Errorf("ResolveAll() after cancellation = %+v, want [b.py]", results[0])
+	}
+}
@@ -12,21 +12,29 @@ import (
 	"github.com/smacker/go-tree-sitter/golang"
 )
 
+// goLanguage finds import paths within import specs
+var goLanguage = newLanguage("Go", golang.GetLanguage(), `(import_spec path: (interpreted_string_literal) @import_path)`)
+
 // GoResolver implements Resolver for Go files.
 type GoResolver struct{}
 
 // Resolve finds Go dependencies.
-func (r *GoResolver) Resolve(fileContent []byte, filePath string, projectRoot string, projectModuleName string) ([]string, error) {
+func (r *GoResolver) Resolve(ctx context.Context, fileContent []byte, filePath string, projectRoot string, projectModuleName string) ([]string, error) {
+	return r.resolve(ctx, goLanguage, fileContent, filePath, projectRoot, projectModuleName)
+}
+
+// resolve finds the dependencies using the parsers and import query of l
+func (r *GoResolver) resolve(ctx context.Context, l *language, fileContent []byte, filePath string, projectRoot string, projectModuleName string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if len(fileContent) == 0 {
 		return nil, nil
 	}
 
 	dependencies := make(map[string]struct{})
 
-	parser := sitter.NewParser()
-	parser.SetLanguage(golang.GetLanguage())
-
-	tree, err := parser.ParseCtx(context.Background(), nil, fileContent)
+	tree, err := l.parse(ctx, fileContent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse Go file %s: %w", filePath, err)
 	}
@@ -36,13 +44,10 @@ func (r *GoResolver) Resolve(fileContent []byte, filePath string, projectRoot st
 		return nil, fmt.Errorf("parsing error detected in Go file %s", filePath)
 	}
 
-	// Query to find import paths within import specs
-	queryStr := `(import_spec path: (interpreted_string_literal) @import_path)`
-	query, err := sitter.NewQuery([]byte(queryStr), golang.GetLanguage())
+	query, err := l.importQuery()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Go query: %w", err)
+		return nil, err
 	}
-	defer query.Close()
 
 	qc := sitter.NewQueryCursor()
 	qc.Exec(query, tree.RootNode())
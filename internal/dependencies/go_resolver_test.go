@@ -1,6 +1,7 @@
 package dependencies
 
 import (
+	"context"
 	"errors"
 	"io/fs"
 	"os"
@@ -149,7 +150,7 @@ func TestGoResolver_Resolve(t *testing.T) {
 				currentModuleName = moduleName
 			}
 
-			deps, err := resolver.Resolve(fileContent, tt.filePath, tempDir, currentModuleName)
+			deps, err := resolver.Resolve(context.Background(), fileContent, tt.filePath, tempDir, currentModuleName)
 
 			if tt.expectError {
 				if err == nil && !errors.Is(readErr, fs.ErrNotExist) {
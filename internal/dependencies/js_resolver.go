@@ -11,21 +11,46 @@ import (
 	"github.com/smacker/go-tree-sitter/typescript/tsx"
 )
 
+// jsLanguage finds import/require/export sources
+var jsLanguage = newLanguage("JS/TS", tsx.GetLanguage(), `
+        [
+          (import_statement source: (string (string_fragment)? @import_path))
+
+          (call_expression
+            function: [(identifier)@id (member_expression property: (property_identifier)@id)]
+            arguments: (arguments (string (string_fragment)? @import_path))
+            (#eq? @id "require")
+          )
+
+          (export_statement source: (string (string_fragment)? @import_path))
+
+          (call_expression ; Dynamic import
+            function: (import)
+            arguments: (arguments (string (string_fragment)? @import_path))
+          )
+        ] @import
+    `)
+
 // JSResolver implements Resolver for TypeScript/JavaScript files.
 type JSResolver struct{}
 
 // Resolve finds TS/JS dependencies.
-func (r *JSResolver) Resolve(fileContent []byte, filePath string, projectRoot string, projectModuleName string) ([]string, error) {
+func (r *JSResolver) Resolve(ctx context.Context, fileContent []byte, filePath string, projectRoot string, projectModuleName string) ([]string, error) {
+	return r.resolve(ctx, jsLanguage, fileContent, filePath, projectRoot, projectModuleName)
+}
+
+// resolve finds the dependencies using the parsers and import query of l
+func (r *JSResolver) resolve(ctx context.Context, l *language, fileContent []byte, filePath string, projectRoot string, projectModuleName string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if len(fileContent) == 0 {
 		return []string{}, nil
 	}
 
 	dependencies := make(map[string]struct{})
 
-	parser := sitter.NewParser()
-	parser.SetLanguage(tsx.GetLanguage())
-
-	tree, err := parser.ParseCtx(context.Background(), nil, fileContent)
+	tree, err := l.parse(ctx, fileContent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse JS/TS file %s: %w", filePath, err)
 	}
@@ -35,30 +60,10 @@ func (r *JSResolver) Resolve(fileContent []byte, filePath string, projectRoot st
 		return nil, fmt.Errorf("parsing error detected in JS/TS file %s", filePath)
 	}
 
-	// Query to find import/require/export sources
-	queryStr := `
-        [
-          (import_statement source: (string (string_fragment)? @import_path))
-
-          (call_expression
-            function: [(identifier)@id (member_expression property: (property_identifier)@id)]
-            arguments: (arguments (string (string_fragment)? @import_path))
-            (#eq? @id "require")
-          )
-
-          (export_statement source: (string (string_fragment)? @import_path))
-
-          (call_expression ; Dynamic import
-            function: (import)
-            arguments: (arguments (string (string_fragment)? @import_path))
-          )
-        ] @import
-    `
-	query, err := sitter.NewQuery([]byte(queryStr), tsx.GetLanguage())
+	query, err := l.importQuery()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create JS/TS query: %w", err)
+		return nil, err
 	}
-	defer query.Close()
 
 	qc := sitter.NewQueryCursor()
 	qc.Exec(query, tree.RootNode())
@@ -1,6 +1,7 @@
 package dependencies
 
 import (
+	"context"
 	"errors"
 	"io/fs"
 	"os"
@@ -102,7 +103,7 @@ func TestJSResolver_Resolve(t *testing.T) {
 				t.Fatalf("Failed to read test file %s: %v", tt.filePath, readErr)
 			}
 
-			deps, err := resolver.Resolve(fileContent, tt.filePath, tempDir, "")
+			deps, err := resolver.Resolve(context.Background(), fileContent, tt.filePath, tempDir, "")
 
 			if tt.expectError {
 				if err == nil && !errors.Is(readErr, fs.ErrNotExist) {
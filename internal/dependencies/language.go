@@ -0,0 +1,62 @@
+package dependencies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// language shares the tree-sitter parsers and the compiled import query of a
+// language between all the files resolved with it.
+type language struct {
+	lang        *sitter.Language
+	parsers     sync.Pool
+	query       *sitter.Query
+	queryErr    error
+	name        string
+	querySource string
+	queryOnce   sync.Once
+}
+
+func newLanguage(name string, lang *sitter.Language, querySource string) *language {
+	l := &language{lang: lang, name: name, querySource: querySource}
+	l.parsers.New = func() any {
+		parser := sitter.NewParser()
+		parser.SetLanguage(lang)
+		return parser
+	}
+	return l
+}
+
+// parse parses content with a pooled parser.
+func (l *language) parse(ctx context.Context, content []byte) (*sitter.Tree, error) {
+	parser := l.parsers.Get().(*sitter.Parser)
+	tree, err := parser.ParseCtx(ctx, nil, content)
+	if errors.Is(err, sitter.ErrOperationLimit) && ctx.Err() == nil {
+		// The parser was halted by a cancellation flag left over from an earlier
+		// cancelled parse, so it is dropped and the file parsed again with a new one.
+		parser.Close()
+		parser = l.parsers.New().(*sitter.Parser)
+		tree, err = parser.ParseCtx(ctx, nil, content)
+	}
+	if err != nil {
+		// A halted parser resumes its parse on the next call unless it is reset.
+		parser.Reset()
+	}
+	l.parsers.Put(parser)
+	return tree, err
+}
+
+// importQuery returns the compiled import query, compiling it on first use.
+func (l *language) importQuery() (*sitter.Query, error) {
+	l.queryOnce.Do(func() {
+		l.query, l.queryErr = sitter.NewQuery([]byte(l.querySource), l.lang)
+		if l.queryErr != nil {
+			l.queryErr = fmt.Errorf("failed to create %s query: %w", l.name, l.queryErr)
+		}
+	})
+	return l.query, l.queryErr
+}
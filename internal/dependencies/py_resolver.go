@@ -11,18 +11,38 @@ import (
 	"github.com/smacker/go-tree-sitter/python"
 )
 
+// pyLanguage finds the modules of import and from-import statements
+var pyLanguage = newLanguage("Python", python.GetLanguage(), `
+	[
+		(import_statement [
+			(dotted_name) @import_path
+			(aliased_import name: (dotted_name) @import_path)
+		])
+		(import_from_statement
+			module_name: [
+				(dotted_name) @import_path
+				(relative_import) @import_path
+			])
+	]
+`)
+
 type PyResolver struct{}
 
-func (r *PyResolver) Resolve(fileContent []byte, filePath string, projectRoot string, projectModuleName string) ([]string, error) {
+func (r *PyResolver) Resolve(ctx context.Context, fileContent []byte, filePath string, projectRoot string, projectModuleName string) ([]string, error) {
+	return r.resolve(ctx, pyLanguage, fileContent, filePath, projectRoot, projectModuleName)
+}
+
+// resolve finds the dependencies using the parsers and import query of l
+func (r *PyResolver) resolve(ctx context.Context, l *language, fileContent []byte, filePath string, projectRoot string, projectModuleName string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if len(fileContent) == 0 {
 		return []string{}, nil
 	}
 	dependencies := make(map[string]struct{})
 
-	parser := sitter.NewParser()
-	parser.SetLanguage(python.GetLanguage())
-
-	tree, err := parser.ParseCtx(context.Background(), nil, fileContent)
+	tree, err := l.parse(ctx, fileContent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse Python file %s: %w", filePath, err)
 	}
@@ -32,25 +52,10 @@ func (r *PyResolver) Resolve(fileContent []byte, filePath string, projectRoot st
 		return nil, fmt.Errorf("parsing error detected in Python file %s", filePath)
 	}
 
-	queryString := `
-		[
-  			(import_statement [
- 	     		(dotted_name) @import_path 
- 	     		(aliased_import name: (dotted_name) @import_path) 
- 	   		])
-			(import_from_statement 
-				module_name: [
-					(dotted_name) @import_path
-					(relative_import) @import_path
-				])
-		]
-	`
-
-	query, err := sitter.NewQuery([]byte(queryString), python.GetLanguage())
+	query, err := l.importQuery()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Python query: %w", err)
+		return nil, err
 	}
-	defer query.Close()
 
 	qc := sitter.NewQueryCursor()
 	qc.Exec(query, tree.RootNode())
@@ -1,6 +1,7 @@
 package dependencies
 
 import (
+	"context"
 	"errors"
 	"io/fs"
 	"os"
@@ -149,7 +150,7 @@ func TestPyResolver_Resolve(t *testing.T) {
 			}
 
 
-			deps, err := resolver.Resolve(fileContent, tt.filePath, tempDir, "")
+			deps, err := resolver.Resolve(context.Background(), fileContent, tt.filePath, tempDir, "")
 
 			if tt.expectError {
 				if err == nil && !errors.Is(readErr, fs.ErrNotExist) {
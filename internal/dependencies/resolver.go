@@ -1,6 +1,7 @@
 package dependencies
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -9,12 +10,13 @@ import (
 // Resolver defines the interface for finding direct dependencies of a file.
 type Resolver interface {
 	// Resolve finds direct, project-local dependencies for the given file path.
+	// ctx cancels the parsing of the file.
 	// fileContent is the content of the file to parse.
 	// filePath is the path relative to the project root.
 	// projectRoot is the absolute path to the project root.
 	// projectModuleName is the go module name (if applicable, empty otherwise).
 	// Returns a slice of dependency paths, also relative to the project root.
-	Resolve(fileContent []byte, filePath string, projectRoot string, projectModuleName string) ([]string, error)
+	Resolve(ctx context.Context, fileContent []byte, filePath string, projectRoot string, projectModuleName string) ([]string, error)
 }
 
 // GetResolver returns the appropriate resolver based on the file extension.
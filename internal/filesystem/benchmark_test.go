@@ -1,6 +1,7 @@
 package filesystem
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"os"
@@ -33,7 +34,7 @@ func BenchmarkWalkDirectory(b *testing.B) {
 
 	// Benchmark the concurrent implementation
 	for i := 0; i < b.N; i++ {
-		_, err := WalkDirectory(tmpDir, gitIgnoreMgr, filterMgr, false, false, math.MaxInt64)
+		_, err := WalkDirectory(context.Background(), tmpDir, gitIgnoreMgr, filterMgr, false, false, math.MaxInt64)
 		if err != nil {
 			b.Fatalf("WalkDirectory failed: %v", err)
 		}
@@ -57,7 +58,7 @@ func BenchmarkWalkDirectoryLarge(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		files, err := WalkDirectory(tmpDir, gitIgnoreMgr, filterMgr, false, false, math.MaxInt64)
+		files, err := WalkDirectory(context.Background(), tmpDir, gitIgnoreMgr, filterMgr, false, false, math.MaxInt64)
 		if err != nil {
 			b.Fatalf("WalkDirectory failed: %v", err)
 		}
@@ -83,7 +84,7 @@ func BenchmarkConcurrentVsSequential(b *testing.B) {
 
 	b.Run("Concurrent", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			_, err := WalkDirectory(tmpDir, gitIgnoreMgr, filterMgr, false, false, math.MaxInt64)
+			_, err := WalkDirectory(context.Background(), tmpDir, gitIgnoreMgr, filterMgr, false, false, math.MaxInt64)
 			if err != nil {
 				b.Fatalf("Concurrent WalkDirectory failed: %v", err)
 			}
@@ -92,7 +93,7 @@ func BenchmarkConcurrentVsSequential(b *testing.B) {
 
 	b.Run("Original", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			_, err := walkDirectoryOriginal(tmpDir, gitIgnoreMgr, filterMgr, false, false, math.MaxInt64)
+			_, err := walkDirectoryOriginal(context.Background(), tmpDir, gitIgnoreMgr, filterMgr, false, false, math.MaxInt64)
 			if err != nil {
 				b.Fatalf("Original WalkDirectory failed: %v", err)
 			}
@@ -127,7 +128,7 @@ func BenchmarkWorkerScaling(b *testing.B) {
 					maxFileSize:  math.MaxInt64,
 					maxWorkers:   workers,
 				}
-				_, err := walker.walk()
+				_, err := walker.walk(context.Background())
 				if err != nil {
 					b.Fatalf("Walker failed with %d workers: %v", workers, err)
 				}
@@ -217,7 +218,7 @@ func BenchmarkWalkDirectoryImplementations(b *testing.B) {
 	// Test all three implementations
 	implementations := []struct {
 		name string
-		fn   func(string, *GitIgnoreManager, *FilterManager, bool, bool, int64) ([]FileItem, error)
+		fn   func(context.Context, string, *GitIgnoreManager, *FilterManager, bool, bool, int64) ([]FileItem, error)
 	}{
 		{"Concurrent", WalkDirectory},
  
@@ -228,7 +229,7 @@ func BenchmarkWalkDirectoryImplementations(b *testing.B) {
 		b.Run(impl.name, func(b *testing.B) {
 			b.ResetTimer()
 			for i := 0; i < b.N; i++ {
-				files, err := impl.fn(tmpDir, gitIgnoreMgr, filterMgr, false, false, math.MaxInt64)
+				files, err := impl.fn(context.Background(), tmpDir, gitIgnoreMgr, filterMgr, false, false, math.MaxInt64)
 				if err != nil {
 					b.Fatalf("%s implementation failed: %v", impl.name, err)
 				}
@@ -242,7 +243,7 @@ func BenchmarkWalkDirectoryImplementations(b *testing.B) {
 }
 
 // walkDirectoryOriginal is the original sequential implementation for benchmark comparison only
-func walkDirectoryOriginal(root string, gitIgnore *GitIgnoreManager, filter *FilterManager, useGitIgnore, showHidden bool, maxFileSize int64) ([]FileItem, error) {
+func walkDirectoryOriginal(_ context.Context, root string, gitIgnore *GitIgnoreManager, filter *FilterManager, useGitIgnore, showHidden bool, maxFileSize int64) ([]FileItem, error) {
 	var files []FileItem
 
 	if _, err := os.Stat(root); err != nil {
@@ -0,0 +1,117 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/epilande/codegrab/internal/utils"
+)
+
+// SkippedPath records a path from an explicit file list that was not accepted.
+type SkippedPath struct {
+	Path   string
+	Reason string
+}
+
+// ReadFileList reads a list of paths separated by newlines or NUL bytes.
+// If the input contains any NUL byte, it is treated as NUL-separated
+// (as produced by `find -print0` or `git ls-files -z`).
+func ReadFileList(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file list: %w", err)
+	}
+
+	sep := []byte("\n")
+	if bytes.IndexByte(data, 0) != -1 {
+		sep = []byte{0}
+	}
+
+	var paths []string
+	for _, entry := range bytes.Split(data, sep) {
+		path := strings.TrimRight(string(entry), "\r")
+		if sep[0] == '\n' {
+			path = strings.TrimSpace(path)
+		}
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// ReadFileListFrom reads a file list from the given file path, or from stdin if source is "-".
+func ReadFileListFrom(source string) ([]string, error) {
+	if source == "-" {
+		return ReadFileList(os.Stdin)
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file list %s: %w", source, err)
+	}
+	defer file.Close()
+
+	return ReadFileList(file)
+}
+
+// ValidateFileList checks each path against the root directory and applies the
+// gitignore, hidden-file and size rules. Paths may be absolute or relative to the
+// root. It returns the accepted paths, relative to the root in slash form, and the
+// paths that were skipped along with the reason. It stops early, returning the
+// context's error, when ctx is cancelled.
+func ValidateFileList(ctx context.Context, root string, paths []string, gitIgnore *GitIgnoreManager, useGitIgnore, showHidden bool, maxFileSize int64) ([]string, []SkippedPath, error) {
+	var accepted []string
+	var skipped []SkippedPath
+	seen := make(map[string]bool)
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		fullPath := path
+		if !filepath.IsAbs(fullPath) {
+			fullPath = filepath.Join(root, filepath.FromSlash(path))
+		}
+		fullPath = filepath.Clean(fullPath)
+
+		relPath, err := filepath.Rel(root, fullPath)
+		if err != nil || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			skipped = append(skipped, SkippedPath{Path: path, Reason: "outside of the root directory"})
+			continue
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if seen[relPath] {
+			continue
+		}
+
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			skipped = append(skipped, SkippedPath{Path: path, Reason: "does not exist"})
+			continue
+		}
+
+		switch {
+		case info.IsDir():
+			skipped = append(skipped, SkippedPath{Path: path, Reason: "is a directory"})
+		case !showHidden && utils.IsHiddenPath(relPath):
+			skipped = append(skipped, SkippedPath{Path: path, Reason: "is hidden"})
+		case useGitIgnore && gitIgnore != nil && gitIgnore.IsIgnored(fullPath):
+			skipped = append(skipped, SkippedPath{Path: path, Reason: "is ignored by .gitignore"})
+		case info.Size() > maxFileSize:
+			skipped = append(skipped, SkippedPath{Path: path, Reason: "exceeds the maximum file size"})
+		default:
+			seen[relPath] = true
+			accepted = append(accepted, relPath)
+		}
+	}
+
+	return accepted, skipped, nil
+}
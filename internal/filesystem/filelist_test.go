@@ -0,0 +1,151 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadFileList(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"Newline separated", "a.go\nb/c.go\n", []string{"a.go", "b/c.go"}},
+		{"CRLF and blank lines", "a.go\r\n\r\n  b.go  \n", []string{"a.go", "b.go"}},
+		{"NUL separated", "a.go\x00dir/with space.go\x00", []string{"a.go", "dir/with space.go"}},
+		{"Empty input", "", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			paths, err := ReadFileList(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("ReadFileList failed: %v", err)
+			}
+			if !reflect.DeepEqual(paths, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, paths)
+			}
+		})
+	}
+}
+
+func TestReadFileListFromEmptyStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	w.Close()
+	defer r.Close()
+
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	paths, err := ReadFileListFrom("-")
+	if err != nil {
+		t.Fatalf("ReadFileListFrom failed: %v", err)
+	}
+	if len(paths) != 0 {
+		t.Errorf("Expected no paths from empty stdin, got %v", paths)
+	}
+
+	// An empty list selects nothing, rather than being mistaken for no list at all
+	accepted, skipped, err := ValidateFileList(context.Background(), t.TempDir(), paths, nil, true, false, math.MaxInt64)
+	if err != nil {
+		t.Fatalf("ValidateFileList failed: %v", err)
+	}
+	if len(accepted) != 0 || len(skipped) != 0 {
+		t.Errorf("Expected an empty list to select nothing, got %v and %v", accepted, skipped)
+	}
+}
+
+func TestValidateFileList(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "filelist-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"main.go":        "package main",
+		"pkg/util.go":    "package pkg",
+		"debug.log":      "log output",
+		".env":           "SECRET=1",
+		"large.txt":      strings.Repeat("a", 200),
+		".gitignore":     "*.log\n",
+		"pkg/nested.txt": "nested",
+	}
+	for file, content := range files {
+		path := filepath.Join(tempDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file %s: %v", file, err)
+		}
+	}
+
+	gitIgnore, err := NewGitIgnoreManager(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create GitIgnoreManager: %v", err)
+	}
+
+	input := []string{
+		"main.go",
+		"./pkg/util.go",
+		filepath.Join(tempDir, "pkg", "nested.txt"),
+		"main.go",
+		"debug.log",
+		".env",
+		"large.txt",
+		"pkg",
+		"missing.go",
+		"../outside.go",
+	}
+
+	accepted, skipped, err := ValidateFileList(context.Background(), tempDir, input, gitIgnore, true, false, 100)
+	if err != nil {
+		t.Fatalf("ValidateFileList failed: %v", err)
+	}
+
+	expectedAccepted := []string{"main.go", "pkg/util.go", "pkg/nested.txt"}
+	if !reflect.DeepEqual(accepted, expectedAccepted) {
+		t.Errorf("Expected accepted %v, got %v", expectedAccepted, accepted)
+	}
+
+	expectedSkipped := map[string]string{
+		"debug.log":     "is ignored by .gitignore",
+		".env":          "is hidden",
+		"large.txt":     "exceeds the maximum file size",
+		"pkg":           "is a directory",
+		"missing.go":    "does not exist",
+		"../outside.go": "outside of the root directory",
+	}
+	if len(skipped) != len(expectedSkipped) {
+		t.Fatalf("Expected %d skipped paths, got %d: %v", len(expectedSkipped), len(skipped), skipped)
+	}
+	for _, s := range skipped {
+		if reason, ok := expectedSkipped[s.Path]; !ok || reason != s.Reason {
+			t.Errorf("Unexpected skip for %q: %q", s.Path, s.Reason)
+		}
+	}
+
+	accepted, _, _ = ValidateFileList(context.Background(), tempDir, []string{".env", "debug.log"}, gitIgnore, false, true, math.MaxInt64)
+	if len(accepted) != 2 {
+		t.Errorf("Expected hidden and ignored files to be accepted when rules are disabled, got %v", accepted)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	accepted, skipped, err = ValidateFileList(ctx, tempDir, input, gitIgnore, true, false, 100)
+	if !errors.Is(err, context.Canceled) || accepted != nil || skipped != nil {
+		t.Errorf("Expected context.Canceled and no paths, got %v, %v and %v", err, accepted, skipped)
+	}
+}
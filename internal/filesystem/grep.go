@@ -0,0 +1,119 @@
+package filesystem
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// GrepMatch describes the content matches found in a single file.
+type GrepMatch struct {
+	Path      string
+	FirstText string
+	Count     int
+	FirstLine int
+}
+
+// CompileGrepPattern builds the regular expression used for content search.
+// When literal is true the pattern is matched as a plain string.
+func CompileGrepPattern(pattern string, literal bool) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty search pattern")
+	}
+	if literal {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// GrepFiles searches the content of the given files, relative to root, in parallel.
+// It returns one GrepMatch per file that contains at least one match, sorted by path.
+// Files that cannot be read are skipped. It stops early, returning the context's
+// error, when ctx is cancelled.
+func GrepFiles(ctx context.Context, root string, paths []string, re *regexp.Regexp) ([]GrepMatch, error) {
+	if re == nil || len(paths) == 0 {
+		return nil, nil
+	}
+
+	workQueue := make(chan string, len(paths))
+	for _, path := range paths {
+		workQueue <- path
+	}
+	close(workQueue)
+
+	numWorkers := runtime.NumCPU()
+	if numWorkers > len(paths) {
+		numWorkers = len(paths)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var matches []GrepMatch
+
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range workQueue {
+				if ctx.Err() != nil {
+					return
+				}
+				if match, ok := grepFile(root, path, re); ok {
+					mu.Lock()
+					matches = append(matches, match)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Path < matches[j].Path
+	})
+	return matches, nil
+}
+
+// grepFile counts the matching lines of a single file and records the first one.
+func grepFile(root, path string, re *regexp.Regexp) (GrepMatch, bool) {
+	content, err := os.ReadFile(filepath.Join(root, path))
+	if err != nil || bytes.IndexByte(content, 0) != -1 {
+		return GrepMatch{}, false
+	}
+
+	match := GrepMatch{Path: path}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		if !re.Match(line) {
+			continue
+		}
+		match.Count++
+		if match.FirstLine == 0 {
+			match.FirstLine = lineNum
+			match.FirstText = strings.TrimSpace(string(line))
+		}
+	}
+
+	return match, match.Count > 0
+}
@@ -0,0 +1,95 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompileGrepPattern(t *testing.T) {
+	if _, err := CompileGrepPattern("", false); err == nil {
+		t.Error("Expected an error for an empty pattern")
+	}
+	if _, err := CompileGrepPattern("func (", false); err == nil {
+		t.Error("Expected an error for an invalid regular expression")
+	}
+
+	re, err := CompileGrepPattern("func (", true)
+	if err != nil {
+		t.Fatalf("Expected literal pattern to compile, got %v", err)
+	}
+	if !re.MatchString("func (m *Model) View()") {
+		t.Error("Expected literal pattern to match plain text")
+	}
+}
+
+func TestGrepFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "grep-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"a.go":       "package a\n\n// TODO: first\nfunc A() {}\n// TODO: second\n",
+		"b/b.go":     "package b\n\n  // todo lower case\n",
+		"c.txt":      "nothing to see here\n",
+		"binary.bin": "TODO\x00\x01",
+	}
+	for file, content := range files {
+		path := filepath.Join(tempDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file %s: %v", file, err)
+		}
+	}
+
+	paths := []string{"a.go", "b/b.go", "c.txt", "binary.bin", "missing.go"}
+
+	re, _ := CompileGrepPattern("TODO", false)
+	matches, err := GrepFiles(context.Background(), tempDir, paths, re)
+	if err != nil {
+		t.Fatalf("GrepFiles failed: %v", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 matching file, got %d: %v", len(matches), matches)
+	}
+	if matches[0].Path != "a.go" || matches[0].Count != 2 {
+		t.Errorf("Expected 2 hits in a.go, got %+v", matches[0])
+	}
+	if matches[0].FirstLine != 3 || matches[0].FirstText != "// TODO: first" {
+		t.Errorf("Unexpected first match: line %d %q", matches[0].FirstLine, matches[0].FirstText)
+	}
+
+	re, _ = CompileGrepPattern("(?i)todo", false)
+	matches, err = GrepFiles(context.Background(), tempDir, paths, re)
+	if err != nil {
+		t.Fatalf("GrepFiles failed: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 matching files for case-insensitive search, got %d", len(matches))
+	}
+	if matches[1].Path != "b/b.go" || matches[1].FirstText != "// todo lower case" {
+		t.Errorf("Expected trimmed first line for b/b.go, got %+v", matches[1])
+	}
+}
+
+func TestGrepFilesCancelled(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "a.go"), []byte("// TODO"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	re, _ := CompileGrepPattern("TODO", false)
+	matches, err := GrepFiles(ctx, tempDir, []string{"a.go"}, re)
+	if !errors.Is(err, context.Canceled) || matches != nil {
+		t.Errorf("Expected context.Canceled and no matches, got %v and %d matches", err, len(matches))
+	}
+}
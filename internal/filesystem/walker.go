@@ -1,12 +1,14 @@
 package filesystem
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/epilande/codegrab/internal/cache"
 	"github.com/epilande/codegrab/internal/utils"
@@ -14,10 +16,11 @@ import (
 
 // FileItem represents a file or directory found when walking the filesystem.
 type FileItem struct {
-	Path  string
-	IsDir bool
-	Level int
-	Size  int64
+	ModTime time.Time
+	Path    string
+	IsDir   bool
+	Level   int
+	Size    int64
 }
 
 // pathItem represents a discovered path to be processed
@@ -35,10 +38,18 @@ type ConcurrentWalker struct {
 	showHidden   bool
 	maxFileSize  int64
 	maxWorkers   int
+	onProgress   func(found int)
 }
 
 // WalkDirectory traverses the root directory taking into account gitignore, hidden files, and max file size.
-func WalkDirectory(root string, gitIgnore *GitIgnoreManager, filter *FilterManager, useGitIgnore, showHidden bool, maxFileSize int64) ([]FileItem, error) {
+// It stops early, returning the context's error, when ctx is cancelled.
+func WalkDirectory(ctx context.Context, root string, gitIgnore *GitIgnoreManager, filter *FilterManager, useGitIgnore, showHidden bool, maxFileSize int64) ([]FileItem, error) {
+	return WalkDirectoryWithProgress(ctx, root, gitIgnore, filter, useGitIgnore, showHidden, maxFileSize, nil)
+}
+
+// WalkDirectoryWithProgress is WalkDirectory, calling onProgress, when it is not nil,
+// with the number of files and directories found so far each time one is found.
+func WalkDirectoryWithProgress(ctx context.Context, root string, gitIgnore *GitIgnoreManager, filter *FilterManager, useGitIgnore, showHidden bool, maxFileSize int64, onProgress func(found int)) ([]FileItem, error) {
 	walker := &ConcurrentWalker{
 		root:         root,
 		gitIgnore:    gitIgnore,
@@ -47,13 +58,13 @@ func WalkDirectory(root string, gitIgnore *GitIgnoreManager, filter *FilterManag
 		showHidden:   showHidden,
 		maxFileSize:  maxFileSize,
 		maxWorkers:   runtime.NumCPU(),
+		onProgress:   onProgress,
 	}
-	return walker.walk()
+	return walker.walk(ctx)
 }
 
-
 // walk performs the concurrent directory traversal
-func (w *ConcurrentWalker) walk() ([]FileItem, error) {
+func (w *ConcurrentWalker) walk(ctx context.Context) ([]FileItem, error) {
 	if _, err := os.Stat(w.root); err != nil {
 		return nil, fmt.Errorf("failed to access root directory: %w", err)
 	}
@@ -79,6 +90,9 @@ func (w *ConcurrentWalker) walk() ([]FileItem, error) {
 		defer close(done)
 		for item := range resultQueue {
 			files = append(files, item)
+			if w.onProgress != nil {
+				w.onProgress(len(files))
+			}
 		}
 	}()
 
@@ -96,6 +110,9 @@ func (w *ConcurrentWalker) walk() ([]FileItem, error) {
 
 	// Discover paths using sequential filepath.Walk (directory traversal is I/O bound)
 	walkErr := filepath.Walk(w.root, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if err != nil {
 			select {
 			case errorChan <- fmt.Errorf("skipping %s: %w", path, err):
@@ -125,6 +142,8 @@ func (w *ConcurrentWalker) walk() ([]FileItem, error) {
 		case pathQueue <- pathItem{path: path, info: info}:
 		case <-done: // Stop if result collector finished (unlikely but safe)
 			return fmt.Errorf("result collection finished early")
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 
 		return nil
@@ -142,6 +161,9 @@ func (w *ConcurrentWalker) walk() ([]FileItem, error) {
 	<-done
 	<-errorDone
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if walkErr != nil {
 		return files, fmt.Errorf("error walking directory: %w", walkErr)
 	}
@@ -196,10 +218,11 @@ func (w *ConcurrentWalker) processPath(path string, info os.FileInfo) (*FileItem
 	// Handle directories
 	if info.IsDir() {
 		return &FileItem{
-			Path:  relPath,
-			IsDir: true,
-			Level: strings.Count(relPath, "/"),
-			Size:  info.Size(),
+			Path:    relPath,
+			IsDir:   true,
+			Level:   strings.Count(relPath, "/"),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
 		}, nil
 	}
 
@@ -221,9 +244,10 @@ func (w *ConcurrentWalker) processPath(path string, info os.FileInfo) (*FileItem
 	}
 
 	return &FileItem{
-		Path:  relPath,
-		IsDir: false,
-		Level: strings.Count(relPath, "/"),
-		Size:  info.Size(),
+		Path:    relPath,
+		IsDir:   false,
+		Level:   strings.Count(relPath, "/"),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
 	}, nil
 }
@@ -1,6 +1,8 @@
 package filesystem
 
 import (
+	"context"
+	"errors"
 	"math"
 	"os"
 	"path/filepath"
@@ -135,7 +137,7 @@ func TestWalkDirectory(t *testing.T) {
 					filter.AddGlobPattern(p)
 				}
 
-				files, err := WalkDirectory(tempDir, gitIgnore, filter, tc.useGitIgnore, tc.showHidden, tc.maxFileSize)
+				files, err := WalkDirectory(context.Background(), tempDir, gitIgnore, filter, tc.useGitIgnore, tc.showHidden, tc.maxFileSize)
 				if err != nil {
 					t.Fatalf("WalkDirectory failed: %v", err)
 				}
@@ -177,9 +179,44 @@ func TestWalkDirectory(t *testing.T) {
 		filter := NewFilterManager()
 		gitIgnore, _ := NewGitIgnoreManager(".")
 
-		_, err := WalkDirectory("/path/that/does/not/exist", gitIgnore, filter, false, false, math.MaxInt64)
+		_, err := WalkDirectory(context.Background(), "/path/that/does/not/exist", gitIgnore, filter, false, false, math.MaxInt64)
 		if err == nil {
 			t.Error("Expected error for non-existent directory, got nil")
 		}
 	})
 }
+
+func TestWalkDirectoryCancelled(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "file.txt"), []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	files, err := WalkDirectory(ctx, tempDir, nil, NewFilterManager(), false, false, math.MaxInt64)
+	if !errors.Is(err, context.Canceled) || files != nil {
+		t.Errorf("Expected context.Canceled and no files, got %v and %d files", err, len(files))
+	}
+}
+
+func TestWalkDirectoryWithProgress(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	var reported []int
+	files, err := WalkDirectoryWithProgress(context.Background(), tempDir, nil, NewFilterManager(), false, false, math.MaxInt64, func(found int) {
+		reported = append(reported, found)
+	})
+	if err != nil {
+		t.Fatalf("WalkDirectoryWithProgress failed: %v", err)
+	}
+	if len(reported) != len(files) || reported[len(reported)-1] != len(files) {
+		t.Errorf("Expected progress up to %d files, got %v", len(files), reported)
+	}
+}
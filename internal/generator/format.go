@@ -1,6 +1,17 @@
 package generator
 
-import "github.com/epilande/codegrab/internal/secrets"
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/epilande/codegrab/internal/git"
+	"github.com/epilande/codegrab/internal/secrets"
+	"github.com/epilande/codegrab/internal/symbols"
+	"github.com/epilande/codegrab/internal/utils"
+)
 
 // FileData holds file content for the generated sections
 type FileData struct {
@@ -8,16 +19,151 @@ type FileData struct {
 	Content  string
 	Language string
 	Findings []secrets.Finding
+	History  []git.Commit
+	// Lines splits the content into blocks of numbered lines when line numbers are enabled
+	Lines []LineBlock
+	// Ranges are the lines of the file the content is limited to, if only part of it is selected
+	Ranges []symbols.LineRange
+}
+
+// PartialLines returns the line ranges of a partially selected file, such as
+// "120-180, 200-210", or "" for a whole file
+func (f FileData) PartialLines() string {
+	return symbols.FormatRanges(f.Ranges)
+}
+
+// NumberedContent returns the content with each line prefixed with its line number,
+// or the content as is when it has no line blocks
+func (f FileData) NumberedContent() string {
+	if len(f.Lines) == 0 {
+		return f.Content
+	}
+
+	width := len(strconv.Itoa(f.Lines[len(f.Lines)-1].End()))
+	var sb strings.Builder
+	for _, block := range f.Lines {
+		for i, line := range block.lines() {
+			if sb.Len() > 0 {
+				sb.WriteByte('\n')
+			}
+			fmt.Fprintf(&sb, "%*d |", width, block.Start+i)
+			if line != "" {
+				sb.WriteString(" " + line)
+			}
+		}
+	}
+	if strings.HasSuffix(f.Content, "\n") {
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// LineBlock is a run of consecutive lines of a file
+type LineBlock struct {
+	Content string
+	// Start is the number of the first line of the block in the file, from 1
+	Start int
+}
+
+// End returns the number of the last line of the block
+func (b LineBlock) End() int {
+	return b.Start + len(b.lines()) - 1
+}
+
+func (b LineBlock) lines() []string {
+	if b.Content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(b.Content, "\n"), "\n")
+}
+
+// Metadata describes the repository revision and settings the output was generated from
+type Metadata struct {
+	GeneratedAt   time.Time
+	RepoName      string
+	Branch        string
+	CommitHash    string
+	CommitSubject string
+	Version       string
+	GlobPatterns  []string
+	IsGitRepo     bool
+	Dirty         bool
+	UseGitIgnore  bool
+	ShowHidden    bool
+	RedactSecrets bool
+}
+
+// MetadataField is a single labelled metadata value, used by formats to render metadata uniformly
+type MetadataField struct {
+	Name  string
+	Value string
+}
+
+// Fields returns the metadata as an ordered list of labelled values.
+// Git fields are omitted when the output was not generated from a Git repository.
+func (m *Metadata) Fields() []MetadataField {
+	var fields []MetadataField
+	if m.RepoName != "" {
+		fields = append(fields, MetadataField{"Repository", m.RepoName})
+	}
+	if m.IsGitRepo {
+		if m.Branch != "" {
+			fields = append(fields, MetadataField{"Branch", m.Branch})
+		}
+		if m.CommitHash != "" {
+			fields = append(fields, MetadataField{"Commit", m.CommitHash})
+			fields = append(fields, MetadataField{"Commit Subject", m.CommitSubject})
+		}
+		fields = append(fields, MetadataField{"Dirty", fmt.Sprintf("%t", m.Dirty)})
+	}
+	fields = append(fields, MetadataField{"Generated At", m.GeneratedAt.Format(time.RFC3339)})
+	fields = append(fields, MetadataField{"Codegrab Version", m.Version})
+
+	globs := "none"
+	if len(m.GlobPatterns) > 0 {
+		globs = strings.Join(m.GlobPatterns, ", ")
+	}
+	fields = append(fields, MetadataField{"Glob Patterns", globs})
+	fields = append(fields, MetadataField{"Respect .gitignore", fmt.Sprintf("%t", m.UseGitIgnore)})
+	fields = append(fields, MetadataField{"Show Hidden", fmt.Sprintf("%t", m.ShowHidden)})
+	fields = append(fields, MetadataField{"Redact Secrets", fmt.Sprintf("%t", m.RedactSecrets)})
+	return fields
 }
 
 // TemplateData is injected into the templates
 type TemplateData struct {
+	Metadata  *Metadata
 	Structure string
 	Files     []FileData
 }
 
-// Format defines the interface for different output formats
+// Header is what a format writes before the files of the output
+type Header struct {
+	Metadata  *Metadata
+	Structure string
+	// Paths are the paths of the files that follow, in output order
+	Paths []string
+}
+
+// Format defines the interface for different output formats. Output is streamed: the
+// generator calls WriteHeader, then WriteFile for each file in turn, then WriteFooter,
+// so that only one file has to be held in memory at a time.
 type Format interface {
+	// WriteHeader writes everything that comes before the files
+	WriteHeader(w io.Writer, header Header) error
+	// WriteFile writes a file, with its content already redacted
+	WriteFile(w io.Writer, file FileData) error
+	// WriteFooter writes everything that comes after the files
+	WriteFooter(w io.Writer) error
+	// Extension returns the file extension for this format
+	Extension() string
+	// Name returns the name of the format
+	Name() string
+}
+
+// RenderFormat is a format that renders the whole output at once, as formats did
+// before output was streamed. AdaptRenderFormat turns it into a Format.
+type RenderFormat interface {
 	// Render converts the template data into the specific format
 	Render(data TemplateData) (string, int, error)
 	// Extension returns the file extension for this format
@@ -25,3 +171,61 @@ type Format interface {
 	// Name returns the name of the format
 	Name() string
 }
+
+// renderFormatAdapter collects the files written to it and renders them with a
+// RenderFormat when the footer is written
+type renderFormatAdapter struct {
+	RenderFormat
+	data TemplateData
+}
+
+// AdaptRenderFormat returns a Format that renders with f. All files are held in memory
+// until the output is rendered, as before streaming.
+func AdaptRenderFormat(f RenderFormat) Format {
+	return &renderFormatAdapter{RenderFormat: f}
+}
+
+func (a *renderFormatAdapter) WriteHeader(w io.Writer, header Header) error {
+	a.data = TemplateData{Metadata: header.Metadata, Structure: header.Structure}
+	return nil
+}
+
+func (a *renderFormatAdapter) WriteFile(w io.Writer, file FileData) error {
+	a.data.Files = append(a.data.Files, file)
+	return nil
+}
+
+func (a *renderFormatAdapter) WriteFooter(w io.Writer) error {
+	content, _, err := a.Render(a.data)
+	a.data = TemplateData{}
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, content)
+	return err
+}
+
+// RenderString renders template data with a format into a string, returning the
+// output and its estimated number of tokens
+func RenderString(f Format, data TemplateData) (string, int, error) {
+	header := Header{Metadata: data.Metadata, Structure: data.Structure}
+	for _, file := range data.Files {
+		header.Paths = append(header.Paths, file.Path)
+	}
+
+	var b strings.Builder
+	if err := f.WriteHeader(&b, header); err != nil {
+		return "", 0, err
+	}
+	for _, file := range data.Files {
+		if err := f.WriteFile(&b, file); err != nil {
+			return "", 0, err
+		}
+	}
+	if err := f.WriteFooter(&b); err != nil {
+		return "", 0, err
+	}
+
+	content := b.String()
+	return content, utils.EstimateTokens(content), nil
+}
@@ -1,6 +1,8 @@
 package generator
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -39,3 +41,84 @@ func TestTemplateData(t *testing.T) {
 		t.Errorf("Expected Language to be %q, got %q", "text", file.Language)
 	}
 }
+
+// legacyFormat renders the whole output at once
+type legacyFormat struct{}
+
+func (legacyFormat) Render(data TemplateData) (string, int, error) {
+	var b strings.Builder
+	b.WriteString(data.Structure)
+	for _, file := range data.Files {
+		fmt.Fprintf(&b, "%s: %s\n", file.Path, file.Content)
+	}
+	return b.String(), 1, nil
+}
+
+func (legacyFormat) Extension() string { return ".legacy" }
+func (legacyFormat) Name() string      { return "legacy" }
+
+func TestAdaptRenderFormat(t *testing.T) {
+	format := AdaptRenderFormat(legacyFormat{})
+	if format.Name() != "legacy" || format.Extension() != ".legacy" {
+		t.Errorf("Expected the adapter to keep the name and extension, got %q and %q", format.Name(), format.Extension())
+	}
+
+	data := TemplateData{
+		Structure: "root/\n",
+		Files: []FileData{
+			{Path: "a.txt", Content: "first"},
+			{Path: "b.txt", Content: "second"},
+		},
+	}
+	for i := 0; i < 2; i++ {
+		content, tokens, err := RenderString(format, data)
+		if err != nil {
+			t.Fatalf("RenderString failed: %v", err)
+		}
+		expected := "root/\na.txt: first\nb.txt: second\n"
+		if content != expected {
+			t.Errorf("Expected %q, got %q", expected, content)
+		}
+		if tokens != len(expected)/4 {
+			t.Errorf("Expected tokens to be estimated from the output, got %d", tokens)
+		}
+	}
+}
+
+func TestNumberedContent(t *testing.T) {
+	testCases := []struct {
+		name     string
+		file     FileData
+		expected string
+	}{
+		{
+			name:     "No line blocks",
+			file:     FileData{Content: "a\nb\n"},
+			expected: "a\nb\n",
+		},
+		{
+			name: "Whole file",
+			file: FileData{
+				Content: "a\n\nb\n",
+				Lines:   []LineBlock{{Content: "a\n\nb\n", Start: 1}},
+			},
+			expected: "1 | a\n2 |\n3 | b\n",
+		},
+		{
+			name: "Blocks with a gap are padded to the widest number",
+			file: FileData{
+				Content: "a\nb",
+				Lines:   []LineBlock{{Content: "a", Start: 9}, {Content: "b", Start: 120}},
+			},
+			expected: "  9 | a\n120 | b",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.file.NumberedContent(); got != tc.expected {
+				t.Errorf("NumberedContent() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
@@ -3,8 +3,11 @@ package formats
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/epilande/codegrab/internal/generator"
+	"github.com/epilande/codegrab/internal/git"
+	"github.com/epilande/codegrab/internal/symbols"
 )
 
 func TestGetFormat(t *testing.T) {
@@ -47,6 +50,31 @@ func TestGetFormatNames(t *testing.T) {
 	}
 }
 
+func TestRegister(t *testing.T) {
+	if err := Register("markdown", func() generator.Format { return &TxtFormat{} }); err == nil {
+		t.Errorf("Expected registering an existing format name to fail")
+	}
+	if err := Register("", func() generator.Format { return &TxtFormat{} }); err == nil {
+		t.Errorf("Expected registering a format without a name to fail")
+	}
+
+	if err := Register("test-plain", func() generator.Format { return &TxtFormat{} }); err != nil {
+		t.Fatalf("Register returned an error: %v", err)
+	}
+	defer func() {
+		registryMu.Lock()
+		delete(formatRegistry, "test-plain")
+		registryMu.Unlock()
+	}()
+
+	if !IsRegistered("test-plain") {
+		t.Errorf("Expected test-plain to be registered")
+	}
+	if _, ok := GetFormat("test-plain").(*TxtFormat); !ok {
+		t.Errorf("Expected GetFormat to use the registered constructor")
+	}
+}
+
 func TestMarkdownFormat(t *testing.T) {
 	format := &MarkdownFormat{}
 
@@ -156,6 +184,194 @@ func createTestTemplateData() generator.TemplateData {
 	}
 }
 
+func createTestMetadata() *generator.Metadata {
+	return &generator.Metadata{
+		GeneratedAt:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		RepoName:      "test-project",
+		Branch:        "main",
+		CommitHash:    "0123456789abcdef0123456789abcdef01234567",
+		CommitSubject: "Fix <parser> & lexer",
+		Version:       "1.2.3",
+		GlobPatterns:  []string{"*.go", "!*_test.go"},
+		IsGitRepo:     true,
+		Dirty:         true,
+		UseGitIgnore:  true,
+		RedactSecrets: true,
+	}
+}
+
+func TestFormatsRenderMetadata(t *testing.T) {
+	testCases := []struct {
+		format   string
+		expected []string
+	}{
+		{"markdown", []string{
+			"# Metadata",
+			"- **Repository**: test-project",
+			"- **Commit**: 0123456789abcdef0123456789abcdef01234567",
+			"- **Dirty**: true",
+			"- **Generated At**: 2025-01-02T03:04:05Z",
+			"- **Glob Patterns**: *.go, !*_test.go",
+		}},
+		{"text", []string{
+			"METADATA",
+			"Branch: main",
+			"Commit Subject: Fix <parser> & lexer",
+			"Codegrab Version: 1.2.3",
+		}},
+		{"xml", []string{
+			"<repository>test-project</repository>",
+			`<commit hash="0123456789abcdef0123456789abcdef01234567">Fix &lt;parser&gt; &amp; lexer</commit>`,
+			"<dirty>true</dirty>",
+			`<filters gitignore="true" hidden="false" redactSecrets="true">`,
+			"<glob>!*_test.go</glob>",
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			data := createTestTemplateData()
+			data.Metadata = createTestMetadata()
+
+			content, _, err := generator.RenderString(GetFormat(tc.format), data)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			for _, expected := range tc.expected {
+				if !strings.Contains(content, expected) {
+					t.Errorf("Expected %s output to contain %q, got:\n%s", tc.format, expected, content)
+				}
+			}
+
+			data.Metadata = nil
+			content, _, err = generator.RenderString(GetFormat(tc.format), data)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if strings.Contains(strings.ToLower(content), "metadata") {
+				t.Errorf("Expected no metadata in %s output when it is not set", tc.format)
+			}
+		})
+	}
+}
+
+func TestFormatsRenderHistory(t *testing.T) {
+	testCases := []struct {
+		format   string
+		expected []string
+	}{
+		{"markdown", []string{
+			"### History: `main.go`",
+			"- `0123456` 2025-01-02T03:04:05Z Add greeting",
+			"```diff\n+\tprintln(\"Hello, World!\")\n```",
+			"- `89abcde` 2025-01-01T00:00:00Z Initial commit",
+		}},
+		{"text", []string{
+			"HISTORY: main.go",
+			"0123456 2025-01-02T03:04:05Z Add greeting",
+			"89abcde 2025-01-01T00:00:00Z Initial commit",
+		}},
+		{"xml", []string{
+			"<history>",
+			`<commit hash="0123456789abcdef0123456789abcdef01234567" date="2025-01-02T03:04:05Z">`,
+			"<subject>Add greeting</subject>",
+			"<diff><![CDATA[+\tprintln(\"Hello, World!\")]]></diff>",
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			data := createTestTemplateData()
+			data.Files[0].History = []git.Commit{
+				{
+					Hash:       "0123456789abcdef0123456789abcdef01234567",
+					AuthorDate: "2025-01-02T03:04:05Z",
+					Subject:    "Add greeting",
+					Diff:       "+\tprintln(\"Hello, World!\")",
+				},
+				{
+					Hash:       "89abcdef0123456789abcdef0123456789abcdef",
+					AuthorDate: "2025-01-01T00:00:00Z",
+					Subject:    "Initial commit",
+				},
+			}
+
+			content, _, err := generator.RenderString(GetFormat(tc.format), data)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			for _, expected := range tc.expected {
+				if !strings.Contains(content, expected) {
+					t.Errorf("Expected %s output to contain %q, got:\n%s", tc.format, expected, content)
+				}
+			}
+
+			data.Files[0].History = nil
+			content, _, err = generator.RenderString(GetFormat(tc.format), data)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if strings.Contains(strings.ToLower(content), "history") {
+				t.Errorf("Expected no history in %s output when it is not set", tc.format)
+			}
+		})
+	}
+}
+
+func TestFormatsRenderLineNumbers(t *testing.T) {
+	testCases := []struct {
+		format   string
+		expected []string
+	}{
+		{"markdown", []string{"```go\n1 | package main\n2 |\n3 | func main() {\n4 | \tprintln"}},
+		{"text", []string{"1 | package main\n2 |\n3 | func main() {\n4 | \tprintln"}},
+		{"xml", []string{`<lines start="1" end="5"><![CDATA[package main`}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			data := createTestTemplateData()
+			data.Files[0].Lines = []generator.LineBlock{{Content: data.Files[0].Content, Start: 1}}
+
+			content, _, err := generator.RenderString(GetFormat(tc.format), data)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			for _, expected := range tc.expected {
+				if !strings.Contains(content, expected) {
+					t.Errorf("Expected %s output to contain %q, got:\n%s", tc.format, expected, content)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatsMarkPartialFiles(t *testing.T) {
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{"markdown", "## File: `main.go` (partial: lines 3-5, 9)"},
+		{"text", "FILE: main.go (partial: lines 3-5, 9)"},
+		{"xml", `<file path="main.go" language="go" partial="true" range="3-5, 9">`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			data := createTestTemplateData()
+			data.Files[0].Ranges = []symbols.LineRange{{Start: 3, End: 5}, {Start: 9, End: 9}}
+
+			content, _, err := generator.RenderString(GetFormat(tc.format), data)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if !strings.Contains(content, tc.expected) {
+				t.Errorf("Expected %s output to contain %q, got:\n%s", tc.format, tc.expected, content)
+			}
+		})
+	}
+}
+
 func TestAddFileToTree(t *testing.T) {
 	root := &directoryEntry{
 		name:    ".",
@@ -1,32 +1,47 @@
 package formats
 
 import (
-	"bytes"
 	"fmt"
+	"io"
 	"text/template"
 
 	"github.com/epilande/codegrab/internal/generator"
-	"github.com/epilande/codegrab/internal/utils"
 )
 
 // MarkdownFormat implements the generator.Format interface for Markdown output
 type MarkdownFormat struct{}
 
-// Render converts the template data into Markdown format
-func (f *MarkdownFormat) Render(data generator.TemplateData) (string, int, error) {
-	tmpl, err := template.New("markdown").Parse(markdownTemplate)
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to parse template: %w", err)
+// The templates of the generated markdown, for what comes before the files and for each file
+var (
+	markdownHeaderTemplate = template.Must(template.New("markdown").Parse(markdownHeader))
+	markdownFileTemplate   = template.Must(template.New("markdown-file").Parse(markdownFile))
+)
+
+// WriteHeader writes the metadata and the project structure
+func (f *MarkdownFormat) WriteHeader(w io.Writer, header generator.Header) error {
+	if err := markdownHeaderTemplate.Execute(w, header); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
 	}
+	return nil
+}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", 0, fmt.Errorf("failed to execute template: %w", err)
+// WriteFile writes a file with its history
+func (f *MarkdownFormat) WriteFile(w io.Writer, file generator.FileData) error {
+	if err := markdownFileTemplate.Execute(w, file); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
 	}
+	return nil
+}
+
+// WriteFooter ends the output
+func (f *MarkdownFormat) WriteFooter(w io.Writer) error {
+	_, err := io.WriteString(w, "\n")
+	return err
+}
 
-	markdownContent := buf.String()
-	tokenCount := utils.EstimateTokens(markdownContent)
-	return markdownContent, tokenCount, nil
+// Render converts the template data into Markdown format
+func (f *MarkdownFormat) Render(data generator.TemplateData) (string, int, error) {
+	return generator.RenderString(f, data)
 }
 
 // Extension returns the file extension for Markdown
@@ -39,18 +54,32 @@ func (f *MarkdownFormat) Name() string {
 	return "markdown"
 }
 
-// The base template for our generated markdown
-const markdownTemplate = `# Project Structure
+const markdownHeader = `{{if .Metadata}}# Metadata
+
+{{range .Metadata.Fields}}- **{{.Name}}**: {{.Value}}
+{{end}}
+{{end}}# Project Structure
 
 ` + "```" + `
 {{.Structure}}` + "```" + `
 
 # Project Files
-{{range .Files}}
-## File: ` + "`" + `{{.Path}}` + "`" + `
+`
+
+const markdownFile = `
+## File: ` + "`" + `{{.Path}}` + "`" + `{{with .PartialLines}} (partial: lines {{.}}){{end}}
 
 ` + "```" + `{{.Language}}
-{{.Content}}
+{{.NumberedContent}}
 ` + "```" + `
-{{end}}
-`
+{{if .History}}
+### History: ` + "`" + `{{.Path}}` + "`" + `
+{{range .History}}
+- ` + "`" + `{{.ShortHash}}` + "`" + ` {{.AuthorDate}} {{.Subject}}
+{{- if .Diff}}
+
+` + "```" + `diff
+{{.Diff}}
+` + "```" + `
+{{- end}}
+{{end}}{{end}}`
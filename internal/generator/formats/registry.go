@@ -1,6 +1,10 @@
 package formats
 
 import (
+	"fmt"
+	"sort"
+	"sync"
+
 	"github.com/epilande/codegrab/internal/generator"
 )
 
@@ -11,20 +15,58 @@ var formatRegistry = map[string]func() generator.Format{
 	"xml":      func() generator.Format { return &XMLFormat{} },
 }
 
+var registryMu sync.RWMutex
+
+// Register adds a format to the registry, making it available by name
+func Register(name string, constructor func() generator.Format) error {
+	if name == "" || constructor == nil {
+		return fmt.Errorf("a format needs a name and a constructor")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := formatRegistry[name]; exists {
+		return fmt.Errorf("format %q is already registered", name)
+	}
+	formatRegistry[name] = constructor
+	return nil
+}
+
 // GetFormat returns a format by name, or the default if not found
 func GetFormat(name string) generator.Format {
-	if constructor, exists := formatRegistry[name]; exists {
-		return constructor()
+	return Constructor(name)()
+}
+
+// Constructor returns the constructor of a format by name, or of the default if not
+// found. Formats may keep state while an output is written, so generators are given
+// the constructor to make a format for each output.
+func Constructor(name string) func() generator.Format {
+	registryMu.RLock()
+	constructor, exists := formatRegistry[name]
+	registryMu.RUnlock()
+	if exists {
+		return constructor
 	}
 	// Default to markdown if format not found
-	return &MarkdownFormat{}
+	return func() generator.Format { return &MarkdownFormat{} }
+}
+
+// IsRegistered reports whether a format with the given name exists
+func IsRegistered(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, exists := formatRegistry[name]
+	return exists
 }
 
-// GetFormatNames returns a list of available format names
+// GetFormatNames returns a sorted list of available format names
 func GetFormatNames() []string {
+	registryMu.RLock()
 	names := make([]string, 0, len(formatRegistry))
 	for name := range formatRegistry {
 		names = append(names, name)
 	}
+	registryMu.RUnlock()
+	sort.Strings(names)
 	return names
 }
@@ -1,44 +1,58 @@
 package formats
 
 import (
-	"bytes"
 	"fmt"
+	"io"
 	"strings"
 	"text/template"
 
 	"github.com/epilande/codegrab/internal/generator"
-	"github.com/epilande/codegrab/internal/utils"
 )
 
 // TxtFormat implements the generator.Format interface for plain text output
 type TxtFormat struct{}
 
-// Render converts the template data into plain text format
-func (f *TxtFormat) Render(data generator.TemplateData) (string, int, error) {
-	tmpl := template.New("txt").Funcs(template.FuncMap{
-		"separator": func(s ...string) string {
-			length := 60
-			if len(s) > 0 && len(s[0]) > length {
-				length = len("FILE: " + s[0])
-			}
-			return strings.Repeat("=", length)
-		},
-	})
-
-	// Parse the template
-	tmpl, err := tmpl.Parse(txtTemplate)
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to parse template: %w", err)
+var txtFuncs = template.FuncMap{
+	"separator": func(s ...string) string {
+		length := 60
+		if len(s) > 0 && len(s[0]) > length {
+			length = len("FILE: " + s[0])
+		}
+		return strings.Repeat("=", length)
+	},
+}
+
+// The templates of the generated plain text, for what comes before the files and for each file
+var (
+	txtHeaderTemplate = template.Must(template.New("txt").Funcs(txtFuncs).Parse(txtHeader))
+	txtFileTemplate   = template.Must(template.New("txt-file").Funcs(txtFuncs).Parse(txtFile))
+)
+
+// WriteHeader writes the metadata and the project structure
+func (f *TxtFormat) WriteHeader(w io.Writer, header generator.Header) error {
+	if err := txtHeaderTemplate.Execute(w, header); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
 	}
+	return nil
+}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", 0, fmt.Errorf("failed to execute template: %w", err)
+// WriteFile writes a file with its history
+func (f *TxtFormat) WriteFile(w io.Writer, file generator.FileData) error {
+	if err := txtFileTemplate.Execute(w, file); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
 	}
+	return nil
+}
+
+// WriteFooter ends the output
+func (f *TxtFormat) WriteFooter(w io.Writer) error {
+	_, err := io.WriteString(w, "\n")
+	return err
+}
 
-	txtContent := buf.String()
-	tokenCount := utils.EstimateTokens(txtContent)
-	return txtContent, tokenCount, nil
+// Render converts the template data into plain text format
+func (f *TxtFormat) Render(data generator.TemplateData) (string, int, error) {
+	return generator.RenderString(f, data)
 }
 
 // Extension returns the file extension for plain text
@@ -51,8 +65,13 @@ func (f *TxtFormat) Name() string {
 	return "text"
 }
 
-// The base template for our generated plain text
-const txtTemplate = `{{separator}}
+const txtHeader = `{{if .Metadata}}{{separator}}
+METADATA
+{{separator}}
+
+{{range .Metadata.Fields}}{{.Name}}: {{.Value}}
+{{end}}
+{{end}}{{separator}}
 PROJECT STRUCTURE
 {{separator}}
 
@@ -61,11 +80,20 @@ PROJECT STRUCTURE
 {{separator}}
 PROJECT FILES
 {{separator}}
-{{range .Files}}
+`
+
+const txtFile = `
 {{separator .Path}}
-FILE: {{.Path}}
+FILE: {{.Path}}{{with .PartialLines}} (partial: lines {{.}}){{end}}
 {{separator .Path}}
 
-{{.Content}}
-{{end}}
-`
+{{.NumberedContent}}
+{{if .History}}
+HISTORY: {{.Path}}
+{{range .History}}
+{{.ShortHash}} {{.AuthorDate}} {{.Subject}}
+{{- if .Diff}}
+
+{{.Diff}}
+{{- end}}
+{{end}}{{end}}`
@@ -3,22 +3,45 @@ package formats
 import (
 	"encoding/xml"
 	"fmt"
+	"io"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/epilande/codegrab/internal/generator"
-	"github.com/epilande/codegrab/internal/utils"
 )
 
-// XMLFormat implements the generator.Format interface for XML output
-type XMLFormat struct{}
+// XMLFormat implements the generator.Format interface for XML output. The output is a
+// project element with the metadata, the filesystem and the files.
+type XMLFormat struct {
+	// hasFiles records whether a files element was opened by the header
+	hasFiles bool
+}
+
+// XMLMetadata represents the repository revision and generation settings
+type XMLMetadata struct {
+	Repository  string     `xml:"repository,omitempty"`
+	Branch      string     `xml:"branch,omitempty"`
+	Commit      *XMLCommit `xml:"commit,omitempty"`
+	Dirty       *bool      `xml:"dirty,omitempty"`
+	GeneratedAt string     `xml:"generatedAt"`
+	Version     string     `xml:"codegrabVersion"`
+	Filters     XMLFilters `xml:"filters"`
+}
 
-// XMLProject represents the root XML element
-type XMLProject struct {
-	XMLName    xml.Name      `xml:"project"`
-	Filesystem XMLFilesystem `xml:"filesystem"`
-	Files      []XMLFile     `xml:"files>file"`
+// XMLCommit represents the HEAD commit of the repository
+type XMLCommit struct {
+	Hash    string `xml:"hash,attr"`
+	Subject string `xml:",chardata"`
+}
+
+// XMLFilters represents the filter settings used to select files
+type XMLFilters struct {
+	UseGitIgnore  bool     `xml:"gitignore,attr"`
+	ShowHidden    bool     `xml:"hidden,attr"`
+	RedactSecrets bool     `xml:"redactSecrets,attr"`
+	Globs         []string `xml:"glob"`
 }
 
 // XMLFilesystem represents the directory structure
@@ -38,11 +61,41 @@ type XMLFileRef struct {
 	Name string `xml:"name,attr"`
 }
 
-// XMLFile represents a file with its content
+// XMLFile represents a file with its content, or with its blocks of numbered lines
+// when line numbers are enabled. Partially selected files give their line ranges.
 type XMLFile struct {
-	Path     string `xml:"path,attr"`
-	Language string `xml:"language,attr"`
-	Content  string `xml:",cdata"`
+	Path     string      `xml:"path,attr"`
+	Language string      `xml:"language,attr"`
+	Partial  bool        `xml:"partial,attr,omitempty"`
+	Range    string      `xml:"range,attr,omitempty"`
+	Content  string      `xml:",cdata"`
+	Lines    []XMLLines  `xml:"lines,omitempty"`
+	History  *XMLHistory `xml:"history,omitempty"`
+}
+
+// XMLLines represents a block of consecutive lines of a file
+type XMLLines struct {
+	Start   int    `xml:"start,attr"`
+	End     int    `xml:"end,attr"`
+	Content string `xml:",cdata"`
+}
+
+// XMLHistory represents the recent commits that touched a file
+type XMLHistory struct {
+	Commits []XMLHistoryEntry `xml:"commit"`
+}
+
+// XMLHistoryEntry represents a commit that touched a file
+type XMLHistoryEntry struct {
+	Hash       string   `xml:"hash,attr"`
+	AuthorDate string   `xml:"date,attr"`
+	Subject    string   `xml:"subject"`
+	Diff       *XMLDiff `xml:"diff,omitempty"`
+}
+
+// XMLDiff represents the patch a commit applied to a file
+type XMLDiff struct {
+	Content string `xml:",cdata"`
 }
 
 // directoryEntry represents a directory in our internal tree structure
@@ -52,8 +105,8 @@ type directoryEntry struct {
 	files   []string
 }
 
-// Render converts the template data into XML format
-func (f *XMLFormat) Render(data generator.TemplateData) (string, int, error) {
+// WriteHeader writes the metadata and the filesystem, and opens the files element
+func (f *XMLFormat) WriteHeader(w io.Writer, header generator.Header) error {
 	// Create the root directory
 	root := &directoryEntry{
 		name:    ".",
@@ -61,38 +114,125 @@ func (f *XMLFormat) Render(data generator.TemplateData) (string, int, error) {
 		files:   []string{},
 	}
 
-	// Get all file paths from the files data
-	for _, file := range data.Files {
-		addFileToTree(root, file.Path)
+	// Add the paths of the files that follow
+	for _, path := range header.Paths {
+		addFileToTree(root, path)
 	}
 
+	if _, err := io.WriteString(w, xml.Header+"<project>\n"); err != nil {
+		return err
+	}
+	if metadata := convertToXMLMetadata(header.Metadata); metadata != nil {
+		if err := encodeXMLElement(w, metadata, "metadata", "  "); err != nil {
+			return err
+		}
+	}
 	// Convert our internal tree to the XML structure
-	xmlRoot := convertToXMLDirectory(root)
+	filesystem := XMLFilesystem{Root: convertToXMLDirectory(root)}
+	if err := encodeXMLElement(w, filesystem, "filesystem", "  "); err != nil {
+		return err
+	}
 
-	// Create the XML project
-	xmlProject := XMLProject{
-		Filesystem: XMLFilesystem{
-			Root: xmlRoot,
-		},
-		Files: make([]XMLFile, len(data.Files)),
+	f.hasFiles = len(header.Paths) > 0
+	if f.hasFiles {
+		_, err := io.WriteString(w, "  <files>\n")
+		return err
 	}
+	return nil
+}
 
-	for i, file := range data.Files {
-		xmlProject.Files[i] = XMLFile{
-			Path:     file.Path,
-			Language: file.Language,
-			Content:  file.Content,
+// WriteFile writes a file with its content and history
+func (f *XMLFormat) WriteFile(w io.Writer, file generator.FileData) error {
+	xmlFile := XMLFile{
+		Path:     file.Path,
+		Language: file.Language,
+		Partial:  len(file.Ranges) > 0,
+		Range:    file.PartialLines(),
+	}
+	if len(file.Lines) == 0 {
+		xmlFile.Content = file.Content
+	}
+	for _, block := range file.Lines {
+		xmlFile.Lines = append(xmlFile.Lines, XMLLines{Start: block.Start, End: block.End(), Content: block.Content})
+	}
+	if len(file.History) > 0 {
+		xmlFile.History = &XMLHistory{}
+	}
+	for _, commit := range file.History {
+		entry := XMLHistoryEntry{
+			Hash:       commit.Hash,
+			AuthorDate: commit.AuthorDate,
+			Subject:    commit.Subject,
+		}
+		if commit.Diff != "" {
+			entry.Diff = &XMLDiff{Content: commit.Diff}
 		}
+		xmlFile.History.Commits = append(xmlFile.History.Commits, entry)
+	}
+
+	return encodeXMLElement(w, xmlFile, "file", "    ")
+}
+
+// WriteFooter closes the files and project elements
+func (f *XMLFormat) WriteFooter(w io.Writer) error {
+	footer := "</project>"
+	if f.hasFiles {
+		footer = "  </files>\n" + footer
 	}
+	_, err := io.WriteString(w, footer)
+	return err
+}
+
+// Render converts the template data into XML format
+func (f *XMLFormat) Render(data generator.TemplateData) (string, int, error) {
+	return generator.RenderString(f, data)
+}
 
-	output, err := xml.MarshalIndent(xmlProject, "", "  ")
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to marshal XML: %w", err)
+// encodeXMLElement writes an indented element on its own lines, starting with prefix
+func encodeXMLElement(w io.Writer, v any, name, prefix string) error {
+	enc := xml.NewEncoder(w)
+	enc.Indent(prefix, "  ")
+	if err := enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+		return fmt.Errorf("failed to marshal XML: %w", err)
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// convertToXMLMetadata converts the generator metadata to its XML representation
+func convertToXMLMetadata(metadata *generator.Metadata) *XMLMetadata {
+	if metadata == nil {
+		return nil
+	}
+
+	xmlMetadata := &XMLMetadata{
+		Repository:  metadata.RepoName,
+		GeneratedAt: metadata.GeneratedAt.Format(time.RFC3339),
+		Version:     metadata.Version,
+		Filters: XMLFilters{
+			UseGitIgnore:  metadata.UseGitIgnore,
+			ShowHidden:    metadata.ShowHidden,
+			RedactSecrets: metadata.RedactSecrets,
+			Globs:         metadata.GlobPatterns,
+		},
+	}
+
+	if metadata.IsGitRepo {
+		xmlMetadata.Branch = metadata.Branch
+		if metadata.CommitHash != "" {
+			xmlMetadata.Commit = &XMLCommit{
+				Hash:    metadata.CommitHash,
+				Subject: metadata.CommitSubject,
+			}
+		}
+		dirty := metadata.Dirty
+		xmlMetadata.Dirty = &dirty
 	}
 
-	xmlContent := xml.Header + string(output)
-	tokenCount := utils.EstimateTokens(xmlContent)
-	return xmlContent, tokenCount, nil
+	return xmlMetadata
 }
 
 // addFileToTree adds a file path to our directory tree
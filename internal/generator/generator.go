@@ -1,46 +1,92 @@
 package generator
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/epilande/codegrab/internal/cache"
 	"github.com/epilande/codegrab/internal/filesystem"
+	"github.com/epilande/codegrab/internal/git"
 	"github.com/epilande/codegrab/internal/secrets"
+	"github.com/epilande/codegrab/internal/symbols"
 	"github.com/epilande/codegrab/internal/utils"
 )
 
+// StdoutPath is the output path that directs the rendered output to stdout
+const StdoutPath = "-"
+
 // Generator organizes how we generate the output in different formats
 type Generator struct {
-	format          Format
+	format Format
+	// newFormat, if set, makes the format each output is written with, so that
+	// formats keeping state between their calls are never shared by two outputs
+	newFormat       func() Format
 	SecretScanner   secrets.Scanner
 	SelectedFiles   map[string]bool
 	DeselectedFiles map[string]bool
 	GitIgnoreMgr    *filesystem.GitIgnoreManager
 	FilterMgr       *filesystem.FilterManager
+	Stdout          io.Writer
 	OutputPath      string
 	RootPath        string
 	UseTempFile     bool
+	UseStdout       bool
 	UseGitIgnore    bool
 	ShowHidden      bool
 	RedactSecrets   bool
+	IncludeMetadata bool
+	HistoryDiff     bool
+	HistoryDepth    int
+	// LineRanges limits selected files to some of their lines
+	LineRanges map[string][]symbols.LineRange
+	// LineNumbers prefixes each line of the files with its line number
+	LineNumbers bool
+	// OnProgress, if set, is called as the output is generated
+	OnProgress      func(Progress)
 	lastSecretCount int
+	lastFileTokens  []FileTokenCount
+}
+
+// Progress reports how far the generation of an output is
+type Progress struct {
+	// FilesScanned is the number of files read, scanned and written so far
+	FilesScanned int
+	// TotalFiles is the number of files of the output
+	TotalFiles int
+	// BytesRead is the size of the files written so far
+	BytesRead int64
+	// SecretsFound is the number of secrets found so far
+	SecretsFound int
 }
 
-// NewGenerator constructs a generator with default settings
-func NewGenerator(rootPath string, gitIgnoreMgr *filesystem.GitIgnoreManager, filterMgr *filesystem.FilterManager, outputPath string, useTempFile bool) *Generator {
+// FileTokenCount is the estimated number of tokens a file contributes to the output
+type FileTokenCount struct {
+	Path   string
+	Tokens int
+}
+
+// NewGenerator constructs a generator with default settings. It fails if the secret
+// scanner cannot be initialized.
+func NewGenerator(rootPath string, gitIgnoreMgr *filesystem.GitIgnoreManager, filterMgr *filesystem.FilterManager, outputPath string, useTempFile bool) (*Generator, error) {
 	secretScanner, err := secrets.NewGitleaksScanner()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "FATAL: Failed to initialize secret scanner: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("failed to initialize secret scanner: %w", err)
 	}
 
 	return &Generator{
 		RootPath:        rootPath,
 		OutputPath:      outputPath,
 		UseTempFile:     useTempFile,
+		UseStdout:       outputPath == StdoutPath,
+		Stdout:          os.Stdout,
 		SelectedFiles:   make(map[string]bool),
 		DeselectedFiles: make(map[string]bool),
 		GitIgnoreMgr:    gitIgnoreMgr,
@@ -49,12 +95,22 @@ func NewGenerator(rootPath string, gitIgnoreMgr *filesystem.GitIgnoreManager, fi
 		ShowHidden:      false,
 		SecretScanner:   secretScanner,
 		RedactSecrets:   true,
-	}
+	}, nil
 }
 
-// SetFormat changes the output format
+// SetFormat changes the output format. Every output is written with this one value,
+// so it must not keep state between its calls; use SetFormatFunc for formats that do.
 func (g *Generator) SetFormat(format Format) {
 	g.format = format
+	g.newFormat = nil
+}
+
+// SetFormatFunc changes the output format to the one made by newFormat, which is
+// called for each output written. Copies of the generator can then write their
+// outputs at the same time.
+func (g *Generator) SetFormatFunc(newFormat func() Format) {
+	g.format = newFormat()
+	g.newFormat = newFormat
 }
 
 // GetFormat returns the current format
@@ -75,8 +131,11 @@ func (g *Generator) SetRedactionMode(redact bool) {
 	g.RedactSecrets = redact
 }
 
-// Generate creates an output file in the specified format
-func (g *Generator) Generate() (string, int, int, error) {
+// Generate creates an output file in the specified format, or writes the
+// rendered output to Stdout when UseStdout is set. The output is streamed, one
+// file at a time; when ctx is cancelled, generation stops with the context's error
+// and a partially written output file is removed.
+func (g *Generator) Generate(ctx context.Context) (string, int, int, error) {
 	if len(g.SelectedFiles) == 0 {
 		return "", 0, 0, fmt.Errorf("no files selected, skipping generation")
 	}
@@ -85,28 +144,34 @@ func (g *Generator) Generate() (string, int, int, error) {
 		return "", 0, 0, fmt.Errorf("no format set, cannot generate output")
 	}
 
-	data, err := g.PrepareTemplateData()
-	if err != nil {
-		return "", 0, g.lastSecretCount, fmt.Errorf("failed to prepare template data: %w", err)
-	}
-
-	content, tokenCount, err := g.format.Render(data)
-	if err != nil {
-		return "", 0, g.lastSecretCount, fmt.Errorf("failed to render %s: %w", g.format.Name(), err)
-	}
-
 	var outputPath string
 	var displayPath string
 
+	if g.UseStdout {
+		out := g.Stdout
+		if out == nil {
+			out = os.Stdout
+		}
+		tokenCount, secretCount, err := g.Stream(ctx, out)
+		if err != nil {
+			return "", tokenCount, secretCount, err
+		}
+		return "stdout", tokenCount, secretCount, nil
+	}
+
+	var tokenCount, secretCount int
 	if g.UseTempFile {
 		tmpFile, err := os.CreateTemp("", fmt.Sprintf("codegrab-*%s", g.format.Extension()))
 		if err != nil {
-			return "", 0, g.lastSecretCount, fmt.Errorf("failed to create temporary file: %w", err)
+			return "", 0, 0, fmt.Errorf("failed to create temporary file: %w", err)
 		}
-		defer tmpFile.Close()
 
-		if _, err := tmpFile.Write([]byte(content)); err != nil {
-			return tmpFile.Name(), tokenCount, g.lastSecretCount, fmt.Errorf("failed to write to temporary file: %w", err)
+		tokenCount, secretCount, err = g.streamToFile(ctx, tmpFile)
+		if ctx.Err() != nil {
+			return "", tokenCount, secretCount, ctx.Err()
+		}
+		if err != nil {
+			return "", tokenCount, secretCount, fmt.Errorf("failed to write to temporary file: %w", err)
 		}
 		outputPath = tmpFile.Name()
 		displayPath = outputPath
@@ -123,46 +188,373 @@ func (g *Generator) Generate() (string, int, int, error) {
 		displayPath = outputPath
 		absPath, err := filepath.Abs(outputPath)
 		if err != nil {
-			return displayPath, tokenCount, g.lastSecretCount, fmt.Errorf("failed to get absolute path: %w", err)
+			return displayPath, 0, 0, fmt.Errorf("failed to get absolute path: %w", err)
 		}
 
-		if err := os.WriteFile(outputPath, []byte(content), 0644); err != nil {
-			return displayPath, tokenCount, g.lastSecretCount, fmt.Errorf("failed to write to output file %s: %w", absPath, err)
+		file, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return displayPath, 0, 0, fmt.Errorf("failed to write to output file %s: %w", absPath, err)
+		}
+		tokenCount, secretCount, err = g.streamToFile(ctx, file)
+		if ctx.Err() != nil {
+			return displayPath, tokenCount, secretCount, ctx.Err()
+		}
+		if err != nil {
+			return displayPath, tokenCount, secretCount, fmt.Errorf("failed to write to output file %s: %w", absPath, err)
 		}
 
 		outputPath = absPath
 	}
 
 	if err := utils.CopyFileObject(outputPath); err != nil {
-		return displayPath, tokenCount, g.lastSecretCount, fmt.Errorf("clipboard copy failed: %w", err)
+		return displayPath, tokenCount, secretCount, fmt.Errorf("clipboard copy failed: %w", err)
 	}
 
-	return displayPath, tokenCount, g.lastSecretCount, nil
+	return displayPath, tokenCount, secretCount, nil
+}
+
+// streamToFile streams the output into a file and closes it. The file is removed if
+// the output could not be written completely.
+func (g *Generator) streamToFile(ctx context.Context, file *os.File) (int, int, error) {
+	buffered := bufio.NewWriter(file)
+	tokenCount, secretCount, err := g.Stream(ctx, buffered)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return tokenCount, secretCount, err
 }
 
 // GenerateString returns the rendered content as a string along with counts
-func (g *Generator) GenerateString() (string, int, int, error) {
+func (g *Generator) GenerateString(ctx context.Context) (string, int, int, error) {
+	var b strings.Builder
+	tokenCount, secretCount, err := g.Stream(ctx, &b)
+	if err != nil {
+		return "", tokenCount, secretCount, err
+	}
+	return b.String(), tokenCount, secretCount, nil
+}
+
+// Stream finalizes the selection and writes the output to w in the current format.
+// Files are read, scanned and redacted a few at a time ahead of the one being
+// written, so only those are held in memory. It returns the estimated tokens of the
+// output and the number of secrets found. OnProgress is called as files are written.
+func (g *Generator) Stream(ctx context.Context, w io.Writer) (int, int, error) {
 	if len(g.SelectedFiles) == 0 {
-		return "", 0, 0, fmt.Errorf("no files selected, skipping generation")
+		return 0, 0, fmt.Errorf("no files selected, skipping generation")
 	}
 
 	if g.format == nil {
-		return "", 0, 0, fmt.Errorf("no format set, cannot generate output")
+		return 0, 0, fmt.Errorf("no format set, cannot generate output")
 	}
 
-	data, err := g.PrepareTemplateData()
+	format := g.format
+	if g.newFormat != nil {
+		format = g.newFormat()
+	}
+
+	g.lastSecretCount = 0
+	g.finalizeSelection()
+
+	rootNode := g.buildTree()
+	structure, baseRootName := g.renderStructure(rootNode)
+
+	var nodes []*Node
+	collectFileNodes(rootNode, &nodes)
+	header := Header{Structure: structure, Paths: make([]string, 0, len(nodes))}
+	for _, node := range nodes {
+		header.Paths = append(header.Paths, node.Path)
+	}
+	if g.IncludeMetadata {
+		header.Metadata = g.buildMetadata(baseRootName)
+	}
+
+	out := &countingWriter{w: w}
+	tokenCount := func() int {
+		return int(utils.EstimateTokensForSize(out.n))
+	}
+	renderErr := func(err error) error {
+		if out.err != nil {
+			return fmt.Errorf("failed to write output: %w", out.err)
+		}
+		return fmt.Errorf("failed to render %s: %w", format.Name(), err)
+	}
+
+	if err := format.WriteHeader(out, header); err != nil {
+		return tokenCount(), 0, renderErr(err)
+	}
+
+	progress := Progress{TotalFiles: len(nodes)}
+	g.reportProgress(progress)
+
+	g.lastFileTokens = make([]FileTokenCount, 0, len(nodes))
+	err := g.streamFiles(ctx, nodes, func(file FileData, secretCount int) error {
+		g.lastSecretCount += secretCount
+		g.lastFileTokens = append(g.lastFileTokens, FileTokenCount{Path: file.Path, Tokens: fileTokens(file)})
+		if err := format.WriteFile(out, file); err != nil {
+			return err
+		}
+
+		progress.FilesScanned++
+		progress.BytesRead += int64(len(file.Content))
+		progress.SecretsFound = g.lastSecretCount
+		g.reportProgress(progress)
+		return nil
+	})
+	if ctx.Err() != nil {
+		return tokenCount(), g.lastSecretCount, ctx.Err()
+	}
+	if err != nil {
+		return tokenCount(), g.lastSecretCount, renderErr(err)
+	}
+
+	if err := format.WriteFooter(out); err != nil {
+		return tokenCount(), g.lastSecretCount, renderErr(err)
+	}
+	return tokenCount(), g.lastSecretCount, nil
+}
+
+// preparedFile is a file read, scanned and redacted ahead of being written
+type preparedFile struct {
+	file        FileData
+	secretCount int
+	ok          bool
+}
+
+// reportProgress passes progress to OnProgress, if set
+func (g *Generator) reportProgress(progress Progress) {
+	if g.OnProgress != nil {
+		g.OnProgress(progress)
+	}
+}
+
+// streamFiles prepares the files of nodes concurrently and passes them to write in
+// order. At most runtime.NumCPU() files are prepared or waiting at a time.
+func (g *Generator) streamFiles(ctx context.Context, nodes []*Node, write func(file FileData, secretCount int) error) error {
+	withHistory := g.HistoryDepth > 0 && git.IsRepository(g.RootPath)
+
+	results := make([]chan preparedFile, len(nodes))
+	for i := range results {
+		results[i] = make(chan preparedFile, 1)
+	}
+	slots := make(chan struct{}, runtime.NumCPU())
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for i, node := range nodes {
+			select {
+			case slots <- struct{}{}:
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+			go func(i int, node *Node) {
+				results[i] <- g.prepareFile(node, withHistory)
+			}(i, node)
+		}
+	}()
+
+	for i := range nodes {
+		var prepared preparedFile
+		select {
+		case prepared = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		<-slots
+		if !prepared.ok {
+			continue
+		}
+		if err := write(prepared.file, prepared.secretCount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareFile reads a file through the cache, scans and redacts it, and attaches its
+// history. The file is skipped with a warning if it cannot be read.
+func (g *Generator) prepareFile(node *Node, withHistory bool) preparedFile {
+	content, err := cache.GetGlobalFileCache().GetLazy(filepath.Join(g.RootPath, node.Path))
 	if err != nil {
-		return "", 0, g.lastSecretCount, fmt.Errorf("failed to prepare template data: %w", err)
+		fmt.Fprintf(os.Stderr, "Warning: failed to read file %s: %v\n", node.Path, err)
+		return preparedFile{}
+	}
+
+	file := FileData{
+		Path:     node.Path,
+		Content:  content,
+		Language: node.Language,
+	}
+	secretCount := g.scanFile(&file)
+	g.selectLines(&file)
+	g.numberLines(&file)
+	if withHistory {
+		history, historySecrets, err := g.fileHistory(file.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		file.History = history
+		secretCount += historySecrets
+	}
+	return preparedFile{file: file, secretCount: secretCount, ok: true}
+}
+
+// scanFile scans the content of a file for secrets, redacting them if enabled, and
+// returns the number found
+func (g *Generator) scanFile(file *FileData) int {
+	if g.SecretScanner == nil {
+		return 0
+	}
+	if file.Findings == nil {
+		findings, err := g.SecretScanner.Scan(file.Content)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to scan %s for secrets: %v\n", file.Path, err)
+		}
+		file.Findings = findings
+	}
+	if len(file.Findings) > 0 && g.RedactSecrets {
+		file.Content = g.SecretScanner.Redact(file.Content, file.Findings)
+	}
+	return len(file.Findings)
+}
+
+// selectLines limits the content of a file to its line ranges, if it has any. It runs
+// after redaction, so that secrets crossing the edge of a range are still found. A range
+// past the end of the file is kept with no content and a warning, rather than leaving
+// nothing to mark the file as partial.
+func (g *Generator) selectLines(file *FileData) {
+	ranges := g.LineRanges[file.Path]
+	if len(ranges) == 0 {
+		return
+	}
+
+	lines := strings.SplitAfter(file.Content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
 	}
 
-	content, tokenCount, err := g.format.Render(data)
-	return content, tokenCount, g.lastSecretCount, err
+	var content strings.Builder
+	file.Ranges = nil
+	for _, r := range symbols.MergeRanges(ranges) {
+		if r.Start > len(lines) {
+			fmt.Fprintf(os.Stderr, "Warning: lines %s of %s are past the end of the file (%d lines)\n", r, file.Path, len(lines))
+			file.Ranges = append(file.Ranges, r)
+			continue
+		}
+		r.End = min(r.End, len(lines))
+		block := strings.Join(lines[r.Start-1:r.End], "")
+		content.WriteString(block)
+		file.Ranges = append(file.Ranges, r)
+		if g.LineNumbers {
+			file.Lines = append(file.Lines, LineBlock{Content: block, Start: r.Start})
+		}
+	}
+	file.Content = content.String()
 }
 
-// PrepareTemplateData finalizes the selection, scans/redacts secrets, and builds TemplateData
+// numberLines splits the content of a file into numbered lines if line numbers are
+// enabled. It runs after redaction, which keeps the line breaks of secrets.
+func (g *Generator) numberLines(file *FileData) {
+	if g.LineNumbers && file.Lines == nil {
+		file.Lines = []LineBlock{{Content: file.Content, Start: 1}}
+	}
+}
+
+// fileTokens returns the estimated tokens a file contributes to the output
+func fileTokens(file FileData) int {
+	tokens := utils.EstimateTokens(file.NumberedContent())
+	for _, commit := range file.History {
+		tokens += utils.EstimateTokens(commit.Subject) + utils.EstimateTokens(commit.Diff)
+	}
+	return tokens
+}
+
+// collectFileNodes gathers the file nodes of the tree, in tree order
+func collectFileNodes(node *Node, nodes *[]*Node) {
+	if !node.IsDir {
+		*nodes = append(*nodes, node)
+	}
+	for _, child := range node.Children {
+		collectFileNodes(child, nodes)
+	}
+}
+
+// countingWriter counts the bytes written through it and keeps the first write error
+type countingWriter struct {
+	w   io.Writer
+	err error
+	n   int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+	return n, err
+}
+
+// FileTokenCounts returns the estimated tokens of each file in the last prepared output,
+// in output order
+func (g *Generator) FileTokenCounts() []FileTokenCount {
+	return g.lastFileTokens
+}
+
+// PrepareTemplateData finalizes the selection, scans/redacts secrets, and builds
+// TemplateData with the content of every file. Stream writes the same output without
+// holding all the files in memory.
 func (g *Generator) PrepareTemplateData() (TemplateData, error) {
 	g.lastSecretCount = 0
+	g.finalizeSelection()
+
+	rootNode := g.buildTree()
+	structure, baseRootName := g.renderStructure(rootNode)
+
+	var filesData []FileData
+	collectFiles(context.Background(), rootNode, &filesData, g.RootPath, &g.SecretScanner)
+
+	secretCount := 0
+	for i := range filesData {
+		secretCount += g.scanFile(&filesData[i])
+		g.selectLines(&filesData[i])
+		g.numberLines(&filesData[i])
+	}
+
+	if g.HistoryDepth > 0 {
+		secretCount += g.attachHistory(filesData)
+	}
+
+	g.lastSecretCount = secretCount
 
+	g.lastFileTokens = make([]FileTokenCount, 0, len(filesData))
+	for _, file := range filesData {
+		g.lastFileTokens = append(g.lastFileTokens, FileTokenCount{Path: file.Path, Tokens: fileTokens(file)})
+	}
+
+	var metadata *Metadata
+	if g.IncludeMetadata {
+		metadata = g.buildMetadata(baseRootName)
+	}
+
+	return TemplateData{
+		Metadata:  metadata,
+		Structure: structure,
+		Files:     filesData,
+	}, nil
+}
+
+// finalizeSelection drops the selected paths that no longer exist, are hidden,
+// ignored, directories or not text files
+func (g *Generator) finalizeSelection() {
 	expandedSelection := make(map[string]bool)
 
 	for path := range g.SelectedFiles {
@@ -195,8 +587,11 @@ func (g *Generator) PrepareTemplateData() (TemplateData, error) {
 	}
 
 	g.SelectedFiles = expandedSelection
+}
 
-	rootNode := g.buildTree()
+// renderStructure renders the directory tree of the selection, returning it with the
+// name of the root directory
+func (g *Generator) renderStructure(rootNode *Node) (string, string) {
 	var structureBuilder strings.Builder
 	baseRootName := filepath.Base(g.RootPath)
 	if baseRootName == "." || baseRootName == "/" {
@@ -209,27 +604,98 @@ func (g *Generator) PrepareTemplateData() (TemplateData, error) {
 		isLast := i == len(rootNode.Children)-1
 		renderTree(child, "", isLast, &structureBuilder, baseRootName, make(map[string]bool))
 	}
+	return structureBuilder.String(), baseRootName
+}
 
-	var filesData []FileData
-	collectFiles(rootNode, &filesData, g.RootPath, &g.SecretScanner)
+// buildMetadata collects the repository state and generation settings for the output header
+func (g *Generator) buildMetadata(rootName string) *Metadata {
+	metadata := &Metadata{
+		GeneratedAt:   time.Now(),
+		RepoName:      rootName,
+		Version:       utils.Version,
+		UseGitIgnore:  g.UseGitIgnore,
+		ShowHidden:    g.ShowHidden,
+		RedactSecrets: g.RedactSecrets,
+	}
+	if g.FilterMgr != nil {
+		metadata.GlobPatterns = append(metadata.GlobPatterns, g.FilterMgr.Patterns...)
+	}
+
+	if info, err := git.GetRepoInfo(g.RootPath); err == nil {
+		metadata.IsGitRepo = true
+		metadata.RepoName = info.Name
+		metadata.Branch = info.Branch
+		metadata.CommitHash = info.CommitHash
+		metadata.CommitSubject = info.CommitSubject
+		metadata.Dirty = info.Dirty
+	}
+
+	return metadata
+}
+
+// attachHistory adds the recent git history of each file to its FileData.
+// Diffs are scanned for secrets like file contents; the number of findings is returned.
+func (g *Generator) attachHistory(files []FileData) int {
+	if !git.IsRepository(g.RootPath) {
+		return 0
+	}
 
+	workQueue := make(chan int, len(files))
+	for i := range files {
+		workQueue <- i
+	}
+	close(workQueue)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
 	secretCount := 0
 
-	if g.SecretScanner != nil {
-		for i := range filesData {
-			if len(filesData[i].Findings) > 0 {
-				secretCount += len(filesData[i].Findings)
-				if g.RedactSecrets {
-					filesData[i].Content = g.SecretScanner.Redact(filesData[i].Content, filesData[i].Findings)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range workQueue {
+				history, findings, err := g.fileHistory(files[i].Path)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+					continue
 				}
+
+				files[i].History = history
+				mu.Lock()
+				secretCount += findings
+				mu.Unlock()
 			}
-		}
+		}()
 	}
+	wg.Wait()
 
-	g.lastSecretCount = secretCount
+	return secretCount
+}
 
-	return TemplateData{
-		Structure: structureBuilder.String(),
-		Files:     filesData,
-	}, nil
+// fileHistory returns the recent git history of a file. Diffs are scanned for secrets
+// like file contents; the number of findings is returned.
+func (g *Generator) fileHistory(path string) ([]git.Commit, int, error) {
+	history, err := git.FileHistory(g.RootPath, path, g.HistoryDepth, g.HistoryDiff)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findings := 0
+	if g.SecretScanner != nil {
+		for c := range history {
+			if history[c].Diff == "" {
+				continue
+			}
+			diffFindings, err := g.SecretScanner.Scan(history[c].Diff)
+			if err != nil || len(diffFindings) == 0 {
+				continue
+			}
+			findings += len(diffFindings)
+			if g.RedactSecrets {
+				history[c].Diff = g.SecretScanner.Redact(history[c].Diff, diffFindings)
+			}
+		}
+	}
+	return history, findings, nil
 }
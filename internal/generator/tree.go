@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -90,7 +91,7 @@ func (g *Generator) buildTree() *Node {
 					fileCache := cache.GetGlobalFileCache()
 					absolutePath := filepath.Join(g.RootPath, fullPath)
 					if err := fileCache.CacheMetadataOnly(absolutePath); err == nil {
-						newNode.Language = determineLanguage(part)
+						newNode.Language = DetermineLanguage(part)
 					} else {
 						fmt.Fprintf(os.Stderr, "Warning: failed to cache metadata for %s: %v\n", fullPath, err)
 					}
@@ -173,7 +174,7 @@ func NewConcurrentFileCollector(rootPath string) *ConcurrentFileCollector {
 }
 
 // collectFiles intelligently chooses between concurrent and sequential based on file count
-func collectFiles(node *Node, files *[]FileData, rootPath string, secretScanner *secrets.Scanner) {
+func collectFiles(ctx context.Context, node *Node, files *[]FileData, rootPath string, secretScanner *secrets.Scanner) {
 	// Count files to determine best approach
 	fileCount := countFiles(node)
 	
@@ -182,7 +183,10 @@ func collectFiles(node *Node, files *[]FileData, rootPath string, secretScanner
 	
 	if fileCount >= concurrentThreshold {
 		collector := NewConcurrentFileCollector(rootPath)
-		result, err := collector.CollectFilesConcurrent(node, secretScanner)
+		result, err := collector.CollectFilesConcurrent(ctx, node, secretScanner)
+		if ctx.Err() != nil {
+			return
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: concurrent file collection failed, falling back to sequential: %v\n", err)
 			collectFilesSequential(node, files, rootPath, secretScanner)
@@ -208,8 +212,9 @@ func countFiles(node *Node) int {
 	return count
 }
 
-// CollectFilesConcurrent performs concurrent file content reading
-func (c *ConcurrentFileCollector) CollectFilesConcurrent(node *Node, secretScanner *secrets.Scanner) ([]FileData, error) {
+// CollectFilesConcurrent performs concurrent file content reading. It stops early,
+// returning the context's error, when ctx is cancelled.
+func (c *ConcurrentFileCollector) CollectFilesConcurrent(ctx context.Context, node *Node, secretScanner *secrets.Scanner) ([]FileData, error) {
 	// First pass: collect all file work items
 	var workItems []fileWorkItem
 	c.collectWorkItems(node, &workItems)
@@ -235,7 +240,7 @@ func (c *ConcurrentFileCollector) CollectFilesConcurrent(node *Node, secretScann
 	// Start worker goroutines
 	for i := 0; i < c.maxWorkers; i++ {
 		wg.Add(1)
-		go c.fileWorker(workQueue, resultQueue, errorChan, &wg)
+		go c.fileWorker(ctx, workQueue, resultQueue, errorChan, &wg)
 	}
 
 	// Start error collector
@@ -266,6 +271,10 @@ func (c *ConcurrentFileCollector) CollectFilesConcurrent(node *Node, secretScann
 	// Wait for error collector to finish
 	<-errorDone
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Sort files to maintain consistent order (by path)
 	sort.Slice(files, func(i, j int) bool {
 		return files[i].Path < files[j].Path
@@ -288,12 +297,16 @@ func (c *ConcurrentFileCollector) collectWorkItems(node *Node, workItems *[]file
 }
 
 // fileWorker processes files from the work queue
-func (c *ConcurrentFileCollector) fileWorker(workQueue <-chan fileWorkItem, resultQueue chan<- FileData, errorChan chan<- error, wg *sync.WaitGroup) {
+func (c *ConcurrentFileCollector) fileWorker(ctx context.Context, workQueue <-chan fileWorkItem, resultQueue chan<- FileData, errorChan chan<- error, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	fileCache := cache.GetGlobalFileCache()
 
 	for item := range workQueue {
+		if ctx.Err() != nil {
+			return
+		}
+
 		content, err := fileCache.GetLazy(item.path)
 		if err != nil {
 			select {
@@ -343,7 +356,9 @@ func collectFilesSequential(node *Node, files *[]FileData, rootPath string, secr
 	}
 }
 
-func determineLanguage(filename string) string {
+// DetermineLanguage returns the language name used for code fences and syntax
+// highlighting, based on the file's extension or name
+func DetermineLanguage(filename string) string {
 	ext := strings.ToLower(filepath.Ext(filename))
 	if ext == "" {
 		// Check for special filenames without extensions
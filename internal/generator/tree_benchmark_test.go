@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -35,7 +36,7 @@ func BenchmarkFileCollection(b *testing.B) {
 		for i := 0; i < b.N; i++ {
 			var files []FileData
 			collector := NewConcurrentFileCollector(tmpDir)
-			result, err := collector.CollectFilesConcurrent(tree, nil) // Pass nil for now, secret scanning happens later
+			result, err := collector.CollectFilesConcurrent(context.Background(), tree, nil) // Pass nil for now, secret scanning happens later
 			if err != nil {
 				b.Fatalf("Concurrent collection failed: %v", err)
 			}
@@ -77,7 +78,7 @@ func BenchmarkLargeFileCollection(b *testing.B) {
 	b.Run("Concurrent-Large", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
 			collector := NewConcurrentFileCollector(tmpDir)
-			files, err := collector.CollectFilesConcurrent(tree, nil) // Pass nil for now
+			files, err := collector.CollectFilesConcurrent(context.Background(), tree, nil) // Pass nil for now
 			if err != nil {
 				b.Fatalf("Concurrent collection failed: %v", err)
 			}
@@ -194,7 +195,7 @@ func createTestTree(rootPath string) *Node {
 				}
 				
 				if !newNode.IsDir {
-					newNode.Language = determineLanguage(part)
+					newNode.Language = DetermineLanguage(part)
 				}
 
 				current.Children = append(current.Children, newNode)
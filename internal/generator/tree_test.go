@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -35,7 +36,10 @@ func TestBuildTree(t *testing.T) {
 		}
 	}
 
-	gen := NewGenerator(tempDir, nil, nil, "", false)
+	gen, err := NewGenerator(tempDir, nil, nil, "", false)
+	if err != nil {
+		t.Fatalf("NewGenerator returned an error: %v", err)
+	}
 
 	gen.SelectedFiles = map[string]bool{
 		"file1.txt":              true,
@@ -71,7 +75,7 @@ func TestBuildTree(t *testing.T) {
 
 	// Test lazy loading by using the actual collectFiles function
 	var files []FileData
-	collectFiles(root, &files, tempDir, nil)
+	collectFiles(context.Background(), root, &files, tempDir, nil)
 
 	if len(files) != len(testFiles) {
 		t.Errorf("Expected %d files from collectFiles, got %d", len(testFiles), len(files))
@@ -194,9 +198,9 @@ func TestDetermineLanguage(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.filename, func(t *testing.T) {
-			result := determineLanguage(tc.filename)
+			result := DetermineLanguage(tc.filename)
 			if result != tc.expected {
-				t.Errorf("determineLanguage(%q) = %q, want %q", tc.filename, result, tc.expected)
+				t.Errorf("DetermineLanguage(%q) = %q, want %q", tc.filename, result, tc.expected)
 			}
 		})
 	}
@@ -305,7 +309,7 @@ func TestCollectFiles(t *testing.T) {
 	}
 
 	var files []FileData
-	collectFiles(root, &files, tempDir, nil)
+	collectFiles(context.Background(), root, &files, tempDir, nil)
 
 	if len(files) != 2 {
 		t.Fatalf("Expected 2 files, got %d", len(files))
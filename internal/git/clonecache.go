@@ -0,0 +1,227 @@
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultCloneCacheMaxAge is how long an unused cached clone is kept
+	DefaultCloneCacheMaxAge = 30 * 24 * time.Hour
+	// DefaultCloneCacheMaxEntries is the number of cached clones kept before the least recently used are evicted
+	DefaultCloneCacheMaxEntries = 20
+
+	cacheEntryFile = "entry.json"
+	cacheRepoDir   = "repo"
+)
+
+// CloneCache keeps clones of remote repositories between runs, keyed by URL and ref.
+// Cached clones are updated with a shallow fetch on reuse, and the cached copy is
+// used as is when the remote cannot be reached.
+type CloneCache struct {
+	Dir        string
+	MaxAge     time.Duration
+	MaxEntries int
+}
+
+// CacheEntry describes a cached clone
+type CacheEntry struct {
+	URL      string    `json:"url"`
+	Ref      string    `json:"ref"`
+	LastUsed time.Time `json:"lastUsed"`
+	Path     string    `json:"-"`
+}
+
+// DefaultCloneCacheDir returns the default location of the clone cache
+// (codegrab/repos in the user cache directory)
+func DefaultCloneCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "codegrab", "repos"), nil
+}
+
+// NewCloneCache creates a clone cache in dir with the default eviction policy
+func NewCloneCache(dir string) *CloneCache {
+	return &CloneCache{
+		Dir:        dir,
+		MaxAge:     DefaultCloneCacheMaxAge,
+		MaxEntries: DefaultCloneCacheMaxEntries,
+	}
+}
+
+// cacheKey returns the directory name of the cache entry for a URL and ref
+func cacheKey(url, ref string) string {
+	sum := sha256.Sum256([]byte(url + "\x00" + ref))
+	return hex.EncodeToString(sum[:8])
+}
+
+// checkout returns the cached clone of spec, cloning or updating it as needed.
+// The returned cleanup function is a no-op since the clone is kept.
+func (c *CloneCache) checkout(spec RemoteSpec) (string, func(), error) {
+	subdir, err := cleanSubdir(spec.Subdir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	entryDir := filepath.Join(c.Dir, cacheKey(spec.URL, spec.Ref))
+	repoDir := filepath.Join(entryDir, cacheRepoDir)
+
+	if _, err := os.Stat(filepath.Join(repoDir, ".git")); err == nil {
+		if err := checkoutRef(repoDir, spec.URL, spec.Ref); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to update cached clone, using cached copy: %v\n", err)
+		}
+	} else {
+		// Start from scratch if a previous clone was interrupted
+		os.RemoveAll(entryDir)
+		if err := os.MkdirAll(repoDir, 0755); err != nil {
+			return "", nil, fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		// Cached clones are shared between subdirectories, so the full tree is checked out
+		err := initClone(repoDir, spec.URL)
+		if err == nil {
+			err = checkoutRef(repoDir, spec.URL, spec.Ref)
+		}
+		if err != nil {
+			os.RemoveAll(entryDir)
+			return "", nil, err
+		}
+	}
+
+	entry := CacheEntry{URL: spec.URL, Ref: spec.Ref, LastUsed: time.Now()}
+	if err := writeCacheEntry(entryDir, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	if _, err := c.enforcePolicy(entryDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to evict cached clones: %v\n", err)
+	}
+
+	root, err := checkoutRoot(repoDir, subdir, spec.Ref)
+	if err != nil {
+		return "", nil, err
+	}
+	return root, func() {}, nil
+}
+
+// Entries lists the cached clones, most recently used first
+func (c *CloneCache) Entries() ([]CacheEntry, error) {
+	dirEntries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read clone cache %s: %w", c.Dir, err)
+	}
+
+	var entries []CacheEntry
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		entryDir := filepath.Join(c.Dir, dirEntry.Name())
+		entry, err := readCacheEntry(entryDir)
+		if err != nil {
+			// Entries without metadata, e.g. from an interrupted clone, fall back to the directory time
+			entry = CacheEntry{}
+			if info, statErr := dirEntry.Info(); statErr == nil {
+				entry.LastUsed = info.ModTime()
+			}
+		}
+		entry.Path = entryDir
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// Prune removes cached clones that have not been used within maxAge, or all of them
+// if maxAge is zero. It returns the removed entries.
+func (c *CloneCache) Prune(maxAge time.Duration) ([]CacheEntry, error) {
+	return c.evict(olderThan(maxAge), 0, "")
+}
+
+// olderThan returns a predicate matching entries unused for longer than maxAge,
+// or every entry if maxAge is zero
+func olderThan(maxAge time.Duration) func(CacheEntry) bool {
+	now := time.Now()
+	return func(entry CacheEntry) bool {
+		return maxAge <= 0 || now.Sub(entry.LastUsed) > maxAge
+	}
+}
+
+// enforcePolicy evicts entries beyond the cache's MaxAge and MaxEntries limits,
+// keeping the entry at keep. A zero limit is not enforced.
+func (c *CloneCache) enforcePolicy(keep string) ([]CacheEntry, error) {
+	expired := func(CacheEntry) bool { return false }
+	if c.MaxAge > 0 {
+		expired = olderThan(c.MaxAge)
+	}
+	return c.evict(expired, c.MaxEntries, keep)
+}
+
+// evict removes the entries matching expired, then the least recently used entries
+// beyond maxEntries (if positive). The entry at keep is never removed.
+func (c *CloneCache) evict(expired func(CacheEntry) bool, maxEntries int, keep string) ([]CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	// The entry being used counts towards maxEntries ahead of the others
+	kept := 0
+	for _, entry := range entries {
+		if entry.Path == keep {
+			kept++
+		}
+	}
+
+	var removed []CacheEntry
+	for _, entry := range entries {
+		if entry.Path == keep {
+			continue
+		}
+		if !expired(entry) && (maxEntries <= 0 || kept < maxEntries) {
+			kept++
+			continue
+		}
+		if err := os.RemoveAll(entry.Path); err != nil {
+			return removed, fmt.Errorf("failed to remove cached clone %s: %w", entry.Path, err)
+		}
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
+
+// readCacheEntry reads the metadata of the cache entry in entryDir
+func readCacheEntry(entryDir string) (CacheEntry, error) {
+	var entry CacheEntry
+	data, err := os.ReadFile(filepath.Join(entryDir, cacheEntryFile))
+	if err != nil {
+		return entry, err
+	}
+	err = json.Unmarshal(data, &entry)
+	return entry, err
+}
+
+// writeCacheEntry records the metadata of the cache entry in entryDir
+func writeCacheEntry(entryDir string, entry CacheEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(entryDir, cacheEntryFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloneCache(t *testing.T) {
+	url, _ := initBareRemote(t)
+	cache := NewCloneCache(t.TempDir())
+
+	root, cleanup, err := CloneRepository(url, CloneOptions{Cache: cache})
+	if err != nil {
+		t.Fatalf("CloneRepository failed: %v", err)
+	}
+	cleanup()
+	if _, err := os.Stat(filepath.Join(root, "main.go")); err != nil {
+		t.Fatalf("Expected the cached clone to be kept after cleanup: %v", err)
+	}
+
+	// Push a new commit to the remote; the cached clone should be updated on reuse
+	barePath := strings.TrimPrefix(url, "file://")
+	work := filepath.Join(t.TempDir(), "work")
+	gitCmd(t, barePath, "clone", "-q", barePath, work)
+	if err := os.WriteFile(filepath.Join(work, "new.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	gitCmd(t, work, "add", ".")
+	gitCmd(t, work, "commit", "-q", "-m", "Add new.go")
+	gitCmd(t, work, "push", "-q", "origin", "HEAD")
+
+	updatedRoot, _, err := CloneRepository(url, CloneOptions{Cache: cache, Subdir: "pkg"})
+	if err != nil {
+		t.Fatalf("CloneRepository failed on reuse: %v", err)
+	}
+	if updatedRoot != filepath.Join(root, "pkg") {
+		t.Errorf("Expected the cached clone to be reused, got %s", updatedRoot)
+	}
+	if _, err := os.Stat(filepath.Join(root, "new.go")); err != nil {
+		t.Errorf("Expected the cached clone to be updated from the remote: %v", err)
+	}
+
+	// The cached copy is used when the remote is unavailable
+	if err := os.Rename(barePath, barePath+".offline"); err != nil {
+		t.Fatalf("Failed to move remote: %v", err)
+	}
+	if _, _, err := CloneRepository(url, CloneOptions{Cache: cache, ForceRemote: true}); err != nil {
+		t.Errorf("Expected the cached clone to be used offline, got %v", err)
+	}
+
+	if _, _, err := CloneRepository(url, CloneOptions{Cache: cache, Ref: "v1", ForceRemote: true}); err == nil {
+		t.Errorf("Expected an uncached ref to fail while offline")
+	}
+
+	entries, err := cache.Entries()
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].URL != url || entries[0].Ref != "" {
+		t.Errorf("Expected a single cache entry for %s, got %+v", url, entries)
+	}
+}
+
+func TestCloneCacheEviction(t *testing.T) {
+	cache := &CloneCache{Dir: t.TempDir(), MaxAge: 48 * time.Hour, MaxEntries: 2}
+
+	now := time.Now()
+	ages := map[string]time.Duration{
+		"recent": time.Hour,
+		"older":  2 * time.Hour,
+		"oldest": 3 * time.Hour,
+		"stale":  72 * time.Hour,
+	}
+	for name, age := range ages {
+		entryDir := filepath.Join(cache.Dir, name)
+		if err := os.MkdirAll(entryDir, 0755); err != nil {
+			t.Fatalf("Failed to create entry: %v", err)
+		}
+		if err := writeCacheEntry(entryDir, CacheEntry{URL: name, LastUsed: now.Add(-age)}); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+
+	removed, err := cache.enforcePolicy(filepath.Join(cache.Dir, "oldest"))
+	if err != nil {
+		t.Fatalf("enforcePolicy failed: %v", err)
+	}
+	if len(removed) != 2 {
+		t.Fatalf("Expected 2 entries to be evicted, got %+v", removed)
+	}
+
+	entries, _ := cache.Entries()
+	var remaining []string
+	for _, entry := range entries {
+		remaining = append(remaining, entry.URL)
+	}
+	if strings.Join(remaining, ",") != "recent,oldest" {
+		t.Errorf("Expected the most recent and the kept entry to remain, got %v", remaining)
+	}
+
+	removed, err = cache.Prune(2 * time.Hour)
+	if err != nil || len(removed) != 1 || removed[0].URL != "oldest" {
+		t.Errorf("Expected Prune to remove entries older than the given age, got %+v, %v", removed, err)
+	}
+
+	removed, err = cache.Prune(0)
+	if err != nil || len(removed) != 1 {
+		t.Errorf("Expected Prune(0) to remove all entries, got %+v, %v", removed, err)
+	}
+}
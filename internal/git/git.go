@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -17,6 +18,38 @@ var GitURLPatterns = []*regexp.Regexp{
 	regexp.MustCompile(`^https?://bitbucket\.org/[^/]+/[^/]+/?$`), // https://bitbucket.org/user/repo
 	regexp.MustCompile(`^git@.*:.*\.git$`),                        // git@github.com:user/repo.git
 	regexp.MustCompile(`^ssh://git@.*/.*/.*\.git$`),               // ssh://git@github.com/user/repo.git
+	regexp.MustCompile(`^git://[^/]+/.+$`),                        // git://example.com/repo.git
+	regexp.MustCompile(`^file://.+$`),                             // file:///srv/git/repo.git
+
+	// Browse URLs pointing at a ref or subdirectory
+	regexp.MustCompile(`^https?://github\.com/[^/]+/[^/]+/(tree|commit)/.+$`),    // https://github.com/user/repo/tree/main/pkg
+	regexp.MustCompile(`^https?://gitlab\.com/.+/-/(tree|commit)/.+$`),           // https://gitlab.com/group/repo/-/tree/main/pkg
+	regexp.MustCompile(`^https?://bitbucket\.org/[^/]+/[^/]+/(src|commits)/.+$`), // https://bitbucket.org/user/repo/src/main/pkg
+}
+
+// AddForgeHosts registers the hostnames of additional Git forges, such as self-hosted
+// Gitea or GitLab instances, so their HTTPS repository URLs are recognized by IsGitURL
+func AddForgeHosts(hosts ...string) {
+	for _, host := range hosts {
+		host = strings.TrimSpace(host)
+		host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+		host = strings.TrimSuffix(host, "/")
+		if host == "" {
+			continue
+		}
+		// Owner and repository, or a nested group path
+		pattern := regexp.MustCompile(`(?i)^https?://` + regexp.QuoteMeta(host) + `/[^/]+/.+$`)
+		GitURLPatterns = append(GitURLPatterns, pattern)
+	}
+}
+
+// IsBareRepository reports whether dir is a local bare Git repository
+func IsBareRepository(dir string) bool {
+	if stat, err := os.Stat(dir); err != nil || !stat.IsDir() {
+		return false
+	}
+	out, err := runGit(dir, "rev-parse", "--is-bare-repository")
+	return err == nil && out == "true"
 }
 
 // IsGitURL checks if the given string appears to be a Git repository URL
@@ -30,13 +63,142 @@ func IsGitURL(url string) bool {
 	return false
 }
 
+// CloneOptions selects the revision and subdirectory to check out when cloning.
+// Empty fields fall back to the values parsed from the URL, then to the default branch
+// and the repository root.
+type CloneOptions struct {
+	Ref    string
+	Subdir string
+	// ForceRemote clones url even if it is not recognized as a Git URL,
+	// e.g. a self-hosted forge or a local repository path
+	ForceRemote bool
+	// Cache, if set, reuses and updates a cached clone instead of cloning into a temporary directory
+	Cache *CloneCache
+}
+
+// RemoteSpec is a Git URL split into the repository URL and the ref and subdirectory
+// it points at, as found in forge browse URLs
+type RemoteSpec struct {
+	URL    string
+	Ref    string
+	Subdir string
+	// refPath holds the unsplit ref and subdirectory of a tree URL, since refs may contain slashes
+	refPath string
+}
+
+// forgeRefMarkers maps the path segment that precedes a ref in forge browse URLs to
+// whether the rest of the path can include a subdirectory
+var forgeRefMarkers = map[string]bool{
+	"tree":    true,  // https://github.com/user/repo/tree/main/pkg, https://gitlab.com/group/repo/-/tree/main/pkg
+	"src":     true,  // https://bitbucket.org/user/repo/src/main/pkg
+	"commit":  false, // https://github.com/user/repo/commit/<sha>, https://gitlab.com/group/repo/-/commit/<sha>
+	"commits": false, // https://bitbucket.org/user/repo/commits/<sha>
+}
+
+// ParseGitURL splits a forge browse URL such as https://github.com/org/repo/tree/v2/pkg/foo
+// into the repository URL, ref and subdirectory. URLs without a ref are returned unchanged.
+// When the ref is followed by a path, the first segment is taken as the ref; CloneRepository
+// resolves refs containing slashes against the remote.
+func ParseGitURL(url string) RemoteSpec {
+	url = strings.TrimSpace(url)
+	scheme, rest, ok := strings.Cut(url, "://")
+	if !ok || (scheme != "http" && scheme != "https") || strings.HasSuffix(url, ".git") {
+		return RemoteSpec{URL: url}
+	}
+
+	host, repoPath, _ := strings.Cut(strings.TrimSuffix(rest, "/"), "/")
+	segments := strings.Split(repoPath, "/")
+
+	// The marker needs at least an owner and repository before it and a ref after it
+	for i := 2; i < len(segments)-1; i++ {
+		withSubdir, isMarker := forgeRefMarkers[segments[i]]
+		if !isMarker {
+			continue
+		}
+
+		repoSegments := segments[:i]
+		if dash := indexOf(segments, "-"); dash != -1 {
+			// GitLab separates the (possibly nested) project path from the route with "/-/"
+			if dash != i-1 {
+				continue
+			}
+			repoSegments = segments[:dash]
+		}
+
+		spec := RemoteSpec{URL: scheme + "://" + host + "/" + strings.Join(repoSegments, "/")}
+		refPath := strings.Join(segments[i+1:], "/")
+		if !withSubdir {
+			spec.Ref = refPath
+			return spec
+		}
+		spec.refPath = refPath
+		spec.Ref, spec.Subdir, _ = strings.Cut(refPath, "/")
+		return spec
+	}
+
+	return RemoteSpec{URL: url}
+}
+
+// indexOf returns the index of the first segment equal to value, or -1
+func indexOf(segments []string, value string) int {
+	for i, segment := range segments {
+		if segment == value {
+			return i
+		}
+	}
+	return -1
+}
+
 // CloneRepository clones a Git repository to a temporary directory
-// Returns the path to the cloned directory and a cleanup function
-func CloneRepository(url string) (string, func(), error) {
-	if !IsGitURL(url) {
+// Returns the path to the cloned directory (or the requested subdirectory within it)
+// and a cleanup function. Besides Git URLs, local bare repositories can be cloned.
+func CloneRepository(url string, opts CloneOptions) (string, func(), error) {
+	if !opts.ForceRemote && !IsGitURL(url) && !IsBareRepository(url) {
 		return "", nil, fmt.Errorf("invalid Git URL: %s", url)
 	}
 
+	spec := ParseGitURL(url)
+	if _, err := os.Stat(spec.URL); err == nil {
+		// Local repository paths are resolved before cloning from the temporary directory
+		if absPath, err := filepath.Abs(spec.URL); err == nil {
+			spec.URL = absPath
+		}
+	}
+	if strings.HasPrefix(spec.URL, "-") {
+		return "", nil, fmt.Errorf("invalid Git URL: %s", url)
+	}
+	if opts.Ref != "" {
+		spec.Ref = opts.Ref
+		spec.Subdir = ""
+	} else if spec.Subdir != "" {
+		if err := validateRef(spec.refPath); err != nil {
+			return "", nil, err
+		}
+		spec.Ref, spec.Subdir = resolveRefPath(spec.URL, spec.refPath)
+	}
+	if opts.Subdir != "" {
+		spec.Subdir = opts.Subdir
+	}
+	if spec.Ref != "" {
+		if err := validateRef(spec.Ref); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if opts.Cache != nil {
+		return opts.Cache.checkout(spec)
+	}
+	return clone(spec)
+}
+
+// clone performs a shallow fetch of spec.Ref into a temporary directory, limiting the
+// checkout to spec.Subdir with a sparse checkout when possible
+func clone(spec RemoteSpec) (string, func(), error) {
+	subdir, err := cleanSubdir(spec.Subdir)
+	if err != nil {
+		return "", nil, err
+	}
+
 	// Create a temporary directory
 	tempDir, err := os.MkdirTemp("", "codegrab-clone-*")
 	if err != nil {
@@ -47,27 +209,165 @@ func CloneRepository(url string) (string, func(), error) {
 		os.RemoveAll(tempDir)
 	}
 
-	// Clone the repository with shallow depth
-	cmd := exec.Command("git", "clone", "--depth=1", url, tempDir)
-	cmd.Stderr = os.Stderr
+	if err := initClone(tempDir, spec.URL); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	if subdir != "" {
+		// Older Git versions lack sparse-checkout; fall back to a full checkout
+		if _, err := runGit(tempDir, "sparse-checkout", "set", "--cone", "--end-of-options", subdir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: sparse checkout unavailable, checking out the full tree: %v\n", err)
+		}
+	}
 
-	if err := cmd.Run(); err != nil {
+	// Clone the ref with shallow depth
+	if err := checkoutRef(tempDir, spec.URL, spec.Ref); err != nil {
 		cleanup()
-		return "", nil, fmt.Errorf("failed to clone repository %s: %w", url, err)
+		return "", nil, err
 	}
 
-	// Verify the cloned directory exists and is valid
-	if stat, err := os.Stat(tempDir); err != nil || !stat.IsDir() {
+	root, err := checkoutRoot(tempDir, subdir, spec.Ref)
+	if err != nil {
 		cleanup()
-		return "", nil, fmt.Errorf("cloned directory is invalid: %s", tempDir)
+		return "", nil, err
+	}
+
+	return root, cleanup, nil
+}
+
+// initClone creates an empty repository in dir with url as its origin remote
+func initClone(dir, url string) error {
+	if _, err := runGit(dir, "init", "-q"); err != nil {
+		return fmt.Errorf("failed to initialize clone directory: %w", err)
+	}
+	if _, err := runGit(dir, "remote", "add", "--end-of-options", "origin", url); err != nil {
+		return fmt.Errorf("failed to add remote %s: %w", url, err)
+	}
+	return nil
+}
+
+// checkoutRef fetches ref (the remote HEAD if empty) from origin and checks it out in dir
+func checkoutRef(dir, url, ref string) error {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if err := fetchRef(dir, ref); err != nil {
+		return fmt.Errorf("failed to clone repository %s: %w", url, err)
+	}
+	if _, err := runGit(dir, "checkout", "-q", "--force", "FETCH_HEAD"); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", ref, err)
+	}
+	return nil
+}
+
+// checkoutRoot returns the directory to grab within the checkout at dir, verifying that it exists
+func checkoutRoot(dir, subdir, ref string) (string, error) {
+	root := filepath.Join(dir, filepath.FromSlash(subdir))
+
+	// Verify the cloned directory exists and is valid
+	if stat, err := os.Stat(root); err != nil || !stat.IsDir() {
+		if subdir != "" {
+			if ref == "" {
+				ref = "HEAD"
+			}
+			return "", fmt.Errorf("subdirectory %q not found at %s", subdir, ref)
+		}
+		return "", fmt.Errorf("cloned directory is invalid: %s", dir)
+	}
+	return root, nil
+}
+
+// fetchRef fetches a single ref with depth 1. Abbreviated commit hashes cannot be
+// fetched directly, so it falls back to a full fetch when the shallow fetch fails.
+func fetchRef(dir, ref string) error {
+	err := runFetch(dir, "--depth=1", "--end-of-options", "origin", ref)
+	if err == nil || !commitHashPattern.MatchString(ref) {
+		return err
+	}
+
+	if err := runFetch(dir, "--end-of-options", "origin"); err != nil {
+		return err
+	}
+	hash, err := runGit(dir, "rev-parse", "--verify", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("commit %s not found: %w", ref, err)
 	}
+	_, err = runGit(dir, "update-ref", "FETCH_HEAD", hash)
+	return err
+}
 
-	return tempDir, cleanup, nil
+// runFetch runs git fetch in dir, including git's error output in the returned error
+func runFetch(dir string, args ...string) error {
+	cmd := exec.Command("git", append([]string{"-C", dir, "fetch", "-q"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git fetch %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// validateRef rejects refs that git would take for an option, such as
+// "--upload-pack=...", and refs that are not valid ref names
+func validateRef(ref string) error {
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid ref %q: must not start with '-'", ref)
+	}
+	if err := exec.Command("git", "check-ref-format", "--allow-onelevel", ref).Run(); err != nil {
+		return fmt.Errorf("invalid ref %q", ref)
+	}
+	return nil
+}
+
+// commitHashPattern matches full or abbreviated commit hashes
+var commitHashPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
+
+// resolveRefPath splits "ref/sub/dir" into a ref and subdirectory by matching the longest
+// prefix against the branches and tags of the remote. If the remote cannot be listed,
+// the first path segment is used as the ref.
+func resolveRefPath(url, refPath string) (string, string) {
+	parts := strings.Split(refPath, "/")
+
+	out, err := exec.Command("git", "ls-remote", "--heads", "--tags", "--end-of-options", url).Output()
+	if err == nil {
+		refs := make(map[string]bool)
+		for _, line := range strings.Split(string(out), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			name := strings.TrimSuffix(fields[1], "^{}")
+			name = strings.TrimPrefix(name, "refs/heads/")
+			name = strings.TrimPrefix(name, "refs/tags/")
+			refs[name] = true
+		}
+		for i := len(parts); i > 1; i-- {
+			if candidate := strings.Join(parts[:i], "/"); refs[candidate] {
+				return candidate, strings.Join(parts[i:], "/")
+			}
+		}
+	}
+
+	return parts[0], strings.Join(parts[1:], "/")
+}
+
+// cleanSubdir normalizes a subdirectory path and rejects paths outside of the repository
+func cleanSubdir(subdir string) (string, error) {
+	if subdir == "" {
+		return "", nil
+	}
+	cleaned := path.Clean(strings.Trim(filepath.ToSlash(subdir), "/"))
+	if cleaned == "." {
+		return "", nil
+	}
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid subdirectory %q: must be a path inside the repository", subdir)
+	}
+	return cleaned, nil
 }
 
 // GetRepoName extracts a repository name from a Git URL for display purposes
 func GetRepoName(url string) string {
-	url = strings.TrimSpace(url)
+	url = ParseGitURL(url).URL
 	url = strings.TrimSuffix(url, "/")
 	url = strings.TrimSuffix(url, ".git")
 
@@ -93,3 +393,113 @@ func GetRepoName(url string) string {
 	return filepath.Base(url)
 }
 
+// RepoInfo describes the checked-out state of a local Git repository
+type RepoInfo struct {
+	Name          string
+	Branch        string
+	CommitHash    string
+	CommitSubject string
+	Dirty         bool
+}
+
+// IsRepository reports whether dir is inside a Git working tree
+func IsRepository(dir string) bool {
+	out, err := runGit(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && out == "true"
+}
+
+// GetRepoInfo reads the repository name, branch, HEAD commit and dirty state for dir.
+// Fields that cannot be determined (e.g. no commits yet) are left empty.
+func GetRepoInfo(dir string) (*RepoInfo, error) {
+	topLevel, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %s", dir)
+	}
+
+	info := &RepoInfo{Name: filepath.Base(topLevel)}
+	if remote, err := runGit(dir, "remote", "get-url", "origin"); err == nil && remote != "" {
+		info.Name = GetRepoName(remote)
+	}
+
+	if branch, err := runGit(dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+		info.Branch = branch
+	}
+
+	if commit, err := runGit(dir, "log", "-1", "--format=%H%n%s"); err == nil && commit != "" {
+		hash, subject, _ := strings.Cut(commit, "\n")
+		info.CommitHash = hash
+		info.CommitSubject = subject
+	}
+
+	if status, err := runGit(dir, "status", "--porcelain"); err == nil {
+		info.Dirty = status != ""
+	}
+
+	return info, nil
+}
+
+// runGit runs a git command in dir and returns its trimmed stdout
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Commit describes a single commit in a file's history
+type Commit struct {
+	Hash       string
+	AuthorDate string
+	Subject    string
+	Diff       string
+}
+
+// ShortHash returns the abbreviated commit hash
+func (c Commit) ShortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+	return c.Hash
+}
+
+// FileHistory returns the last limit commits that touched path (relative to dir),
+// following renames. When withDiff is true, each commit includes its patch for the file.
+func FileHistory(dir, path string, limit int, withDiff bool) ([]Commit, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	// Each record starts with a record separator, fields are split by a unit separator
+	args := []string{"log", "--follow", fmt.Sprintf("-n%d", limit), "--format=%x1e%H%x1f%aI%x1f%s"}
+	if withDiff {
+		args = append(args, "-p", "--no-color")
+	}
+	args = append(args, "--", path)
+
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history for %s: %w", path, err)
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(string(out), "\x1e") {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+		header, diff, _ := strings.Cut(record, "\n")
+		fields := strings.SplitN(header, "\x1f", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		commits = append(commits, Commit{
+			Hash:       fields[0],
+			AuthorDate: fields[1],
+			Subject:    fields[2],
+			Diff:       strings.TrimSpace(diff),
+		})
+	}
+	return commits, nil
+}
@@ -2,6 +2,9 @@ package git
 
 import (
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -18,6 +21,12 @@ func TestIsGitURL(t *testing.T) {
 		{"git@github.com:user/repo.git", true},
 		{"ssh://git@github.com/user/repo.git", true},
 		{"http://github.com/user/repo.git", true},
+		{"https://github.com/user/repo/tree/v2/pkg/foo", true},
+		{"https://github.com/user/repo/commit/0123abc", true},
+		{"https://gitlab.com/group/sub/repo/-/tree/main/docs", true},
+		{"https://bitbucket.org/user/repo/src/main/pkg", true},
+		{"file:///srv/git/repo.git", true},
+		{"git://example.com/repo.git", true},
 
 		// Invalid URLs
 		{"./local/path", false},
@@ -26,6 +35,7 @@ func TestIsGitURL(t *testing.T) {
 		{"not-a-url", false},
 		{"https://example.com", false},
 		{"https://github.com", false},
+		{"https://git.example.com/team/repo", false},
 		{"", false},
 		{"   ", false},
 	}
@@ -51,6 +61,8 @@ func TestGetRepoName(t *testing.T) {
 		{"git@github.com:user/repo.git", "repo"},
 		{"ssh://git@github.com/user/repo.git", "repo"},
 		{"https://github.com/user/repo/", "repo"},
+		{"https://github.com/user/repo/tree/v2/pkg/foo", "repo"},
+		{"https://gitlab.com/group/sub/repo/-/tree/main/docs", "repo"},
 	}
 
 	for _, test := range tests {
@@ -63,8 +75,56 @@ func TestGetRepoName(t *testing.T) {
 	}
 }
 
+func TestParseGitURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected RemoteSpec
+	}{
+		{"https://github.com/user/repo", RemoteSpec{URL: "https://github.com/user/repo"}},
+		{"https://github.com/user/repo.git", RemoteSpec{URL: "https://github.com/user/repo.git"}},
+		{"git@github.com:user/repo.git", RemoteSpec{URL: "git@github.com:user/repo.git"}},
+		{"https://github.com/user/repo/tree/v2", RemoteSpec{URL: "https://github.com/user/repo", Ref: "v2", refPath: "v2"}},
+		{"https://github.com/user/repo/tree/v2/pkg/foo/", RemoteSpec{URL: "https://github.com/user/repo", Ref: "v2", Subdir: "pkg/foo", refPath: "v2/pkg/foo"}},
+		{"https://github.com/user/repo/commit/0123abc", RemoteSpec{URL: "https://github.com/user/repo", Ref: "0123abc"}},
+		{"https://github.com/src/repo", RemoteSpec{URL: "https://github.com/src/repo"}},
+		{"https://gitlab.com/group/sub/repo/-/tree/main/docs", RemoteSpec{URL: "https://gitlab.com/group/sub/repo", Ref: "main", Subdir: "docs", refPath: "main/docs"}},
+		{"https://gitlab.com/group/tree/repo/-/commit/0123abc", RemoteSpec{URL: "https://gitlab.com/group/tree/repo", Ref: "0123abc"}},
+		{"https://bitbucket.org/user/repo/src/main/pkg", RemoteSpec{URL: "https://bitbucket.org/user/repo", Ref: "main", Subdir: "pkg", refPath: "main/pkg"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			result := ParseGitURL(test.url)
+			if result != test.expected {
+				t.Errorf("ParseGitURL(%q) = %+v, expected %+v", test.url, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestCleanSubdir(t *testing.T) {
+	valid := map[string]string{
+		"":           "",
+		".":          "",
+		"pkg/foo/":   "pkg/foo",
+		"/pkg/./foo": "pkg/foo",
+	}
+	for input, expected := range valid {
+		result, err := cleanSubdir(input)
+		if err != nil || result != expected {
+			t.Errorf("cleanSubdir(%q) = %q, %v; expected %q", input, result, err, expected)
+		}
+	}
+
+	for _, input := range []string{"..", "../outside", "pkg/../../outside"} {
+		if _, err := cleanSubdir(input); err == nil {
+			t.Errorf("cleanSubdir(%q) should fail for a path outside the repository", input)
+		}
+	}
+}
+
 func TestCloneRepository_InvalidURL(t *testing.T) {
-	_, cleanup, err := CloneRepository("not-a-git-url")
+	_, cleanup, err := CloneRepository("not-a-git-url", CloneOptions{})
 	if err == nil {
 		if cleanup != nil {
 			cleanup()
@@ -89,7 +149,7 @@ func TestCloneRepository_NetworkTest(t *testing.T) {
 	}
 
 	// Test with a small public repository
-	tempDir, cleanup, err := CloneRepository("https://github.com/octocat/Hello-World.git")
+	tempDir, cleanup, err := CloneRepository("https://github.com/octocat/Hello-World.git", CloneOptions{})
 	if err != nil {
 		t.Skipf("Could not clone test repository (network issue?): %v", err)
 		return
@@ -108,3 +168,418 @@ func TestCloneRepository_NetworkTest(t *testing.T) {
 	}
 }
 
+// initTestRepo creates a Git repository in a temporary directory with a single commit
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found, skipping repository test")
+	}
+
+	dir := t.TempDir()
+	gitCmd(t, dir, "init", "-q", "-b", "main")
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	gitCmd(t, dir, "add", ".")
+	gitCmd(t, dir, "commit", "-q", "-m", "Initial commit")
+	return dir
+}
+
+// gitCmd runs a git command in dir with a fixed test identity
+func gitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func TestGetRepoInfo(t *testing.T) {
+	dir := initTestRepo(t)
+
+	if !IsRepository(dir) {
+		t.Fatalf("Expected %s to be a repository", dir)
+	}
+
+	info, err := GetRepoInfo(dir)
+	if err != nil {
+		t.Fatalf("GetRepoInfo failed: %v", err)
+	}
+	if info.Name != filepath.Base(dir) {
+		t.Errorf("Expected name %q, got %q", filepath.Base(dir), info.Name)
+	}
+	if info.Branch != "main" {
+		t.Errorf("Expected branch %q, got %q", "main", info.Branch)
+	}
+	if len(info.CommitHash) != 40 {
+		t.Errorf("Expected a full commit hash, got %q", info.CommitHash)
+	}
+	if info.CommitSubject != "Initial commit" {
+		t.Errorf("Expected subject %q, got %q", "Initial commit", info.CommitSubject)
+	}
+	if info.Dirty {
+		t.Errorf("Expected a clean working tree")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify file: %v", err)
+	}
+	gitCmd(t, dir, "remote", "add", "origin", "https://github.com/user/my-repo.git")
+
+	info, err = GetRepoInfo(dir)
+	if err != nil {
+		t.Fatalf("GetRepoInfo failed: %v", err)
+	}
+	if !info.Dirty {
+		t.Errorf("Expected a dirty working tree after modifying a file")
+	}
+	if info.Name != "my-repo" {
+		t.Errorf("Expected name from origin remote, got %q", info.Name)
+	}
+}
+
+func TestGetRepoInfo_NotARepository(t *testing.T) {
+	dir := t.TempDir()
+	if IsRepository(dir) {
+		t.Skip("temporary directory is inside a git repository")
+	}
+	if _, err := GetRepoInfo(dir); err == nil {
+		t.Error("Expected an error for a directory outside of a repository")
+	}
+}
+
+func TestFileHistory(t *testing.T) {
+	dir := initTestRepo(t)
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify file: %v", err)
+	}
+	gitCmd(t, dir, "commit", "-q", "-am", "Add main function")
+	gitCmd(t, dir, "mv", "main.go", "app.go")
+	gitCmd(t, dir, "commit", "-q", "-m", "Rename main.go")
+
+	history, err := FileHistory(dir, "app.go", 10, false)
+	if err != nil {
+		t.Fatalf("FileHistory failed: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 commits following the rename, got %d: %+v", len(history), history)
+	}
+	if history[0].Subject != "Rename main.go" || history[2].Subject != "Initial commit" {
+		t.Errorf("Expected newest commit first, got %q ... %q", history[0].Subject, history[2].Subject)
+	}
+	if history[1].Diff != "" {
+		t.Errorf("Expected no diff unless requested")
+	}
+	if len(history[0].ShortHash()) != 7 || history[0].AuthorDate == "" {
+		t.Errorf("Expected short hash and author date, got %+v", history[0])
+	}
+
+	history, err = FileHistory(dir, "app.go", 1, true)
+	if err != nil {
+		t.Fatalf("FileHistory failed: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("Expected history to be limited to 1 commit, got %d", len(history))
+	}
+
+	history, err = FileHistory(dir, "app.go", 3, true)
+	if err != nil {
+		t.Fatalf("FileHistory failed: %v", err)
+	}
+	if !strings.Contains(history[1].Diff, "+func main() {}") {
+		t.Errorf("Expected diff to contain the added line, got %q", history[1].Diff)
+	}
+}
+
+// initBareRemote creates a bare repository with a main branch, a "feature/x" branch
+// and a v1 tag, and returns its file:// URL and the hash of the tagged commit
+func initBareRemote(t *testing.T) (string, string) {
+	t.Helper()
+	src := initTestRepo(t)
+
+	if err := os.MkdirAll(filepath.Join(src, "pkg", "foo"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "pkg", "foo", "a.go"), []byte("package foo\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	gitCmd(t, src, "add", ".")
+	gitCmd(t, src, "commit", "-q", "-m", "Add foo")
+	gitCmd(t, src, "tag", "v1")
+	tagged := strings.TrimSpace(gitCmd(t, src, "rev-parse", "HEAD"))
+
+	if err := os.WriteFile(filepath.Join(src, "pkg", "foo", "b.go"), []byte("package foo\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	gitCmd(t, src, "add", ".")
+	gitCmd(t, src, "commit", "-q", "-m", "Add b")
+	gitCmd(t, src, "branch", "feature/x", "v1")
+
+	bare := filepath.Join(t.TempDir(), "remote.git")
+	gitCmd(t, src, "clone", "-q", "--bare", src, bare)
+	return "file://" + filepath.ToSlash(bare), tagged
+}
+
+func TestClone(t *testing.T) {
+	url, tagged := initBareRemote(t)
+
+	tests := []struct {
+		name     string
+		spec     RemoteSpec
+		expected []string
+		missing  []string
+	}{
+		{"default branch", RemoteSpec{URL: url}, []string{"main.go", "pkg/foo/a.go", "pkg/foo/b.go"}, nil},
+		{"tag", RemoteSpec{URL: url, Ref: "v1"}, []string{"main.go", "pkg/foo/a.go"}, []string{"pkg/foo/b.go"}},
+		{"commit", RemoteSpec{URL: url, Ref: tagged}, []string{"pkg/foo/a.go"}, []string{"pkg/foo/b.go"}},
+		{"abbreviated commit", RemoteSpec{URL: url, Ref: tagged[:10]}, []string{"pkg/foo/a.go"}, []string{"pkg/foo/b.go"}},
+		{"branch with subdirectory", RemoteSpec{URL: url, Ref: "main", Subdir: "pkg/foo"}, []string{"a.go", "b.go"}, []string{"main.go"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, cleanup, err := clone(test.spec)
+			if err != nil {
+				t.Fatalf("clone failed: %v", err)
+			}
+			defer cleanup()
+
+			for _, file := range test.expected {
+				if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(file))); err != nil {
+					t.Errorf("Expected %s to be checked out: %v", file, err)
+				}
+			}
+			for _, file := range test.missing {
+				if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(file))); err == nil {
+					t.Errorf("Expected %s not to be checked out", file)
+				}
+			}
+		})
+	}
+
+	if _, _, err := clone(RemoteSpec{URL: url, Subdir: "missing"}); err == nil {
+		t.Error("clone should fail for a subdirectory that does not exist")
+	}
+}
+
+func TestResolveRefPath(t *testing.T) {
+	url, _ := initBareRemote(t)
+
+	tests := []struct {
+		refPath, ref, subdir string
+	}{
+		{"main/pkg/foo", "main", "pkg/foo"},
+		{"feature/x/pkg/foo", "feature/x", "pkg/foo"},
+		{"v1/pkg", "v1", "pkg"},
+		{"unknown/pkg", "unknown", "pkg"},
+	}
+
+	for _, test := range tests {
+		ref, subdir := resolveRefPath(url, test.refPath)
+		if ref != test.ref || subdir != test.subdir {
+			t.Errorf("resolveRefPath(%q) = %q, %q; expected %q, %q", test.refPath, ref, subdir, test.ref, test.subdir)
+		}
+	}
+}
+
+func TestAddForgeHosts(t *testing.T) {
+	original := GitURLPatterns
+	defer func() { GitURLPatterns = original }()
+
+	AddForgeHosts("git.example.com", "https://Gitea.Internal:3000/", " ")
+
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"https://git.example.com/team/repo", true},
+		{"https://git.example.com/group/sub/repo/-/tree/main/docs", true},
+		{"http://gitea.internal:3000/team/repo", true},
+		{"https://git.example.com/team", false},
+		{"https://git.example.com.evil.com/team/repo", false},
+	}
+
+	for _, test := range tests {
+		if result := IsGitURL(test.url); result != test.expected {
+			t.Errorf("IsGitURL(%q) = %v, expected %v", test.url, result, test.expected)
+		}
+	}
+}
+
+func TestCloneRepository_RejectsOptionLikeInput(t *testing.T) {
+	url, _ := initBareRemote(t)
+	marker := filepath.Join(t.TempDir(), "pwned")
+	injection := "--upload-pack=touch " + marker
+
+	tests := []struct {
+		name string
+		url  string
+		opts CloneOptions
+	}{
+		{"ref option", url, CloneOptions{Ref: injection}},
+		{"ref with a refspec", url, CloneOptions{Ref: "main:refs/heads/other"}},
+		{"ref from a tree URL", "https://github.com/user/repo/tree/" + injection + "/pkg", CloneOptions{}},
+		{"ref from a commit URL", "https://github.com/user/repo/commit/" + injection, CloneOptions{}},
+		{"URL option", "--upload-pack=touch " + marker, CloneOptions{ForceRemote: true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, cleanup, err := CloneRepository(test.url, test.opts)
+			if err == nil {
+				cleanup()
+				t.Fatalf("Expected CloneRepository to reject %q", test.url)
+			}
+			if _, statErr := os.Stat(marker); statErr == nil {
+				t.Fatalf("Expected no command to run, but %s was created", marker)
+			}
+		})
+	}
+}
+
+func TestValidateRef(t *testing.T) {
+	for _, ref := range []string{"main", "v2.0.0", "feature/x", "HEAD", "abc1234"} {
+		if err := validateRef(ref); err != nil {
+			t.Errorf("validateRef(%q) = %v, expected it to be valid", ref, err)
+		}
+	}
+	for _, ref := range []string{"-x", "--upload-pack=sh", "a..b", "a:b", "a b", ""} {
+		if err := validateRef(ref); err == nil {
+			t.Errorf("validateRef(%q) should fail", ref)
+		}
+	}
+}
+
+func TestCloneRepository_LocalRemotes(t *testing.T) {
+	url, _ := initBareRemote(t)
+	barePath := strings.TrimPrefix(url, "file://")
+	workTree := initTestRepo(t)
+
+	if !IsBareRepository(barePath) {
+		t.Errorf("Expected %s to be detected as a bare repository", barePath)
+	}
+	if IsBareRepository(workTree) || IsBareRepository(t.TempDir()) {
+		t.Error("Expected work trees and plain directories not to be bare repositories")
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		opts CloneOptions
+	}{
+		{"file URL", url, CloneOptions{}},
+		{"bare repository path", barePath, CloneOptions{Subdir: "pkg"}},
+		{"forced remote", workTree, CloneOptions{ForceRemote: true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, cleanup, err := CloneRepository(test.url, test.opts)
+			if err != nil {
+				t.Fatalf("CloneRepository failed: %v", err)
+			}
+			defer cleanup()
+
+			entries, err := os.ReadDir(root)
+			if err != nil || len(entries) == 0 {
+				t.Errorf("Expected a checked out tree at %s, got %v", root, err)
+			}
+		})
+	}
+
+	if _, _, err := CloneRepository(workTree, CloneOptions{}); err == nil {
+		t.Error("CloneRepository should reject a work tree path unless ForceRemote is set")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	dir := initTestRepo(t)
+
+	files := map[string]string{
+		"pkg/staged.go":   "package pkg\n",
+		"pkg/modified.go": "package pkg\n",
+		"pkg/renamed.go":  "package pkg\n\nvar renamed = 1\n",
+	}
+	for file, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	gitCmd(t, dir, "add", ".")
+	gitCmd(t, dir, "commit", "-q", "-m", "Add pkg")
+
+	write := func(file, content string) {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(file)), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	write("pkg/staged.go", "package pkg\n\nvar staged = 1\n")
+	gitCmd(t, dir, "add", "pkg/staged.go")
+	write("pkg/modified.go", "package pkg\n\nvar modified = 1\n")
+	write("pkg/added.go", "package pkg\n\nvar added = 1\n")
+	gitCmd(t, dir, "add", "pkg/added.go")
+	write("pkg/untracked.go", "package pkg\n")
+	gitCmd(t, dir, "mv", "pkg/renamed.go", "pkg/moved.go")
+
+	expected := map[string]FileStatus{
+		"pkg/staged.go":    StatusStaged,
+		"pkg/modified.go":  StatusModified,
+		"pkg/added.go":     StatusAdded,
+		"pkg/untracked.go": StatusUntracked,
+		"pkg/moved.go":     StatusStaged,
+	}
+
+	statuses, err := Status(dir)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(statuses) != len(expected) {
+		t.Errorf("Expected %d changed files, got %v", len(expected), statuses)
+	}
+	for path, status := range expected {
+		if statuses[path] != status {
+			t.Errorf("Expected %s to be %s, got %s", path, status, statuses[path])
+		}
+	}
+
+	// Paths are relative to the given directory, even below the repository root
+	statuses, err = Status(filepath.Join(dir, "pkg"))
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if statuses["modified.go"] != StatusModified || len(statuses) != len(expected) {
+		t.Errorf("Expected paths relative to pkg/, got %v", statuses)
+	}
+}
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected FileStatus
+	}{
+		{"??", StatusUntracked},
+		{"!!", StatusUnmodified},
+		{"UU", StatusConflicted},
+		{"AA", StatusConflicted},
+		{"DU", StatusConflicted},
+		{"MM", StatusModified},
+		{" M", StatusModified},
+		{"AM", StatusModified},
+		{"A ", StatusAdded},
+		{"M ", StatusStaged},
+		{"R ", StatusStaged},
+	}
+
+	for _, test := range tests {
+		if result := classifyStatus(test.code[0], test.code[1]); result != test.expected {
+			t.Errorf("classifyStatus(%q) = %s, expected %s", test.code, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// FileStatus is the working tree state of a file. Higher values take precedence
+// when the status of a directory is derived from its files.
+type FileStatus int
+
+const (
+	StatusUnmodified FileStatus = iota
+	StatusUntracked
+	StatusStaged
+	StatusAdded
+	StatusModified
+	StatusConflicted
+)
+
+// Marker returns the single-character marker shown next to files with this status
+func (s FileStatus) Marker() string {
+	switch s {
+	case StatusUntracked:
+		return "?"
+	case StatusStaged:
+		return "S"
+	case StatusAdded:
+		return "A"
+	case StatusModified:
+		return "M"
+	case StatusConflicted:
+		return "!"
+	default:
+		return ""
+	}
+}
+
+// String returns a human-readable name of the status
+func (s FileStatus) String() string {
+	switch s {
+	case StatusUntracked:
+		return "untracked"
+	case StatusStaged:
+		return "staged"
+	case StatusAdded:
+		return "added"
+	case StatusModified:
+		return "modified"
+	case StatusConflicted:
+		return "conflicted"
+	default:
+		return "unmodified"
+	}
+}
+
+// Status returns the working tree state of the changed files under dir, keyed by
+// their slash-separated path relative to dir. Unmodified files are omitted.
+func Status(dir string) (map[string]FileStatus, error) {
+	prefix, err := runGit(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git status: %w", err)
+	}
+
+	cmd := exec.Command("git", "-C", dir, "status", "--porcelain=v1", "-z", "--untracked-files=all", "--", ".")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git status: %w", err)
+	}
+	return parseStatus(string(out), prefix), nil
+}
+
+// parseStatus parses the NUL-separated output of git status --porcelain=v1 -z.
+// Paths in that output are relative to the repository root, so entries outside
+// prefix are dropped and the prefix is stripped from the rest.
+func parseStatus(out, prefix string) map[string]FileStatus {
+	statuses := make(map[string]FileStatus)
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		x, y, path := entry[0], entry[1], entry[3:]
+		if x == 'R' || x == 'C' {
+			// Renames and copies are followed by the original path
+			i++
+		}
+
+		status := classifyStatus(x, y)
+		if status == StatusUnmodified {
+			continue
+		}
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		statuses[strings.TrimPrefix(path, prefix)] = status
+	}
+	return statuses
+}
+
+// classifyStatus maps the two-letter porcelain status code to a FileStatus
+func classifyStatus(x, y byte) FileStatus {
+	switch {
+	case x == '?' && y == '?':
+		return StatusUntracked
+	case x == '!' && y == '!':
+		return StatusUnmodified
+	case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+		return StatusConflicted
+	case y != ' ':
+		return StatusModified
+	case x == 'A':
+		return StatusAdded
+	case x != ' ':
+		return StatusStaged
+	default:
+		return StatusUnmodified
+	}
+}
@@ -0,0 +1,287 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/epilande/codegrab/internal/project"
+	"github.com/epilande/codegrab/internal/utils"
+)
+
+// maxRequestBody is the maximum size of a request body
+const maxRequestBody = 1 << 20
+
+// Server serves a JSON API over HTTP for editor integrations. It implements
+// http.Handler.
+type Server struct {
+	*project.Project
+	mux   *http.ServeMux
+	Token string // bearer token requests must present, if not empty
+	// Addr is the address the server listens on. Requests must name its host, an IP
+	// address or localhost as their Host, so that web pages cannot reach the server
+	// through a domain rebound to a local address.
+	Addr string
+}
+
+// NewServer returns a server for the project at root. Secrets are redacted from
+// everything it returns unless redactSecrets is false.
+func NewServer(root string, redactSecrets bool) (*Server, error) {
+	p, err := project.New(root, redactSecrets)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{Project: p, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /api/health", s.handleHealth)
+	s.mux.HandleFunc("GET /api/tree", s.handleTree)
+	s.mux.HandleFunc("GET /api/file", s.handleFile)
+	s.mux.HandleFunc("GET /api/deps", s.handleDeps)
+	s.mux.HandleFunc("POST /api/tokens", s.handleTokens)
+	s.mux.HandleFunc("POST /api/generate", s.handleGenerate)
+	return s, nil
+}
+
+// ServeHTTP checks the host, the origin and the bearer token of the request and
+// dispatches it to its handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.allowedHost(r.Host) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not allowed", r.Host))
+		return
+	}
+	// The API serves no pages, so a request with an origin comes from another site
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || !strings.EqualFold(u.Host, r.Host) {
+			writeError(w, http.StatusForbidden, errors.New("cross-origin requests are not allowed"))
+			return
+		}
+	}
+	if s.Token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="codegrab"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// allowedHost reports whether a request may name hostport as its Host. DNS rebinding
+// needs a domain name, so IP addresses are always allowed.
+func (s *Server) allowedHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") || net.ParseIP(host) != nil {
+		return true
+	}
+	addrHost, _, err := net.SplitHostPort(s.Addr)
+	return err == nil && addrHost != "" && strings.EqualFold(addrHost, host)
+}
+
+// fileEntry is a file or directory of the tree
+type fileEntry struct {
+	Path    string `json:"path"`
+	ModTime string `json:"mod_time,omitempty"`
+	Size    int64  `json:"size"`
+	Tokens  int64  `json:"tokens"`
+	IsDir   bool   `json:"is_dir"`
+}
+
+// tokenCount is the estimated number of tokens a file contributes to a bundle
+type tokenCount struct {
+	Path   string `json:"path"`
+	Tokens int    `json:"tokens"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":         "ok",
+		"version":        utils.Version,
+		"root":           s.Root,
+		"redact_secrets": s.RedactSecrets,
+	})
+}
+
+// handleTree lists the files below a path, with their sizes and estimated tokens
+func (s *Server) handleTree(w http.ResponseWriter, r *http.Request) {
+	filters, err := filtersFromQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	files, err := s.Walk(r.Context(), filters)
+	if err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
+
+	entries := make([]fileEntry, 0, len(files))
+	var totalFiles int
+	var totalTokens int64
+	for _, f := range files {
+		entry := fileEntry{Path: f.Path, IsDir: f.IsDir}
+		if !f.ModTime.IsZero() {
+			entry.ModTime = f.ModTime.UTC().Format(time.RFC3339)
+		}
+		if !f.IsDir {
+			entry.Size = f.Size
+			entry.Tokens = utils.EstimateTokensForSize(f.Size)
+			totalFiles++
+			totalTokens += entry.Tokens
+		}
+		entries = append(entries, entry)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"files":        entries,
+		"total_files":  totalFiles,
+		"total_tokens": totalTokens,
+	})
+}
+
+// handleFile returns the content of a text file, with its secrets redacted
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	rel, text, err := s.ReadFile(r.URL.Query().Get("path"))
+	if err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"path": rel, "content": text})
+}
+
+// handleDeps resolves the dependencies of a file
+func (s *Server) handleDeps(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	maxDepth := 1
+	if value := query.Get("max_depth"); value != "" {
+		var err error
+		if maxDepth, err = strconv.Atoi(value); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid max_depth %q", value))
+			return
+		}
+	}
+
+	rel, deps, err := s.FileDependencies(r.Context(), query.Get("path"), maxDepth)
+	if err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
+	if deps == nil {
+		deps = []project.Dependency{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"path": rel, "dependencies": deps})
+}
+
+// handleTokens counts the tokens of a bundle without returning its content
+func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
+	bundle, ok := s.bundle(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tokens":  bundle.Tokens,
+		"files":   tokenCounts(bundle),
+		"omitted": nonNil(bundle.Omitted),
+	})
+}
+
+// handleGenerate renders a bundle
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	bundle, ok := s.bundle(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"content": bundle.Output,
+		"format":  bundle.Format,
+		"tokens":  bundle.Tokens,
+		"secrets": bundle.Secrets,
+		"files":   tokenCounts(bundle),
+		"omitted": nonNil(bundle.Omitted),
+	})
+}
+
+// bundle decodes the bundle options of the request body and renders the bundle,
+// writing the error response if that fails
+func (s *Server) bundle(w http.ResponseWriter, r *http.Request) (*project.Bundle, bool) {
+	opts := project.DefaultBundleOptions()
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return nil, false
+	}
+
+	bundle, err := s.Bundle(r.Context(), opts)
+	if err != nil {
+		writeRequestError(w, r, err)
+		return nil, false
+	}
+	return bundle, true
+}
+
+// filtersFromQuery reads the filters of a request from its query parameters
+func filtersFromQuery(r *http.Request) (project.Filters, error) {
+	query := r.URL.Query()
+	filters := project.Filters{
+		Path:        query.Get("path"),
+		MaxFileSize: query.Get("max_file_size"),
+		Glob:        query["glob"],
+	}
+	for name, target := range map[string]*bool{"include_hidden": &filters.IncludeHidden, "include_ignored": &filters.IncludeIgnored} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return filters, fmt.Errorf("invalid %s %q", name, value)
+			}
+			*target = parsed
+		}
+	}
+	return filters, nil
+}
+
+func tokenCounts(bundle *project.Bundle) []tokenCount {
+	counts := make([]tokenCount, 0, len(bundle.Files))
+	for _, f := range bundle.Files {
+		counts = append(counts, tokenCount{Path: f.Path, Tokens: f.Tokens})
+	}
+	return counts
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// writeRequestError writes the error of a request. Nothing is written when the
+// request was cancelled, since the client is gone.
+func writeRequestError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(r.Context().Err(), context.Canceled) {
+		return
+	}
+	writeError(w, http.StatusBadRequest, err)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
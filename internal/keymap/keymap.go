@@ -0,0 +1,346 @@
+package keymap
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Action names a command of the file tree that can be bound to keys
+type Action string
+
+const (
+	None Action = ""
+
+	CursorDown      Action = "cursor_down"
+	CursorUp        Action = "cursor_up"
+	Collapse        Action = "collapse"
+	Expand          Action = "expand"
+	ToggleExpandAll Action = "toggle_expand_all"
+
+	Search        Action = "search"
+	ContentSearch Action = "content_search"
+
+	ToggleSelect      Action = "toggle_select"
+	VisualSelect      Action = "visual_select"
+	SelectVisible     Action = "select_visible"
+	InvertDirectory   Action = "invert_directory"
+	SelectExtension   Action = "select_extension"
+	ClearSelection    Action = "clear_selection"
+	Undo              Action = "undo"
+	Redo              Action = "redo"
+	Copy              Action = "copy"
+	PreviewOutput     Action = "preview_output"
+	PickSymbols       Action = "pick_symbols"
+	Generate          Action = "generate"
+	ToggleDeps        Action = "toggle_deps"
+	CycleFormat       Action = "cycle_format"
+	ToggleRedaction   Action = "toggle_redaction"
+	ToggleMetadata    Action = "toggle_metadata"
+	SelectChanged     Action = "select_changed"
+	ToggleGitIgnore   Action = "toggle_gitignore"
+	ToggleHidden      Action = "toggle_hidden"
+	TogglePreview     Action = "toggle_preview"
+	ToggleHistory     Action = "toggle_history"
+	ToggleRedacted    Action = "toggle_redacted_preview"
+	ToggleChanged     Action = "toggle_changed_only"
+	CycleSort         Action = "cycle_sort"
+	ToggleLargest     Action = "toggle_largest_files"
+	ToggleStats       Action = "toggle_stats"
+	Refresh           Action = "refresh"
+	Help              Action = "help"
+	Cancel            Action = "cancel"
+	Quit              Action = "quit"
+	GoToTop           Action = "go_to_top"
+	GoToBottom        Action = "go_to_bottom"
+	HalfPageUp        Action = "half_page_up"
+	HalfPageDown      Action = "half_page_down"
+	ScrollPreviewDown Action = "scroll_preview_down"
+	ScrollPreviewUp   Action = "scroll_preview_up"
+	NextMatch         Action = "next_match"
+	PrevMatch         Action = "prev_match"
+)
+
+// QuitKey always quits, so that rebinding keys can never leave the program without a way out
+const QuitKey = "ctrl+c"
+
+// ErrUnknownAction is returned by New for a config entry that names no action. Unlike a
+// conflict between keys, it usually means a typo that leaves the intended keys unbound.
+var ErrUnknownAction = errors.New("unknown action")
+
+// Binding is an entry of the help screen: an action with its default keys. Bindings
+// without an action document keys that cannot be rebound, such as those of search mode.
+type Binding struct {
+	Action Action
+	Keys   []string
+	Help   string
+}
+
+// Section is a titled group of bindings on the help screen
+type Section struct {
+	Title    string
+	Bindings []Binding
+}
+
+// Sections lists every action with its default keys, in the order of the help screen
+var Sections = []Section{
+	{
+		Title: "Navigation",
+		Bindings: []Binding{
+			{CursorDown, []string{"j", "down", "ctrl+n"}, "Move cursor down (or scroll the focused preview)"},
+			{CursorUp, []string{"k", "up", "ctrl+p"}, "Move cursor up (or scroll the focused preview)"},
+			{Collapse, []string{"h", "left"}, "Collapse directory or return focus to file tree"},
+			{Expand, []string{"l", "right"}, "Expand directory or focus preview panel"},
+			{ToggleExpandAll, []string{"e"}, "Toggle expand/collapse all directories"},
+		},
+	},
+	{
+		Title: "Search",
+		Bindings: []Binding{
+			{Search, []string{"/"}, "Start search"},
+			{ContentSearch, []string{"ctrl+f"}, "Start content search (regex, or switch modes while searching)"},
+			{None, []string{"ctrl+n", "down"}, "Next search result"},
+			{None, []string{"ctrl+p", "up"}, "Previous search result"},
+			{None, []string{"tab", "enter"}, "Select/deselect file in search results"},
+			{None, []string{"ctrl+a"}, "Select all files in search results"},
+			{None, []string{"esc"}, "Exit search mode"},
+		},
+	},
+	{
+		Title: "Selection & Output",
+		Bindings: []Binding{
+			{ToggleSelect, []string{" ", "tab"}, "Select/deselect file or directory"},
+			{VisualSelect, []string{"V"}, "Visual mode: mark a range of files, then select it"},
+			{SelectVisible, []string{"A"}, "Select all visible files"},
+			{InvertDirectory, []string{"I"}, "Invert selection in the directory under the cursor"},
+			{SelectExtension, []string{"E"}, "Select all files with the extension under the cursor"},
+			{ClearSelection, []string{"X"}, "Clear selection without reloading files"},
+			{Undo, []string{"u"}, "Undo selection change"},
+			{Redo, []string{"ctrl+r"}, "Redo selection change"},
+			{Copy, []string{"y"}, "Copy generated output to clipboard"},
+			{PreviewOutput, []string{"O"}, "Preview the generated output"},
+			{PickSymbols, []string{"p"}, "Pick symbols or a line range of the previewed file, selecting only those lines"},
+			{Generate, []string{"ctrl+g"}, "Generate output file"},
+			{ToggleDeps, []string{"D"}, "Toggle automatic dependency resolution (Go, TS/JS)"},
+			{CycleFormat, []string{"F"}, "Cycle through output formats (md, txt, xml)"},
+			{ToggleRedaction, []string{"S"}, "Toggle secret redaction (Default: On)"},
+			{ToggleMetadata, []string{"M"}, "Toggle metadata header (git revision, timestamp, filters)"},
+			{SelectChanged, []string{"C"}, "Select all files with git changes"},
+		},
+	},
+	{
+		Title: "View Options",
+		Bindings: []Binding{
+			{ToggleGitIgnore, []string{"i"}, "Toggle .gitignore filter"},
+			{ToggleHidden, []string{"."}, "Toggle hidden files"},
+			{TogglePreview, []string{"P"}, "Toggle file preview pane"},
+			{ToggleHistory, []string{"H"}, "Toggle git history in the preview pane"},
+			{ToggleRedacted, []string{"R"}, "Toggle redacted file content in the preview pane"},
+			{ToggleChanged, []string{"m"}, "Show only files with git changes (M, A, S, ?, ! markers)"},
+			{CycleSort, []string{"s"}, "Cycle through sort modes (name, size, tokens, mtime)"},
+			{ToggleLargest, []string{"L"}, "Toggle flat view of all files, largest first (or by the sort mode)"},
+			{ToggleStats, []string{"T"}, "Toggle project statistics (languages, directories, largest files)"},
+			{Refresh, []string{"r"}, "Refresh file list & reset selection"},
+			{Help, []string{"?"}, "Toggle help screen"},
+			{Cancel, []string{"esc"}, "Close help, cancel visual mode or a running generation"},
+			{Quit, []string{"q", QuitKey}, "Quit, or cancel a running generation"},
+		},
+	},
+	{
+		Title: "Navigation (Vim Style)",
+		Bindings: []Binding{
+			{GoToTop, []string{"gg"}, "Go to top (file tree or preview)"},
+			{GoToBottom, []string{"G"}, "Go to bottom (file tree or preview)"},
+			{HalfPageUp, []string{"ctrl+u"}, "Scroll half page up (file tree or preview)"},
+			{HalfPageDown, []string{"ctrl+d"}, "Scroll half page down (file tree or preview)"},
+		},
+	},
+	{
+		Title: "Preview Navigation",
+		Bindings: []Binding{
+			{ScrollPreviewDown, []string{"J"}, "Scroll preview down (when preview not focused)"},
+			{ScrollPreviewUp, []string{"K"}, "Scroll preview up (when preview not focused)"},
+		},
+	},
+	{
+		Title: "Output Preview",
+		Bindings: []Binding{
+			{NextMatch, []string{"n"}, "Jump to the next search match"},
+			{PrevMatch, []string{"N"}, "Jump to the previous search match"},
+			{None, []string{"pgdown", "ctrl+f"}, "Scroll one page down"},
+			{None, []string{"pgup", "ctrl+b"}, "Scroll one page up"},
+			{None, []string{"home", "end"}, "Go to top or bottom"},
+		},
+	},
+}
+
+// Keymap maps keys to the actions they trigger
+type Keymap struct {
+	keys    map[Action][]string
+	actions map[string]Action
+}
+
+// defaultKeymap is used by a nil *Keymap
+var defaultKeymap = Default()
+
+// Default returns the keymap with the default bindings
+func Default() *Keymap {
+	km, _ := New(nil)
+	return km
+}
+
+// orDefault returns km, or the default keymap if km is nil
+func (km *Keymap) orDefault() *Keymap {
+	if km == nil {
+		return defaultKeymap
+	}
+	return km
+}
+
+// New returns the default keymap with the keys of the actions in overrides replaced,
+// as read from the config file. An empty list of keys unbinds an action.
+//
+// An error is returned for unknown actions, matching ErrUnknownAction, and for keys bound
+// to more than one action. The returned keymap is still usable: a conflicting key
+// triggers the action listed first on the help screen.
+func New(overrides map[string][]string) (*Keymap, error) {
+	km := &Keymap{
+		keys:    make(map[Action][]string),
+		actions: make(map[string]Action),
+	}
+
+	var order []Action
+	for _, section := range Sections {
+		for _, binding := range section.Bindings {
+			if binding.Action != None {
+				order = append(order, binding.Action)
+				km.keys[binding.Action] = binding.Keys
+			}
+		}
+	}
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		keys := overrides[name]
+		action := Action(name)
+		if _, ok := km.keys[action]; !ok {
+			errs = append(errs, fmt.Errorf("%w %q", ErrUnknownAction, name))
+			continue
+		}
+		normalized := make([]string, 0, len(keys))
+		for _, key := range keys {
+			normalized = append(normalized, normalizeKey(key))
+		}
+		km.keys[action] = normalized
+	}
+
+	// The quit key cannot be rebound or unbound
+	if !contains(km.keys[Quit], QuitKey) {
+		km.keys[Quit] = append(km.keys[Quit], QuitKey)
+	}
+
+	for _, action := range order {
+		for _, key := range km.keys[action] {
+			if owner, ok := km.actions[key]; ok && owner != action {
+				errs = append(errs, fmt.Errorf("key %q is bound to both %s and %s", DisplayKey(key), owner, action))
+				continue
+			}
+			km.actions[key] = action
+		}
+	}
+
+	// A key that starts a sequence waits for the next key, so it cannot also be bound on its own
+	for _, action := range order {
+		for _, key := range km.keys[action] {
+			if !isSequence(key) {
+				continue
+			}
+			first := string([]rune(key)[0])
+			if owner, ok := km.actions[first]; ok {
+				errs = append(errs, fmt.Errorf("key %q of %s starts the sequence %q of %s", first, owner, key, action))
+			}
+		}
+	}
+
+	return km, errors.Join(errs...)
+}
+
+// Action returns the action bound to key, or None if the key is not bound. Key
+// sequences are passed as the concatenation of their keys, e.g. "gg".
+func (km *Keymap) Action(key string) Action {
+	return km.orDefault().actions[key]
+}
+
+// Keys returns the keys bound to an action
+func (km *Keymap) Keys(action Action) []string {
+	return km.orDefault().keys[action]
+}
+
+// StartsSequence reports whether key is the first key of a bound key sequence
+func (km *Keymap) StartsSequence(key string) bool {
+	for bound := range km.orDefault().actions {
+		if isSequence(bound) && string([]rune(bound)[0]) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayKey returns the name of a key as shown on the help screen
+func DisplayKey(key string) string {
+	switch key {
+	case " ":
+		return "space"
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case "left":
+		return "←"
+	case "right":
+		return "→"
+	}
+	return key
+}
+
+// normalizeKey converts the name of a key in the config file to the name reported
+// for it by the terminal
+func normalizeKey(key string) string {
+	if key == "space" {
+		return " "
+	}
+	return key
+}
+
+// isSequence reports whether key is a sequence of two printable keys, such as "gg"
+func isSequence(key string) bool {
+	runes := []rune(key)
+	return len(runes) == 2 && !strings.ContainsAny(key, "+ ") && isPrintable(runes[0]) && isPrintable(runes[1]) &&
+		!isNamedKey(key)
+}
+
+// isPrintable reports whether r is typed as a key of its own
+func isPrintable(r rune) bool {
+	return r > ' ' && r != 0x7f
+}
+
+// isNamedKey reports whether a two letter key is the name of a special key, such as "up" or "f1"
+func isNamedKey(key string) bool {
+	return key == "up" || (key[0] == 'f' && key[1] >= '1' && key[1] <= '9')
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
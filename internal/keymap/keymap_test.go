@@ -0,0 +1,113 @@
+package keymap
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	km := Default()
+
+	tests := map[string]Action{
+		"j":      CursorDown,
+		"down":   CursorDown,
+		" ":      ToggleSelect,
+		"gg":     GoToTop,
+		"ctrl+c": Quit,
+		"g":      None,
+		"Z":      None,
+	}
+	for key, expected := range tests {
+		if action := km.Action(key); action != expected {
+			t.Errorf("Expected %q to trigger %q, got %q", key, expected, action)
+		}
+	}
+
+	if !km.StartsSequence("g") || km.StartsSequence("j") {
+		t.Errorf("Expected only 'g' to start a key sequence")
+	}
+
+	var nilKeymap *Keymap
+	if nilKeymap.Action("j") != CursorDown {
+		t.Errorf("Expected a nil keymap to use the default bindings")
+	}
+}
+
+func TestNew(t *testing.T) {
+	km, err := New(map[string][]string{
+		"toggle_select":   {"space", "x"},
+		"select_visible":  {"ctrl+a"},
+		"clear_selection": {},
+		"quit":            {"Q"},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(km.Keys(ToggleSelect), []string{" ", "x"}) {
+		t.Errorf("Expected \"space\" to be read as the space key, got %q", km.Keys(ToggleSelect))
+	}
+	if km.Action("ctrl+a") != SelectVisible || km.Action("A") != None {
+		t.Errorf("Expected select_visible to move from A to ctrl+a")
+	}
+	if len(km.Keys(ClearSelection)) != 0 || km.Action("X") != None {
+		t.Errorf("Expected an empty list to unbind clear_selection, got %q", km.Keys(ClearSelection))
+	}
+	if km.Action("Q") != Quit || km.Action(QuitKey) != Quit || km.Action("q") != None {
+		t.Errorf("Expected quit to be rebound to Q, keeping %s", QuitKey)
+	}
+	// Other actions keep their defaults
+	if km.Action("j") != CursorDown {
+		t.Errorf("Expected cursor_down to keep its default keys")
+	}
+}
+
+func TestNewConflicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string][]string
+		expected  []string
+	}{
+		{
+			name:      "unknown action",
+			overrides: map[string][]string{"launch_rockets": {"L"}},
+			expected:  []string{`unknown action "launch_rockets"`},
+		},
+		{
+			name:      "key bound twice",
+			overrides: map[string][]string{"copy": {"j"}},
+			expected:  []string{`key "j" is bound to both cursor_down and copy`},
+		},
+		{
+			name:      "quit key",
+			overrides: map[string][]string{"search": {"ctrl+c"}},
+			expected:  []string{`key "ctrl+c" is bound to both search and quit`},
+		},
+		{
+			name:      "sequence prefix",
+			overrides: map[string][]string{"refresh": {"g"}},
+			expected:  []string{`key "g" of refresh starts the sequence "gg" of go_to_top`},
+		},
+	}
+
+	for _, test := range tests {
+		km, err := New(test.overrides)
+		if err == nil {
+			t.Errorf("%s: expected an error", test.name)
+			continue
+		}
+		for _, expected := range test.expected {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("%s: expected the error to contain %q, got %q", test.name, expected, err)
+			}
+		}
+		if km == nil || km.Action("k") != CursorUp {
+			t.Errorf("%s: expected a usable keymap despite the error", test.name)
+		}
+		if unknown := test.name == "unknown action"; errors.Is(err, ErrUnknownAction) != unknown {
+			t.Errorf("%s: expected errors.Is(err, ErrUnknownAction) to be %v", test.name, unknown)
+		}
+	}
+}
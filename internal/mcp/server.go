@@ -0,0 +1,172 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/epilande/codegrab/internal/project"
+	"github.com/epilande/codegrab/internal/utils"
+)
+
+// Protocol versions of the Model Context Protocol the server supports, newest first
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// request is a JSON-RPC request, or a notification if it has no ID
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response, carrying either a result or an error
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// Server exposes codegrab as Model Context Protocol tools, speaking JSON-RPC over stdio
+type Server struct {
+	*project.Project
+}
+
+// NewServer returns a server for the project at root. Secrets are redacted from
+// everything the tools return unless redactSecrets is false.
+func NewServer(root string, redactSecrets bool) (*Server, error) {
+	p, err := project.New(root, redactSecrets)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{Project: p}, nil
+}
+
+// Serve reads newline-delimited JSON-RPC messages from r and writes the responses to w,
+// until r is exhausted
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	encoder := json.NewEncoder(w)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if resp := s.handleMessage(line); resp != nil {
+				if encodeErr := encoder.Encode(resp); encodeErr != nil {
+					return fmt.Errorf("failed to write response: %w", encodeErr)
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+	}
+}
+
+// handleMessage handles a single message, returning nil for notifications
+func (s *Server) handleMessage(line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: "parse error: " + err.Error()}}
+	}
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if req.ID == nil {
+			return nil
+		}
+		return &response{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{Code: codeInvalidRequest, Message: "invalid request"}}
+	}
+
+	result, err := s.handle(req.Method, req.Params)
+	if req.ID == nil {
+		// Notifications, such as notifications/initialized, are never answered
+		return nil
+	}
+
+	resp := &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		resp.Result = nil
+		resp.Error = rpcErr
+	}
+	return resp
+}
+
+// handle dispatches a request to the handler of its method
+func (s *Server) handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return s.initialize(params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return map[string]any{"tools": toolDefinitions()}, nil
+	case "tools/call":
+		return s.callTool(params)
+	case "notifications/initialized":
+		return nil, nil
+	default:
+		// Requests are handled one at a time, so a notifications/cancelled always comes
+		// after its call was answered, and is left unanswered like other notifications
+		return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", method)}
+	}
+}
+
+// initialize negotiates the protocol version and describes the server
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	var args struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, fmt.Errorf("invalid initialize params: %w", err)
+		}
+	}
+
+	version := protocolVersions[0]
+	for _, supported := range protocolVersions {
+		if args.ProtocolVersion == supported {
+			version = supported
+			break
+		}
+	}
+
+	instructions := "Tools to explore the project at " + s.Root + " and bundle its files for an LLM. Paths are relative to the project root."
+	if s.RedactSecrets {
+		instructions += " Secrets are redacted from all file contents."
+	}
+
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities":    map[string]any{"tools": map[string]any{}},
+		"serverInfo":      map[string]any{"name": "codegrab", "version": utils.Version},
+		"instructions":    instructions,
+	}, nil
+}
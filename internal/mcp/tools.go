@@ -0,0 +1,244 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/epilande/codegrab/internal/generator/formats"
+	"github.com/epilande/codegrab/internal/project"
+	"github.com/epilande/codegrab/internal/utils"
+)
+
+// tool is a tool exposed by the server
+type tool struct {
+	InputSchema map[string]any `json:"inputSchema"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+}
+
+// content is a text block of a tool result
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// toolResult is the result of a tool call. Failures of the tool itself are reported
+// in the result, so that the model can see them.
+type toolResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError"`
+}
+
+// filterProperties are the JSON schema properties of project.Filters
+func filterProperties(pathDescription string) map[string]any {
+	return map[string]any{
+		"path":            map[string]any{"type": "string", "description": pathDescription},
+		"glob":            map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": `Glob patterns to include files, or to exclude them when prefixed with "!" (e.g. "*.go", "!*_test.go")`},
+		"max_file_size":   map[string]any{"type": "string", "description": `Skip files larger than this size (e.g. "50kb", "2MB")`},
+		"include_hidden":  map[string]any{"type": "boolean", "description": "Include hidden files and directories"},
+		"include_ignored": map[string]any{"type": "boolean", "description": "Include files ignored by .gitignore"},
+	}
+}
+
+// toolDefinitions returns the tools exposed by the server
+func toolDefinitions() []tool {
+	bundleProperties := filterProperties("Directory to bundle, relative to the project root (default: the whole project)")
+	bundleProperties["paths"] = map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": `Files or directories to bundle instead of path. A file can be narrowed to lines ("main.go:120-180") or to a function, type or class ("main.go#Server.Start").`}
+	bundleProperties["format"] = map[string]any{"type": "string", "enum": formats.GetFormatNames(), "description": `Output format (default: "markdown")`}
+	bundleProperties["token_budget"] = map[string]any{"type": "integer", "description": "Maximum estimated tokens of the bundle. Files that do not fit are left out, largest first."}
+	bundleProperties["deps"] = map[string]any{"type": "boolean", "description": "Also include the dependencies of the bundled files (Go, JS/TS, Python)"}
+	bundleProperties["max_depth"] = map[string]any{"type": "integer", "description": "Maximum depth of dependency resolution, -1 for unlimited (default: 1)"}
+
+	return []tool{
+		{
+			Name:        "list_files",
+			Description: "List the files of the project as a tree with their sizes and estimated tokens. Hidden and .gitignore'd files are skipped unless requested.",
+			InputSchema: objectSchema(filterProperties("Directory to list, relative to the project root (default: the whole project)")),
+		},
+		{
+			Name:        "read_file",
+			Description: "Read a text file of the project.",
+			InputSchema: objectSchema(map[string]any{
+				"path": map[string]any{"type": "string", "description": "File to read, relative to the project root"},
+			}, "path"),
+		},
+		{
+			Name:        "resolve_dependencies",
+			Description: "List the project-local files a file depends on, following imports (Go, JS/TS, Python).",
+			InputSchema: objectSchema(map[string]any{
+				"path":      map[string]any{"type": "string", "description": "File to resolve, relative to the project root"},
+				"max_depth": map[string]any{"type": "integer", "description": "Maximum depth of dependency resolution, -1 for unlimited (default: 1)"},
+			}, "path"),
+		},
+		{
+			Name:        "scan_secrets",
+			Description: "Scan files for secrets such as API keys and tokens, using gitleaks rules.",
+			InputSchema: objectSchema(filterProperties("File or directory to scan, relative to the project root (default: the whole project)")),
+		},
+		{
+			Name:        "generate_bundle",
+			Description: "Bundle files of the project into a single document with a directory tree and the file contents, optionally within a token budget.",
+			InputSchema: objectSchema(bundleProperties),
+		},
+	}
+}
+
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// callTool runs the tool named in params
+func (s *Server) callTool(params json.RawMessage) (any, error) {
+	var call struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, fmt.Errorf("invalid tools/call params: %w", err)
+	}
+	if len(call.Arguments) == 0 || string(call.Arguments) == "null" {
+		call.Arguments = json.RawMessage("{}")
+	}
+
+	ctx := context.Background()
+	var text []string
+	var err error
+	switch call.Name {
+	case "list_files":
+		var args project.Filters
+		if err = json.Unmarshal(call.Arguments, &args); err == nil {
+			text, err = s.listFiles(ctx, args)
+		}
+	case "read_file":
+		var args struct {
+			Path string `json:"path"`
+		}
+		if err = json.Unmarshal(call.Arguments, &args); err == nil {
+			text, err = s.readFile(args.Path)
+		}
+	case "resolve_dependencies":
+		args := struct {
+			Path     string `json:"path"`
+			MaxDepth int    `json:"max_depth"`
+		}{MaxDepth: 1}
+		if err = json.Unmarshal(call.Arguments, &args); err == nil {
+			text, err = s.resolveDependencies(ctx, args.Path, args.MaxDepth)
+		}
+	case "scan_secrets":
+		var args project.Filters
+		if err = json.Unmarshal(call.Arguments, &args); err == nil {
+			text, err = s.scanSecrets(ctx, args)
+		}
+	case "generate_bundle":
+		args := project.DefaultBundleOptions()
+		if err = json.Unmarshal(call.Arguments, &args); err == nil {
+			text, err = s.generateBundle(ctx, args)
+		}
+	default:
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", call.Name)}
+	}
+
+	if err != nil {
+		return toolResult{Content: []content{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	result := toolResult{Content: make([]content, 0, len(text))}
+	for _, t := range text {
+		result.Content = append(result.Content, content{Type: "text", Text: t})
+	}
+	return result, nil
+}
+
+// listFiles renders the files below a directory as an indented tree
+func (s *Server) listFiles(ctx context.Context, args project.Filters) ([]string, error) {
+	files, err := s.Walk(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	var fileCount int
+	var totalTokens int64
+	for _, f := range files {
+		indent := strings.Repeat("  ", f.Level)
+		if f.IsDir {
+			fmt.Fprintf(&b, "%s%s/\n", indent, filepath.Base(f.Path))
+			continue
+		}
+		tokens := utils.EstimateTokensForSize(f.Size)
+		fileCount++
+		totalTokens += tokens
+		fmt.Fprintf(&b, "%s%s (%s, ~%d tokens)\n", indent, filepath.Base(f.Path), utils.FormatSize(f.Size), tokens)
+	}
+	fmt.Fprintf(&b, "\n%d files, ~%d tokens", fileCount, totalTokens)
+	return []string{b.String()}, nil
+}
+
+// readFile returns the content of a text file, with its secrets redacted
+func (s *Server) readFile(path string) ([]string, error) {
+	_, text, err := s.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return []string{text}, nil
+}
+
+// resolveDependencies lists the dependencies of a file
+func (s *Server) resolveDependencies(ctx context.Context, path string, maxDepth int) ([]string, error) {
+	rel, deps, err := s.FileDependencies(ctx, path, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	if len(deps) == 0 {
+		return []string{fmt.Sprintf("%s has no project-local dependencies", rel)}, nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d dependencies of %s:\n", len(deps), rel)
+	for _, dep := range deps {
+		fmt.Fprintf(&b, "%s (depth %d, required by %s)\n", dep.Path, dep.Depth, dep.RequiredBy)
+	}
+	return []string{strings.TrimRight(b.String(), "\n")}, nil
+}
+
+// scanSecrets reports the secrets found in the files below a path
+func (s *Server) scanSecrets(ctx context.Context, args project.Filters) ([]string, error) {
+	found, err := s.ScanSecrets(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return []string{"No secrets found"}, nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Found %d secrets:\n", len(found))
+	for _, secret := range found {
+		location := secret.Path
+		if secret.Line > 0 {
+			location = fmt.Sprintf("%s:%d", secret.Path, secret.Line)
+		}
+		fmt.Fprintf(&b, "%s: %s: %s\n", location, secret.RuleID, secret.Match)
+	}
+	return []string{strings.TrimRight(b.String(), "\n")}, nil
+}
+
+// generateBundle renders the selected files, within the token budget if one is given
+func (s *Server) generateBundle(ctx context.Context, args project.BundleOptions) ([]string, error) {
+	bundle, err := s.Bundle(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := fmt.Sprintf("Bundled %d files in %s format (~%d tokens).", len(bundle.Files), bundle.Format, bundle.Tokens)
+	if len(bundle.Omitted) > 0 {
+		summary += fmt.Sprintf(" Left out %d files to fit the budget of %d tokens: %s", len(bundle.Omitted), args.TokenBudget, strings.Join(bundle.Omitted, ", "))
+	}
+	return []string{summary, bundle.Output}, nil
+}
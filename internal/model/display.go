@@ -3,16 +3,22 @@ package model
 import (
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 
 	"github.com/epilande/codegrab/internal/filesystem"
+	"github.com/epilande/codegrab/internal/git"
 	"github.com/epilande/go-devicons"
 )
 
 // buildDisplayNodes constructs a hierarchical view of files and directories for display.
 func (m *Model) buildDisplayNodes() {
 	m.displayNodes = nil
+	if m.largestFiles {
+		m.buildLargestFilesNodes()
+		return
+	}
+
+	metrics := m.sortMetrics(m.sortMode)
 	nodesToAdd := make(map[string]filesystem.FileItem)
 	for _, f := range m.files {
 		nodesToAdd[f.Path] = f
@@ -26,29 +32,12 @@ func (m *Model) buildDisplayNodes() {
 		if processed[item.Path] {
 			return
 		}
-		processed[item.Path] = true
-
-		icon := ""
-		iconColor := ""
-		if m.showIcons {
-			fullPath := filepath.Join(m.rootPath, item.Path)
-			style := devicons.IconForPath(fullPath)
-			icon = style.Icon
-			iconColor = style.Color
+		if m.changedOnly && m.gitStatus[item.Path] == git.StatusUnmodified {
+			return
 		}
+		processed[item.Path] = true
 
-		node := FileNode{
-			Path:         item.Path,
-			Name:         filepath.Base(item.Path),
-			IsDir:        item.IsDir,
-			Level:        level,
-			Selected:     m.selected[item.Path],
-			IsDeselected: m.deselected[item.Path],
-			IsDependency: m.isDependency[item.Path],
-			Icon:         icon,
-			IconColor:    iconColor,
-		}
-		m.displayNodes = append(m.displayNodes, node)
+		m.displayNodes = append(m.displayNodes, m.newFileNode(item, level))
 
 		if item.IsDir && !m.collapsed[item.Path] {
 			prefix := item.Path + string(os.PathSeparator)
@@ -68,12 +57,7 @@ func (m *Model) buildDisplayNodes() {
 				children = append(children, childItem)
 			}
 
-			sort.Slice(children, func(i, j int) bool {
-				if children[i].IsDir != children[j].IsDir {
-					return children[i].IsDir
-				}
-				return children[i].Path < children[j].Path
-			})
+			sortItems(children, m.sortMode, metrics)
 
 			for _, child := range children {
 				addNode(child, level+1)
@@ -87,12 +71,7 @@ func (m *Model) buildDisplayNodes() {
 		}
 	}
 
-	sort.Slice(rootItems, func(i, j int) bool {
-		if rootItems[i].IsDir != rootItems[j].IsDir {
-			return rootItems[i].IsDir
-		}
-		return rootItems[i].Path < rootItems[j].Path
-	})
+	sortItems(rootItems, m.sortMode, metrics)
 
 	for _, rootItem := range rootItems {
 		addNode(rootItem, 0)
@@ -110,3 +89,30 @@ func (m *Model) buildDisplayNodes() {
 		}
 	}
 }
+
+// newFileNode returns the display node of a file or directory at the given tree level
+func (m *Model) newFileNode(item filesystem.FileItem, level int) FileNode {
+	icon := ""
+	iconColor := ""
+	if m.showIcons {
+		fullPath := filepath.Join(m.rootPath, item.Path)
+		style := devicons.IconForPath(fullPath)
+		icon = style.Icon
+		iconColor = style.Color
+	}
+
+	return FileNode{
+		Path:         item.Path,
+		Name:         filepath.Base(item.Path),
+		IsDir:        item.IsDir,
+		Level:        level,
+		Size:         item.Size,
+		ModTime:      item.ModTime,
+		Selected:     m.selected[item.Path],
+		IsDeselected: m.deselected[item.Path],
+		IsDependency: m.isDependency[item.Path],
+		GitStatus:    m.gitStatus[item.Path],
+		Icon:         icon,
+		IconColor:    iconColor,
+	}
+}
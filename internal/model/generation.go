@@ -0,0 +1,115 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/epilande/codegrab/internal/generator"
+	"github.com/epilande/codegrab/internal/keymap"
+	"github.com/epilande/codegrab/internal/ui"
+	"github.com/epilande/codegrab/internal/utils"
+)
+
+// generationProgressBarWidth is the width of the progress bar shown in the footer
+const generationProgressBarWidth = 20
+
+// generation holds the state of an output generation running in the background
+type generation struct {
+	cancel   context.CancelFunc
+	progress generator.Progress
+	seq      int
+	active   bool
+}
+
+// generationProgressMsg carries the progress of a running generation
+type generationProgressMsg struct {
+	updates  <-chan generator.Progress
+	progress generator.Progress
+	seq      int
+}
+
+// startGeneration runs generate in the background with a copy of the generator and the
+// selection, reporting its progress. generate is given the sequence number that its
+// result message must carry. A generation in progress is cancelled.
+func (m *Model) startGeneration(generate func(ctx context.Context, gen *generator.Generator, seq int) tea.Msg) tea.Cmd {
+	if m.generation.cancel != nil {
+		m.generation.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.generation = generation{cancel: cancel, seq: m.generation.seq + 1, active: true}
+
+	gen := *m.generator
+	gen.SelectedFiles = copySelection(m.selected)
+	gen.DeselectedFiles = copySelection(m.deselected)
+	gen.LineRanges = copyLineRanges(m.lineRanges)
+
+	// Only the latest progress is kept, the footer does not need every update
+	updates := make(chan generator.Progress, 1)
+	gen.OnProgress = func(progress generator.Progress) {
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- progress
+	}
+
+	seq := m.generation.seq
+	return tea.Batch(
+		func() tea.Msg {
+			defer close(updates)
+			return generate(ctx, &gen, seq)
+		},
+		waitForGenerationProgress(updates, seq),
+	)
+}
+
+// waitForGenerationProgress waits for the next progress of a generation, until it ends
+func waitForGenerationProgress(updates <-chan generator.Progress, seq int) tea.Cmd {
+	return func() tea.Msg {
+		progress, ok := <-updates
+		if !ok {
+			return nil
+		}
+		return generationProgressMsg{updates: updates, progress: progress, seq: seq}
+	}
+}
+
+// handleGenerationProgress shows the progress of the running generation and waits for
+// the next one
+func (m *Model) handleGenerationProgress(msg generationProgressMsg) tea.Cmd {
+	if !m.generation.active || msg.seq != m.generation.seq {
+		return nil
+	}
+	m.generation.progress = msg.progress
+	return waitForGenerationProgress(msg.updates, msg.seq)
+}
+
+// finishGeneration ends the generation with the given sequence number, reporting
+// whether it is still the current one. Results of cancelled generations are ignored.
+func (m *Model) finishGeneration(seq int) bool {
+	if !m.generation.active || seq != m.generation.seq {
+		return false
+	}
+	m.generation = generation{seq: m.generation.seq}
+	return true
+}
+
+// cancelGeneration stops the running generation
+func (m *Model) cancelGeneration() {
+	if m.generation.cancel != nil {
+		m.generation.cancel()
+	}
+	m.generation = generation{seq: m.generation.seq}
+}
+
+// renderGenerationProgress renders the progress of the running generation for the footer
+func (m Model) renderGenerationProgress() string {
+	progress := m.generation.progress
+	status := fmt.Sprintf(" %d/%d files | %s | %d secrets | Cancel: %s",
+		progress.FilesScanned, progress.TotalFiles, utils.FormatSize(progress.BytesRead), progress.SecretsFound, m.keyHint(keymap.Quit))
+	return ui.GetStyleInfo().Render("⏳ Generating ") +
+		ui.RenderProgressBar(progress.FilesScanned, progress.TotalFiles, generationProgressBarWidth) +
+		ui.GetStyleHelp().Render(status)
+}
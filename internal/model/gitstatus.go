@@ -0,0 +1,72 @@
+package model
+
+import (
+	"path/filepath"
+
+	"github.com/epilande/codegrab/internal/filesystem"
+	"github.com/epilande/codegrab/internal/git"
+)
+
+// loadGitStatus returns the git status of the listed files, keyed by path. Directories
+// take the most significant status of the changed files they contain. Returns nil if
+// root is not inside a git repository.
+func loadGitStatus(root string, files []filesystem.FileItem) map[string]git.FileStatus {
+	if !git.IsRepository(root) {
+		return nil
+	}
+
+	statuses, err := git.Status(root)
+	if err != nil {
+		return nil
+	}
+
+	result := make(map[string]git.FileStatus)
+	for _, f := range files {
+		if f.IsDir {
+			continue
+		}
+		status, ok := statuses[filepath.ToSlash(f.Path)]
+		if !ok {
+			continue
+		}
+		result[f.Path] = status
+
+		for dir := filepath.Dir(f.Path); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+			if result[dir] < status {
+				result[dir] = status
+			}
+		}
+	}
+	return result
+}
+
+// toggleChangedOnly switches between showing all files and only files with git changes.
+// Directories containing changes are expanded when the filter is turned on.
+func (m *Model) toggleChangedOnly() {
+	m.changedOnly = !m.changedOnly
+	if m.changedOnly {
+		for path := range m.gitStatus {
+			m.collapsed[path] = false
+		}
+	}
+	m.buildDisplayNodes()
+	if m.cursor >= len(m.displayNodes) {
+		m.cursor = len(m.displayNodes) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// selectChangedFiles selects every file with git changes and returns how many were newly selected
+func (m *Model) selectChangedFiles() int {
+	count := 0
+	for _, f := range m.files {
+		if f.IsDir || m.gitStatus[f.Path] == git.StatusUnmodified || m.selected[f.Path] {
+			continue
+		}
+		m.toggleSelection(f.Path, false)
+		count++
+	}
+	return count
+}
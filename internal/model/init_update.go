@@ -1,7 +1,9 @@
 package model
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/epilande/codegrab/internal/utils"
@@ -10,25 +12,39 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/epilande/codegrab/internal/filesystem"
+	"github.com/epilande/codegrab/internal/generator"
 	"github.com/epilande/codegrab/internal/generator/formats"
+	"github.com/epilande/codegrab/internal/git"
+	"github.com/epilande/codegrab/internal/keymap"
+	"github.com/epilande/codegrab/internal/symbols"
 	"github.com/epilande/codegrab/internal/ui"
 )
 
-// doubleKeyTimeoutMs is the maximum time in milliseconds between two 'g' keypresses to be considered a 'gg' command
+// doubleKeyTimeoutMs is the maximum time in milliseconds between the two keys of a key sequence such as 'gg'
 const doubleKeyTimeoutMs = 500
 
-// defaultFileTreePreviewRatio is the ratio of the screen width allocated to the file tree panel
+// defaultFileTreePreviewRatio is the ratio of the screen width allocated to the file tree panel,
+// until the separator is dragged
 const defaultFileTreePreviewRatio = 0.5
 
 type filesLoadedMsg struct {
-	err   error
-	files []filesystem.FileItem
+	err       error
+	files     []filesystem.FileItem
+	gitStatus map[string]git.FileStatus
+}
+
+// walkProgressMsg carries the number of files found so far by a reload
+type walkProgressMsg struct {
+	updates <-chan int
+	done    <-chan struct{}
+	found   int
 }
 
 type outputGeneratedMsg struct {
 	err         error
 	path        string
 	format      string
+	seq         int
 	tokenCount  int
 	secretCount int
 }
@@ -36,6 +52,7 @@ type outputGeneratedMsg struct {
 type clipboardCopiedMsg struct {
 	err         error
 	format      string
+	seq         int
 	tokenCount  int
 	secretCount int
 }
@@ -47,19 +64,27 @@ func (m Model) Init() tea.Cmd {
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// The startup warning, such as a conflict in the key bindings of the config file,
+	// stays in the footer until the first key press
+	if _, ok := msg.(tea.KeyMsg); ok {
+		m.startupWarning = ""
+	}
 	m.successMsg = ""
-	m.warningMsg = ""
+	m.warningMsg = m.startupWarning
 	m.isGrabbing = false
 
 	switch msg := msg.(type) {
 	case filesLoadedMsg:
+		m.walkFound = 0
 		m.err = msg.err
 		m.files = msg.files
+		m.gitStatus = msg.gitStatus
 		for _, f := range m.files {
 			if f.IsDir {
 				m.collapsed[f.Path] = true
 			}
 		}
+		m.applyPendingSelection()
 		m.buildDisplayNodes()
 		m.refreshViewportContent()
 
@@ -70,7 +95,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m, nil
 
+	case walkProgressMsg:
+		return m, m.handleWalkProgress(msg)
+
+	case generationProgressMsg:
+		return m, m.handleGenerationProgress(msg)
+
 	case outputGeneratedMsg:
+		if !m.finishGeneration(msg.seq) {
+			return m, nil
+		}
 		m.isGrabbing = true
 		if msg.err != nil {
 			m.err = msg.err
@@ -78,6 +112,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.warningMsg = ""
 		} else {
 			m.err = nil
+			if m.generator.UseStdout {
+				// The output has been written to stdout, so there is nothing left to do
+				if m.tokenCache != nil {
+					m.tokenCache.Close()
+				}
+				return m, tea.Quit
+			}
 			m.successMsg = fmt.Sprintf("✅ Generated %s (%d tokens)", msg.path, msg.tokenCount)
 			if msg.secretCount > 0 && !m.redactSecrets {
 				m.warningMsg = fmt.Sprintf("⚠️ %d secrets NOT redacted", msg.secretCount)
@@ -91,6 +132,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case clipboardCopiedMsg:
+		if !m.finishGeneration(msg.seq) {
+			return m, nil
+		}
 		m.isGrabbing = true
 		if msg.err != nil {
 			m.err = msg.err
@@ -110,6 +154,40 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.refreshViewportContent()
 		return m, nil
 
+	case contentSearchDebounceMsg:
+		if !m.isSearching || msg.seq != m.contentSearchSeq {
+			return m, nil
+		}
+		return m, m.runContentSearch(msg.seq)
+
+	case contentSearchResultsMsg:
+		// Ignore results for queries that have since changed
+		if !m.isSearching || m.searchMode != contentSearch || msg.seq != m.contentSearchSeq {
+			return m, nil
+		}
+		m.grepMatches = make(map[string]filesystem.GrepMatch, len(msg.matches))
+		for _, match := range msg.matches {
+			m.grepMatches[match.Path] = match
+		}
+		m.updateSearchResults()
+		if m.cursor >= len(m.searchResults) {
+			m.cursor = 0
+		}
+		m.ensureCursorVisible()
+		m.refreshViewportContent()
+		if m.showPreview && len(m.searchResults) > 0 {
+			m.updatePreview()
+		}
+		return m, nil
+
+	case outputPreviewMsg:
+		m.handleOutputPreviewResult(msg)
+		return m, nil
+
+	case statsComputedMsg:
+		m.handleStatsComputed(msg)
+		return m, nil
+
 	case refreshMsg:
 		m.successMsg = "🔄 Refreshed files and reset selection"
 		m.refreshViewportContent()
@@ -121,6 +199,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Update layout based on new window size
 		m.calculateLayout()
+		m.layoutOutputPreview()
 
 		m.refreshViewportContent()
 
@@ -131,96 +210,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m, nil
 
-	case tea.KeyMsg:
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
 
-		// Get the current key
-		currentKey := msg.String()
-		currentTime := utils.GetCurrentTimeMillis()
-
-		// Handle 'gg' key sequence for Vim-like navigation (consolidated logic)
-		if currentKey == "g" {
-			if m.lastKey == "g" && (currentTime-m.lastKeyTime) < doubleKeyTimeoutMs {
-				// This is the second 'g' within time window - execute 'gg' command
-				m.lastKey = "" // Reset after handling the sequence
-
-				// Go to top (Vim style) - action depends on current state
-				if m.showHelp {
-					// In help mode, just scroll help to top
-					m.viewport.GotoTop()
-				} else if m.previewFocused && m.showPreview {
-					// When preview is focused, scroll preview to top
-					m.previewViewport.GotoTop()
-				} else {
-					// When file tree is focused, move cursor to top and scroll
-					m.viewport.GotoTop()
-					m.cursor = 0
-					m.refreshViewportContent()
-				}
-				return m, nil
-			} else {
-				// First 'g' press - record it and wait for second 'g'
-				m.lastKey = "g"
-				m.lastKeyTime = currentTime
-				return m, nil
-			}
-		} else {
-			// For non-'g' keys, update tracking info
-			m.lastKey = currentKey
-			m.lastKeyTime = currentTime
+	case tea.KeyMsg:
+		// The output preview takes over the screen, and handles its own keys
+		if m.output.active {
+			return m.handleOutputPreviewKey(msg)
 		}
-
-		// Handle half-page scrolling keys
-		if currentKey == "ctrl+u" {
-			if m.previewFocused && m.showPreview {
-				// Scroll preview half page up when preview is focused
-				m.previewViewport.HalfViewUp()
-			} else {
-				// Move cursor half page up in file tree
-				m.halfPageUp()
-				m.refreshViewportContent()
-				// Update preview if enabled
-				if m.showPreview {
-					m.updatePreview()
-				}
-			}
-			return m, nil
-		} else if currentKey == "ctrl+d" {
-			if m.previewFocused && m.showPreview {
-				// Scroll preview half page down when preview is focused
-				m.previewViewport.HalfViewDown()
-			} else {
-				// Move cursor half page down in file tree
-				m.halfPageDown()
-				m.refreshViewportContent()
-				// Update preview if enabled
-				if m.showPreview {
-					m.updatePreview()
-				}
-			}
-			return m, nil
+		// The symbol picker takes over the preview pane
+		if m.picker.active {
+			return m.handleSymbolPickerKey(msg)
 		}
 
-		// Special handling for help mode
-		if m.showHelp {
-			// Handle help mode keys
-			switch currentKey {
-			case "q", "esc", "?":
-				m.showHelp = false
-				m.refreshViewportContent()
-				return m, nil
-			case "j", "down":
-				m.viewport.LineDown(1)
-				return m, nil
-			case "k", "up":
-				m.viewport.LineUp(1)
-				return m, nil
-			case "G":
-				// Vim style: go to bottom
-				m.viewport.GotoBottom()
-				return m, nil
-			default:
-				return m, nil
-			}
+		currentKey := msg.String()
+		if currentKey == keymap.QuitKey {
+			return m, m.quit()
 		}
 
 		if m.isSearching {
@@ -228,9 +233,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			switch msg.String() {
 			case "esc":
+				m.cancelContentSearch()
 				m.isSearching = false
+				m.searchMode = fuzzySearch
+				m.grepMatches = nil
 				m.searchInput.Blur()
 				m.searchInput.SetValue("")
+				m.searchInput.Placeholder = searchPlaceholder(fuzzySearch)
 				m.searchResults = nil
 				m.cursor = 0
 				m.viewport.GotoTop()
@@ -244,7 +253,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "tab", "enter":
 				if len(m.searchResults) > 0 && m.cursor < len(m.searchResults) {
 					node := m.searchResults[m.cursor]
+					before := m.snapshotSelection()
 					m.toggleSelection(node.Path, node.IsDir)
+					m.recordUndo(before)
 					m.buildDisplayNodes()
 					m.updateSearchResults()
 					m.ensureCursorVisible()
@@ -255,6 +266,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				}
 				return m, nil
+			case "ctrl+a":
+				before := m.snapshotSelection()
+				count := m.selectAllSearchResults()
+				m.recordUndo(before)
+				m.buildDisplayNodes()
+				m.updateSearchResults()
+				m.ensureCursorVisible()
+				m.refreshViewportContent()
+				m.successMsg = fmt.Sprintf("Selected %d matching files", count)
+				return m, nil
+			case "ctrl+f":
+				return m, m.switchSearchMode()
 			case "ctrl+n", "down":
 				if len(m.searchResults) > 0 {
 					m.cursor = (m.cursor + 1) % len(m.searchResults)
@@ -282,7 +305,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 
+			previousQuery := m.searchInput.Value()
 			m.searchInput, cmd = m.searchInput.Update(msg)
+			if m.searchInput.Value() != previousQuery {
+				cmd = tea.Batch(cmd, m.scheduleContentSearch())
+			}
 
 			m.updateSearchResults()
 			m.refreshViewportContent()
@@ -295,14 +322,76 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 
-		// Handle single keys
-		switch currentKey {
-		case "q", "ctrl+c":
-			if m.tokenCache != nil {
-				m.tokenCache.Close()
+		action := m.resolveKey(currentKey)
+
+		// Special handling for help mode and the statistics view
+		if m.showHelp || m.showStats {
+			switch action {
+			case keymap.Help, keymap.ToggleStats, keymap.Cancel, keymap.Quit:
+				m.showHelp = false
+				m.closeStats()
+			case keymap.CursorDown:
+				m.viewport.LineDown(1)
+			case keymap.CursorUp:
+				m.viewport.LineUp(1)
+			case keymap.HalfPageDown:
+				m.viewport.HalfViewDown()
+			case keymap.HalfPageUp:
+				m.viewport.HalfViewUp()
+			case keymap.GoToTop:
+				m.viewport.GotoTop()
+			case keymap.GoToBottom:
+				m.viewport.GotoBottom()
 			}
-			return m, tea.Quit
-		case "j", "down", "ctrl+n":
+			return m, nil
+		}
+
+		switch action {
+		case keymap.Quit:
+			// Quitting during a generation cancels it instead
+			if m.generation.active {
+				m.cancelGeneration()
+				m.warningMsg = "Generation cancelled"
+				return m, nil
+			}
+			return m, m.quit()
+		case keymap.GoToTop:
+			if m.previewFocused && m.showPreview {
+				// When preview is focused, scroll preview to top
+				m.previewViewport.GotoTop()
+			} else {
+				// When file tree is focused, move cursor to top and scroll
+				m.viewport.GotoTop()
+				m.cursor = 0
+				m.refreshViewportContent()
+			}
+		case keymap.HalfPageUp:
+			if m.previewFocused && m.showPreview {
+				// Scroll preview half page up when preview is focused
+				m.previewViewport.HalfViewUp()
+			} else {
+				// Move cursor half page up in file tree
+				m.halfPageUp()
+				m.refreshViewportContent()
+				// Update preview if enabled
+				if m.showPreview {
+					m.updatePreview()
+				}
+			}
+		case keymap.HalfPageDown:
+			if m.previewFocused && m.showPreview {
+				// Scroll preview half page down when preview is focused
+				m.previewViewport.HalfViewDown()
+			} else {
+				// Move cursor half page down in file tree
+				m.halfPageDown()
+				m.refreshViewportContent()
+				// Update preview if enabled
+				if m.showPreview {
+					m.updatePreview()
+				}
+			}
+		case keymap.CursorDown:
 			if m.previewFocused && m.showPreview {
 				// Scroll preview down when preview is focused
 				m.previewViewport.LineDown(1)
@@ -314,7 +403,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.updatePreview()
 				}
 			}
-		case "k", "up", "ctrl+p":
+		case keymap.CursorUp:
 			if m.previewFocused && m.showPreview {
 				// Scroll preview up when preview is focused
 				m.previewViewport.LineUp(1)
@@ -326,10 +415,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.updatePreview()
 				}
 			}
-		case "r":
+		case keymap.Refresh:
+			before := m.snapshotSelection()
 			m.selected = make(map[string]bool)
 			m.deselected = make(map[string]bool)
 			m.isDependency = make(map[string]bool)
+			m.lineRanges = make(map[string][]symbols.LineRange)
+			m.recordUndo(before)
 			m.cursor = 0
 			m.viewport.GotoTop()
 			return m, tea.Sequence(
@@ -337,7 +429,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				func() tea.Msg { return refreshMsg{} },
 			)
 
-		case "h", "left":
+		case keymap.Collapse:
 			if m.previewFocused && m.showPreview {
 				// Return focus to file tree
 				m.previewFocused = false
@@ -354,7 +446,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.refreshViewportContent()
 				}
 			}
-		case "l", "right":
+		case keymap.Expand:
 			if !m.previewFocused && m.showPreview && m.cursor < len(m.displayNodes) {
 				node := m.displayNodes[m.cursor]
 				if !node.IsDir {
@@ -374,7 +466,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.refreshViewportContent()
 				}
 			}
-		case "e":
+		case keymap.ToggleExpandAll:
 			if len(m.collapsed) > 0 {
 				m.expandAllDirectories()
 			} else {
@@ -384,54 +476,67 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.ensureCursorVisible()
 			m.refreshViewportContent()
-		case " ", "tab":
+		case keymap.ToggleSelect:
+			if m.visualMode {
+				m.finishVisualSelection()
+				return m, nil
+			}
 			if m.cursor < len(m.displayNodes) {
 				node := m.displayNodes[m.cursor]
+				before := m.snapshotSelection()
 				cmds := []tea.Cmd{m.toggleSelection(node.Path, node.IsDir)}
+				m.recordUndo(before)
 				m.buildDisplayNodes()
 				m.ensureCursorVisible()
 				m.refreshViewportContent()
 				return m, tea.Batch(cmds...)
 			}
-		case "/":
-			m.isSearching = true
-			m.searchInput.Focus()
-			m.searchInput.SetValue("")
-			m.searchResults = nil
-			m.cursor = 0
-			m.viewport.GotoTop()
-			m.expandAllDirectories()
-			m.refreshViewportContent()
+		case keymap.Search:
+			m.startSearch(fuzzySearch)
 			return m, nil
-		case "?":
+		case keymap.ContentSearch:
+			m.startSearch(contentSearch)
+			return m, nil
+		case keymap.Help:
 			m.showHelp = !m.showHelp
 			if m.showHelp {
 				m.showHelpScreen()
 			} else {
 				m.refreshViewportContent()
 			}
-		case "esc":
+		case keymap.ToggleStats:
+			return m, m.openStats()
+		case keymap.Cancel:
 			if m.showHelp {
 				m.showHelp = false
 				m.refreshViewportContent()
 			}
-		case "y":
-			return m, m.copyOutputToClipboard()
-		case "i":
+			if m.visualMode {
+				m.visualMode = false
+				m.refreshViewportContent()
+			}
+			if m.generation.active {
+				m.cancelGeneration()
+				m.warningMsg = "Generation cancelled"
+			}
+		case keymap.Copy:
+			cmd := m.copyOutputToClipboard()
+			return m, cmd
+		case keymap.ToggleGitIgnore:
 			m.useGitIgnore = !m.useGitIgnore
 			m.generator.UseGitIgnore = m.useGitIgnore
 			m.filterSelections()
 			m.cursor = 0
 			m.viewport.GotoTop()
 			return m, m.reloadFiles()
-		case ".":
+		case keymap.ToggleHidden:
 			m.showHidden = !m.showHidden
 			m.generator.ShowHidden = m.showHidden
 			m.filterSelections()
 			m.cursor = 0
 			m.viewport.GotoTop()
 			return m, m.reloadFiles()
-		case "D":
+		case keymap.ToggleDeps:
 			m.resolveDeps = !m.resolveDeps
 			if m.resolveDeps {
 				m.successMsg = "Dependency resolution enabled"
@@ -440,7 +545,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.buildDisplayNodes()
 			m.refreshViewportContent()
-		case "F":
+		case keymap.CycleFormat:
 			formatNames := formats.GetFormatNames()
 			if len(formatNames) == 0 {
 				break
@@ -456,12 +561,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
-			nextFormat := formats.GetFormat(formatNames[nextIndex])
-			m.generator.SetFormat(nextFormat)
+			m.generator.SetFormatFunc(formats.Constructor(formatNames[nextIndex]))
 
 			m.successMsg = fmt.Sprintf("Format changed: %s", m.generator.GetFormatName())
 			m.refreshViewportContent()
-		case "S":
+		case keymap.ToggleRedaction:
 			m.redactSecrets = !m.redactSecrets
 			m.generator.SetRedactionMode(m.redactSecrets)
 			if m.redactSecrets {
@@ -471,7 +575,94 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.warningMsg = ""
 			m.refreshViewportContent()
-		case "P":
+		case keymap.ToggleMetadata:
+			m.generator.IncludeMetadata = !m.generator.IncludeMetadata
+			if m.generator.IncludeMetadata {
+				m.successMsg = "Metadata header enabled"
+			} else {
+				m.successMsg = "Metadata header disabled"
+			}
+			m.refreshViewportContent()
+		case keymap.ToggleHistory:
+			// Toggle between file content and git history in the preview pane
+			m.previewHistory = !m.previewHistory
+			if !m.showPreview {
+				m.showPreview = true
+				m.calculateLayout()
+			}
+			m.currentPreviewPath = ""
+			m.updatePreview()
+			if m.previewHistory {
+				m.successMsg = "Preview showing git history"
+			} else {
+				m.successMsg = "Preview showing file content"
+			}
+			m.refreshViewportContent()
+		case keymap.ToggleRedacted:
+			// Toggle showing files in the preview pane as they appear after secret redaction
+			m.previewRedacted = !m.previewRedacted
+			if !m.showPreview {
+				m.showPreview = true
+				m.calculateLayout()
+			}
+			m.currentPreviewPath = ""
+			m.updatePreview()
+			if m.previewRedacted {
+				m.successMsg = "Preview showing redacted content"
+			} else {
+				m.successMsg = "Preview showing original content"
+			}
+			m.refreshViewportContent()
+		case keymap.ToggleChanged:
+			// Toggle showing only files with git changes
+			if m.gitStatus == nil && !m.changedOnly {
+				m.warningMsg = "No git changes to show"
+				m.refreshViewportContent()
+				break
+			}
+			m.toggleChangedOnly()
+			if m.changedOnly {
+				m.successMsg = "Showing changed files only"
+			} else {
+				m.successMsg = "Showing all files"
+			}
+			m.ensureCursorVisible()
+			m.refreshViewportContent()
+			if m.showPreview {
+				m.updatePreview()
+			}
+		case keymap.CycleSort:
+			m.sortMode = m.sortMode.next()
+			m.rebuildKeepingCursor()
+			m.successMsg = fmt.Sprintf("Sorted by %s", m.sortMode)
+			m.ensureCursorVisible()
+			m.refreshViewportContent()
+			if m.showPreview {
+				m.updatePreview()
+			}
+		case keymap.ToggleLargest:
+			// Toggle between the file tree and a flat list of all files sorted across the project
+			m.largestFiles = !m.largestFiles
+			m.visualMode = false
+			m.rebuildKeepingCursor()
+			if m.largestFiles {
+				m.successMsg = fmt.Sprintf("Showing all files by %s", m.largestFilesMode())
+			} else {
+				m.successMsg = "Showing file tree"
+			}
+			m.ensureCursorVisible()
+			m.refreshViewportContent()
+			if m.showPreview {
+				m.updatePreview()
+			}
+		case keymap.SelectChanged:
+			before := m.snapshotSelection()
+			count := m.selectChangedFiles()
+			m.recordUndo(before)
+			m.buildDisplayNodes()
+			m.refreshViewportContent()
+			m.successMsg = fmt.Sprintf("Selected %d changed files", count)
+		case keymap.TogglePreview:
 			// Toggle preview pane
 			m.showPreview = !m.showPreview
 
@@ -488,15 +679,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			m.refreshViewportContent()
 		// Preview navigation keys
-		case "J":
+		case keymap.ScrollPreviewDown:
 			if m.showPreview {
 				m.previewViewport.LineDown(1)
 			}
-		case "K":
+		case keymap.ScrollPreviewUp:
 			if m.showPreview {
 				m.previewViewport.LineUp(1)
 			}
-		case "G":
+		case keymap.GoToBottom:
 			// Go to bottom (Vim style)
 			if m.previewFocused && m.showPreview {
 				// When preview is focused, just scroll to bottom
@@ -515,27 +706,198 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
-		case "ctrl+g":
+		case keymap.VisualSelect:
+			if m.visualMode {
+				m.finishVisualSelection()
+			} else if len(m.displayNodes) > 0 {
+				m.startVisualSelection()
+				m.refreshViewportContent()
+			}
+
+		case keymap.SelectVisible:
+			before := m.snapshotSelection()
+			count := m.selectAllVisible()
+			m.recordUndo(before)
+			m.buildDisplayNodes()
+			m.refreshViewportContent()
+			m.successMsg = fmt.Sprintf("Selected %d visible files", count)
+
+		case keymap.InvertDirectory:
+			before := m.snapshotSelection()
+			dir, count := m.invertSelectionInDirectory()
+			m.recordUndo(before)
+			m.buildDisplayNodes()
+			m.refreshViewportContent()
+			if dir == "." {
+				dir = filepath.Base(m.rootPath)
+			}
+			m.successMsg = fmt.Sprintf("Inverted selection of %d files in %s", count, dir)
+
+		case keymap.SelectExtension:
+			before := m.snapshotSelection()
+			ext, count := m.selectByExtension()
+			m.recordUndo(before)
+			if ext == "" {
+				m.warningMsg = "No file extension under cursor"
+				break
+			}
+			m.buildDisplayNodes()
+			m.refreshViewportContent()
+			m.successMsg = fmt.Sprintf("Selected %d %s files", count, ext)
+
+		case keymap.ClearSelection:
+			before := m.snapshotSelection()
+			count := m.clearSelection()
+			m.recordUndo(before)
+			m.buildDisplayNodes()
+			m.refreshViewportContent()
+			m.successMsg = fmt.Sprintf("Cleared selection of %d files", count)
+
+		case keymap.Undo:
+			if m.undoSelection() {
+				m.successMsg = fmt.Sprintf("Undid selection change (%d more)", len(m.undoStack))
+			} else {
+				m.warningMsg = "Nothing to undo"
+			}
+			m.buildDisplayNodes()
+			m.refreshViewportContent()
+
+		case keymap.Redo:
+			if m.redoSelection() {
+				m.successMsg = fmt.Sprintf("Redid selection change (%d more)", len(m.redoStack))
+			} else {
+				m.warningMsg = "Nothing to redo"
+			}
+			m.buildDisplayNodes()
+			m.refreshViewportContent()
+
+		case keymap.PreviewOutput:
+			// Preview the generated output
+			return m, m.openOutputPreview()
+
+		case keymap.PickSymbols:
+			// Narrow the previewed file to some of its symbols or lines
+			cmd := m.openSymbolPicker()
+			return m, cmd
+
+		case keymap.Generate:
 			// Generate output
-			return m, m.generateOutput()
+			cmd := m.generateOutput()
+			return m, cmd
 		}
 	}
 	return m, nil
 }
 
+// finishVisualSelection applies the visual range to the selection and reports the result
+func (m *Model) finishVisualSelection() {
+	before := m.snapshotSelection()
+	count, selected := m.applyVisualSelection()
+	m.recordUndo(before)
+	m.buildDisplayNodes()
+	m.refreshViewportContent()
+	if selected {
+		m.successMsg = fmt.Sprintf("Selected %d items", count)
+	} else {
+		m.successMsg = fmt.Sprintf("Deselected %d items", count)
+	}
+}
+
+// resolveKey returns the action bound to key. The first key of a key sequence, such as
+// the 'g' of "gg", is remembered and resolves to no action, so that the next key can
+// complete the sequence.
+func (m *Model) resolveKey(key string) keymap.Action {
+	now := utils.GetCurrentTimeMillis()
+	previousKey, previousTime := m.lastKey, m.lastKeyTime
+	m.lastKey = key
+	m.lastKeyTime = now
+
+	if previousKey != "" && now-previousTime < doubleKeyTimeoutMs {
+		if action := m.keymap.Action(previousKey + key); action != keymap.None {
+			// Reset after handling the sequence
+			m.lastKey = ""
+			return action
+		}
+	}
+	if m.keymap.StartsSequence(key) {
+		return keymap.None
+	}
+	return m.keymap.Action(key)
+}
+
+// quit cancels a running generation, closes the token cache and exits the program
+func (m *Model) quit() tea.Cmd {
+	m.cancel()
+	m.cancelGeneration()
+	m.cancelContentSearch()
+	if m.tokenCache != nil {
+		m.tokenCache.Close()
+	}
+	return tea.Quit
+}
+
 func (m *Model) reloadFiles() tea.Cmd {
-	return func() tea.Msg {
-		files, err := filesystem.WalkDirectory(m.rootPath, m.gitIgnoreMgr, m.filterMgr, m.useGitIgnore, m.showHidden, m.maxFileSize)
-		if err != nil {
-			return filesLoadedMsg{files: nil, err: fmt.Errorf("failed to reload files: %w", err)}
+	// New files may change the dependencies of unchanged ones
+	m.depResolver.Reset()
+
+	// Only the latest number of files found is kept, the footer does not need every one
+	updates := make(chan int, 1)
+	done := make(chan struct{})
+	onProgress := func(found int) {
+		select {
+		case <-updates:
+		default:
 		}
+		updates <- found
+	}
+
+	return tea.Batch(
+		func() tea.Msg {
+			files, err := filesystem.WalkDirectoryWithProgress(m.ctx, m.rootPath, m.gitIgnoreMgr, m.filterMgr, m.useGitIgnore, m.showHidden, m.maxFileSize, onProgress)
+			close(done)
+			if err != nil {
+				return filesLoadedMsg{files: nil, err: fmt.Errorf("failed to reload files: %w", err)}
+			}
 
-		m.isDependency = make(map[string]bool)
+			m.isDependency = make(map[string]bool)
+
+			return filesLoadedMsg{files: files, gitStatus: loadGitStatus(m.rootPath, files), err: nil}
+		},
+		waitForWalkProgress(updates, done),
+	)
+}
 
-		return filesLoadedMsg{files: files, err: nil}
+// waitForWalkProgress waits for the next number of files found by a reload, until the
+// walk ends
+func waitForWalkProgress(updates <-chan int, done <-chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case found := <-updates:
+			return walkProgressMsg{updates: updates, done: done, found: found}
+		case <-done:
+			return nil
+		}
 	}
 }
 
+// handleWalkProgress shows the number of files found by the running reload and waits
+// for the next one. Progress that arrives after the walk ended is ignored.
+func (m *Model) handleWalkProgress(msg walkProgressMsg) tea.Cmd {
+	select {
+	case <-msg.done:
+		return nil
+	default:
+	}
+	m.walkFound = msg.found
+	return waitForWalkProgress(msg.updates, msg.done)
+}
+
+// renderWalkProgress renders the number of files found by the running reload for the footer
+func (m Model) renderWalkProgress() string {
+	return ui.GetStyleInfo().Render("⏳ Loading files ") +
+		ui.GetStyleHelp().Render(fmt.Sprintf("%d found | Quit: %s", m.walkFound, m.keyHint(keymap.Quit)))
+}
+
 func (m *Model) toggleCollapse(path string) {
 	m.collapsed[path] = !m.collapsed[path]
 }
@@ -632,7 +994,7 @@ func (m *Model) calculateLayout() {
 		}
 
 		// Allocate space based on the defined ratio
-		fileTreeInnerWidth := int(float64(adjustedWidth) * defaultFileTreePreviewRatio)
+		fileTreeInnerWidth := int(float64(adjustedWidth) * m.treeRatio())
 		previewInnerWidth := adjustedWidth - fileTreeInnerWidth
 
 		if fileTreeInnerWidth < 0 {
@@ -660,13 +1022,7 @@ func (m *Model) calculateLayout() {
 		m.previewViewport.Width = previewInnerWidth
 
 		// Prepare preview header text
-		previewHeaderText := "No file selected"
-		if m.currentPreviewPath != "" {
-			previewHeaderText = "📄 " + m.currentPreviewPath
-			if m.currentPreviewIsDir {
-				previewHeaderText = "📁 " + m.currentPreviewPath
-			}
-		}
+		previewHeaderText := m.previewHeaderText()
 
 		// Calculate actual preview header height
 		previewOuterWidth := previewInnerWidth + (2 * ui.BorderSize)
@@ -704,18 +1060,17 @@ func (m *Model) calculateLayout() {
 	}
 }
 
-// generateOutput creates the output in the current format
-func (m Model) generateOutput() tea.Cmd {
-	return func() tea.Msg {
-		m.generator.SelectedFiles = m.selected
-		m.generator.DeselectedFiles = m.deselected
-		outPath, tokenCount, secretCount, err := m.generator.Generate()
+// generateOutput creates the output in the current format, in the background
+func (m *Model) generateOutput() tea.Cmd {
+	return m.startGeneration(func(ctx context.Context, gen *generator.Generator, seq int) tea.Msg {
+		outPath, tokenCount, secretCount, err := gen.Generate(ctx)
 		if err != nil {
 			return outputGeneratedMsg{
 				err:         fmt.Errorf("failed to generate output: %w", err),
 				path:        "",
 				tokenCount:  0,
-				format:      m.generator.GetFormatName(),
+				format:      gen.GetFormatName(),
+				seq:         seq,
 				secretCount: secretCount,
 			}
 		}
@@ -723,24 +1078,24 @@ func (m Model) generateOutput() tea.Cmd {
 			err:         nil,
 			path:        outPath,
 			tokenCount:  tokenCount,
-			format:      m.generator.GetFormatName(),
+			format:      gen.GetFormatName(),
+			seq:         seq,
 			secretCount: secretCount,
 		}
-	}
+	})
 }
 
-// copyOutputToClipboard copies the generated content to the clipboard
-func (m Model) copyOutputToClipboard() tea.Cmd {
-	return func() tea.Msg {
-		m.generator.SelectedFiles = m.selected
-		m.generator.DeselectedFiles = m.deselected
-
-		content, tokenCount, secretCount, err := m.generator.GenerateString()
+// copyOutputToClipboard copies the generated content to the clipboard, generating it
+// in the background
+func (m *Model) copyOutputToClipboard() tea.Cmd {
+	return m.startGeneration(func(ctx context.Context, gen *generator.Generator, seq int) tea.Msg {
+		content, tokenCount, secretCount, err := gen.GenerateString(ctx)
 		if err != nil {
 			return clipboardCopiedMsg{
 				err:         err,
 				tokenCount:  0,
-				format:      m.generator.GetFormatName(),
+				format:      gen.GetFormatName(),
+				seq:         seq,
 				secretCount: secretCount,
 			}
 		}
@@ -749,7 +1104,8 @@ func (m Model) copyOutputToClipboard() tea.Cmd {
 			return clipboardCopiedMsg{
 				err:         fmt.Errorf("failed to copy to clipboard: %w", err),
 				tokenCount:  tokenCount,
-				format:      m.generator.GetFormatName(),
+				format:      gen.GetFormatName(),
+				seq:         seq,
 				secretCount: secretCount,
 			}
 		}
@@ -757,8 +1113,9 @@ func (m Model) copyOutputToClipboard() tea.Cmd {
 		return clipboardCopiedMsg{
 			err:         nil,
 			tokenCount:  tokenCount,
-			format:      m.generator.GetFormatName(),
+			format:      gen.GetFormatName(),
+			seq:         seq,
 			secretCount: secretCount,
 		}
-	}
+	})
 }
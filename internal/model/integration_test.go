@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -58,7 +59,7 @@ func TestTokenCacheIntegration(t *testing.T) {
 		t.Fatal("Token cache should be initialized")
 	}
 
-	files, err := filesystem.WalkDirectory(tmpDir, model.gitIgnoreMgr, model.filterMgr, true, false, model.maxFileSize)
+	files, err := filesystem.WalkDirectory(context.Background(), tmpDir, model.gitIgnoreMgr, model.filterMgr, true, false, model.maxFileSize)
 	if err != nil {
 		t.Fatalf("Failed to walk directory: %v", err)
 	}
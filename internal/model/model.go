@@ -1,10 +1,12 @@
 package model
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -14,49 +16,78 @@ import (
 	"github.com/epilande/codegrab/internal/filesystem"
 	"github.com/epilande/codegrab/internal/generator"
 	"github.com/epilande/codegrab/internal/generator/formats"
+	"github.com/epilande/codegrab/internal/git"
+	"github.com/epilande/codegrab/internal/keymap"
+	"github.com/epilande/codegrab/internal/symbols"
 	"github.com/epilande/codegrab/internal/ui"
+	"github.com/epilande/codegrab/internal/ui/highlight"
 	"github.com/epilande/codegrab/internal/utils"
 )
 
+// defaultPreviewHistoryDepth is the number of commits shown in the history preview when --history is not set
+const defaultPreviewHistoryDepth = 10
+
 // FileNode represents a file/folder in the display list.
 type FileNode struct {
+	ModTime      time.Time
 	Path         string
 	Name         string
 	Icon         string
 	IconColor    string
 	Level        int
+	Size         int64
 	IsDir        bool
 	IsLast       bool
 	Selected     bool
 	IsDeselected bool
 	IsDependency bool
+	GitStatus    git.FileStatus
 }
 
 type Model struct {
 	err                   error
 	selected              map[string]bool
 	deselected            map[string]bool
+	lineRanges            map[string][]symbols.LineRange
 	collapsed             map[string]bool
 	isDependency          map[string]bool
 	gitIgnoreMgr          *filesystem.GitIgnoreManager
 	filterMgr             *filesystem.FilterManager
 	generator             *generator.Generator
+	depResolver           *dependencies.ConcurrentResolver
+	ctx                   context.Context
+	cancel                context.CancelFunc
+	contentSearchCancel   context.CancelFunc
 	rootPath              string
 	projectModuleName     string
 	successMsg            string
 	warningMsg            string
+	startupWarning        string // Shown as the warning until the first key press
 	viewport              viewport.Model
 	previewViewport       viewport.Model
 	files                 []filesystem.FileItem
 	displayNodes          []FileNode
 	searchResults         []FileNode
+	pendingSelection      []string
+	pendingLineRanges     map[string][]symbols.LineRange
+	undoStack             []selectionSnapshot
+	redoStack             []selectionSnapshot
 	searchInput           textinput.Model
+	grepMatches           map[string]filesystem.GrepMatch
+	gitStatus             map[string]git.FileStatus
+	searchMode            searchMode
+	sortMode              SortMode
+	contentSearchSeq      int
+	walkFound             int
 	cursor                int
+	visualAnchor          int
+	fileTreeRatio         float64
 	width                 int
 	height                int
 	maxDepth              int
 	maxFileSize           int64
 	showHelp              bool
+	showStats             bool
 	useGitIgnore          bool
 	showHidden            bool
 	showIcons             bool
@@ -67,22 +98,43 @@ type Model struct {
 	showTokenCount        bool
 	showPreview           bool
 	previewFocused        bool
+	previewHistory        bool
+	previewRedacted       bool
+	changedOnly           bool
+	visualMode            bool
+	largestFiles          bool
+	draggingSplit         bool
 	currentPreviewPath    string
 	currentPreviewContent string
 	currentPreviewIsDir   bool
+	previewDoc            *highlight.Document
+	output                outputPreview
+	picker                symbolPicker
+	generation            generation
 	lastKeyTime           int64  // Last key press time
 	lastKey               string // Last key pressed
 	tokenCache            *TokenCache
+	keymap                *keymap.Keymap
 }
 
 type Config struct {
 	FilterMgr      *filesystem.FilterManager
+	Keymap         *keymap.Keymap
+	InitialFiles   []string
+	LineRanges     map[string][]symbols.LineRange
 	RootPath       string
+	StartupWarning string
 	OutputPath     string
 	Format         string
 	MaxDepth       int
+	HistoryDepth   int
 	MaxFileSize    int64
+	SortMode       SortMode
 	UseTempFile    bool
+	UseStdout      bool
+	WithMetadata   bool
+	LineNumbers    bool
+	HistoryDiff    bool
 	SkipRedaction  bool
 	ResolveDeps    bool
 	ShowIcons      bool
@@ -109,9 +161,14 @@ func (m *Model) updatePreview() {
 		m.currentPreviewPath = node.Path
 		m.currentPreviewIsDir = node.IsDir
 
+		m.previewDoc = nil
+
 		if node.IsDir {
 			// For directories, just show the directory name
 			m.currentPreviewContent = fmt.Sprintf("Directory: %s", node.Path)
+		} else if m.previewHistory {
+			m.currentPreviewContent = m.renderFileHistory(node.Path)
+			m.previewDoc = highlight.NewDocument(m.currentPreviewContent, "diff")
 		} else {
 			// For files, read the content
 			fullPath := filepath.Join(m.rootPath, node.Path)
@@ -134,46 +191,25 @@ func (m *Model) updatePreview() {
 				return
 			}
 
-			// Set the content
-			// Calculate the available width for text in the preview pane
-			// Account for border (1 char on each side) and padding (1 char on each side)
-			availableWidth := m.previewViewport.Width - 4
-
-			// Process the content to handle line wrapping consistently
-			rawContent := content
-
-			// If the preview viewport width is set, ensure content fits properly
-			if m.previewViewport.Width > 0 && availableWidth > 0 {
-				// Split content into lines
-				lines := strings.Split(rawContent, "\n")
-				processedLines := make([]string, 0, len(lines))
-
-				// Process each line to ensure consistent wrapping
-				for _, line := range lines {
-					// For very long lines, add some padding to ensure consistent wrapping
-					if len(line) > availableWidth {
-						// Add the line with proper wrapping
-						processedLines = append(processedLines, line)
-					} else {
-						// Keep shorter lines as is
-						processedLines = append(processedLines, line)
-					}
-				}
-
-				// Join the processed lines back together
-				rawContent = strings.Join(processedLines, "\n")
+			if m.previewRedacted {
+				content = m.redactPreview(content)
 			}
 
-			// Store the processed content
-			m.currentPreviewContent = rawContent
+			m.currentPreviewContent = content
+			m.previewDoc = highlight.NewDocument(content, generator.DetermineLanguage(node.Path))
 		}
 
 		// Store the current viewport dimensions before updating content
 		oldWidth := m.previewViewport.Width
 		oldHeight := m.previewViewport.Height
 
-		// Set the content and reset position
-		m.previewViewport.SetContent(m.currentPreviewContent)
+		// Set the content and reset position. Highlighted documents are rendered by
+		// renderPreviewContent, so the viewport only tracks the scroll position.
+		if m.previewDoc != nil {
+			m.previewViewport.SetContent(m.previewDoc.String())
+		} else {
+			m.previewViewport.SetContent(m.currentPreviewContent)
+		}
 		m.previewViewport.GotoTop()
 
 		// Ensure viewport dimensions remain consistent
@@ -182,6 +218,86 @@ func (m *Model) updatePreview() {
 	}
 }
 
+// previewHeaderText returns the title of the preview pane for the current preview path
+func (m Model) previewHeaderText() string {
+	if m.currentPreviewPath == "" {
+		return "No file selected"
+	}
+	if m.currentPreviewIsDir {
+		return "📁 " + m.currentPreviewPath
+	}
+	if m.picker.active {
+		return "🔎 " + m.picker.path + " (symbols)"
+	}
+	if m.previewHistory {
+		return "🕘 " + m.currentPreviewPath + " (history)"
+	}
+	if m.previewRedacted {
+		return "📄 " + m.currentPreviewPath + " (redacted)"
+	}
+	return "📄 " + m.currentPreviewPath
+}
+
+// redactPreview returns content with the secrets found by the generator's scanner redacted,
+// as they would appear in the generated output
+func (m Model) redactPreview(content string) string {
+	scanner := m.generator.SecretScanner
+	if scanner == nil {
+		return content
+	}
+	findings, err := scanner.Scan(content)
+	if err != nil {
+		return content
+	}
+	return scanner.Redact(content, findings)
+}
+
+// renderPreviewContent renders the visible part of the preview pane. Highlighted documents
+// only style the lines in view, which keeps scrolling through large files responsive.
+func (m Model) renderPreviewContent(width int) string {
+	if m.picker.active {
+		return m.renderSymbolPicker(width, m.previewViewport.Height)
+	}
+	if m.previewDoc == nil {
+		return m.previewViewport.View()
+	}
+	anchorBottom := m.previewViewport.YOffset > 0 && m.previewViewport.AtBottom()
+	return m.previewDoc.Render(m.previewViewport.YOffset, m.previewViewport.Height, width, true, anchorBottom)
+}
+
+// renderFileHistory formats the recent commits that touched a file, with their diffs, for the preview pane
+func (m Model) renderFileHistory(path string) string {
+	if !git.IsRepository(m.rootPath) {
+		return "Not a git repository"
+	}
+
+	depth := m.generator.HistoryDepth
+	if depth <= 0 {
+		depth = defaultPreviewHistoryDepth
+	}
+
+	history, err := git.FileHistory(m.rootPath, path, depth, true)
+	if err != nil {
+		return fmt.Sprintf("Error reading history: %v", err)
+	}
+	if len(history) == 0 {
+		return "No commits found for this file"
+	}
+
+	var b strings.Builder
+	for i, commit := range history {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "%s %s %s", commit.ShortHash(), commit.AuthorDate, commit.Subject)
+		if commit.Diff != "" {
+			b.WriteString("\n\n")
+			b.WriteString(commit.Diff)
+		}
+	}
+	return b.String()
+}
+
 func NewModel(config Config) Model {
 	gitIgnoreMgr, err := filesystem.NewGitIgnoreManager(config.RootPath)
 	if err != nil {
@@ -189,17 +305,36 @@ func NewModel(config Config) Model {
 		os.Exit(1)
 	}
 
-	gen := generator.NewGenerator(config.RootPath, gitIgnoreMgr, config.FilterMgr, config.OutputPath, config.UseTempFile)
-	format := formats.GetFormat(config.Format)
-	gen.SetFormat(format)
+	gen, err := generator.NewGenerator(config.RootPath, gitIgnoreMgr, config.FilterMgr, config.OutputPath, config.UseTempFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	gen.SetFormatFunc(formats.Constructor(config.Format))
 	gen.SetRedactionMode(!config.SkipRedaction)
+	gen.UseStdout = config.UseStdout
+	gen.IncludeMetadata = config.WithMetadata
+	gen.LineNumbers = config.LineNumbers
+	gen.HistoryDepth = config.HistoryDepth
+	gen.HistoryDiff = config.HistoryDiff
 
 	moduleName := dependencies.ReadGoModFile(config.RootPath)
 
+	keys := config.Keymap
+	if keys == nil {
+		keys = keymap.Default()
+	}
+
+	// Cancelled on quit, stopping the walks and dependency resolutions in progress
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return Model{
+		ctx:               ctx,
+		cancel:            cancel,
 		rootPath:          config.RootPath,
 		selected:          make(map[string]bool),
 		deselected:        make(map[string]bool),
+		lineRanges:        make(map[string][]symbols.LineRange),
 		collapsed:         make(map[string]bool),
 		isDependency:      make(map[string]bool),
 		useGitIgnore:      true,
@@ -211,7 +346,9 @@ func NewModel(config Config) Model {
 		showIcons:         config.ShowIcons,
 		maxDepth:          config.MaxDepth,
 		maxFileSize:       config.MaxFileSize,
+		sortMode:          config.SortMode,
 		projectModuleName: moduleName,
+		depResolver:       dependencies.NewConcurrentResolver(config.RootPath, moduleName),
 		showHidden:        false,
 		searchInput:       ui.NewSearchInput(),
 		viewport: viewport.Model{
@@ -226,5 +363,10 @@ func NewModel(config Config) Model {
 		showTokenCount: config.ShowTokenCount,
 		showPreview:    false,
 		tokenCache:     NewTokenCache(),
+		keymap:         keys,
+
+		pendingSelection:  config.InitialFiles,
+		pendingLineRanges: config.LineRanges,
+		startupWarning:    config.StartupWarning,
 	}
 }
@@ -0,0 +1,183 @@
+package model
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/epilande/codegrab/internal/ui"
+)
+
+const (
+	// wheelScrollLines is the number of lines scrolled by one step of the mouse wheel
+	wheelScrollLines = 3
+	// minFileTreeRatio and maxFileTreeRatio bound the share of the width given to the
+	// file tree when the preview split is resized
+	minFileTreeRatio = 0.15
+	maxFileTreeRatio = 0.85
+	// checkboxStart and checkboxEnd are the columns of the checkbox in a file tree line,
+	// after the cursor indicator
+	checkboxStart = 3
+	checkboxEnd   = 7
+	// treeLevelWidth is the width of the tree prefix for each level, e.g. "│   "
+	treeLevelWidth = 4
+)
+
+// handleMouse handles clicks, the mouse wheel, and dragging the preview separator
+func (m Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.output.active {
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			m.output.viewport.LineUp(wheelScrollLines)
+		case tea.MouseButtonWheelDown:
+			m.output.viewport.LineDown(wheelScrollLines)
+		}
+		return m, nil
+	}
+	// The symbol picker is driven by the keyboard, and keeps the previewed file in place
+	if m.picker.active {
+		return m, nil
+	}
+
+	if m.draggingSplit {
+		switch msg.Action {
+		case tea.MouseActionMotion:
+			m.resizeSplit(msg.X)
+		case tea.MouseActionRelease:
+			m.draggingSplit = false
+		}
+		return m, nil
+	}
+
+	overPreview := m.showPreview && !m.showHelp && !m.showStats && msg.X >= m.previewLeft()
+
+	switch msg.Button {
+	case tea.MouseButtonWheelUp, tea.MouseButtonWheelDown:
+		vp := &m.viewport
+		if overPreview {
+			vp = &m.previewViewport
+		}
+		if msg.Button == tea.MouseButtonWheelUp {
+			vp.LineUp(wheelScrollLines)
+		} else {
+			vp.LineDown(wheelScrollLines)
+		}
+		return m, nil
+
+	case tea.MouseButtonLeft:
+		if msg.Action != tea.MouseActionPress || m.showHelp || m.showStats {
+			return m, nil
+		}
+		if m.showPreview && msg.X >= m.separatorLeft() && msg.X < m.previewLeft() {
+			m.draggingSplit = true
+			return m, nil
+		}
+		if overPreview {
+			m.previewFocused = true
+			m.refreshViewportContent()
+			return m, nil
+		}
+		m.clickFileTree(msg.X, msg.Y)
+	}
+	return m, nil
+}
+
+// clickFileTree moves the cursor to the clicked node. Clicking the checkbox toggles its
+// selection, and clicking the icon of a directory collapses or expands it.
+func (m *Model) clickFileTree(x, y int) {
+	nodes := m.displayNodes
+	if m.isSearching && len(m.searchResults) > 0 {
+		nodes = m.searchResults
+	}
+
+	row := y - m.fileTreeTop()
+	column := x - ui.FileTreePaddingL - ui.BorderSize
+	if row < 0 || row >= m.viewport.Height || column < 0 || column >= m.viewport.Width {
+		return
+	}
+	index := m.viewport.YOffset + row
+	if index >= len(nodes) {
+		return
+	}
+	node := nodes[index]
+
+	m.previewFocused = false
+	m.cursor = index
+
+	iconStart := checkboxEnd + (node.Level+1)*treeLevelWidth
+	switch {
+	case column >= checkboxStart && column < checkboxEnd:
+		before := m.snapshotSelection()
+		m.toggleSelection(node.Path, node.IsDir)
+		m.recordUndo(before)
+		m.buildDisplayNodes()
+		if m.isSearching {
+			m.updateSearchResults()
+		}
+	case node.IsDir && !m.isSearching && column >= iconStart-treeLevelWidth && column < iconStart+m.iconWidth(node):
+		m.toggleCollapse(node.Path)
+		m.buildDisplayNodes()
+	}
+
+	m.ensureCursorVisible()
+	m.refreshViewportContent()
+	if m.showPreview {
+		m.updatePreview()
+	}
+}
+
+// iconWidth returns the width of the icon of a node in the file tree, including the
+// space after it
+func (m Model) iconWidth(node FileNode) int {
+	if node.Icon != "" {
+		return lipgloss.Width(node.Icon) + 1
+	}
+	if node.IsDir {
+		// Directories without an icon show an open or closed folder glyph
+		return 2
+	}
+	return 0
+}
+
+// resizeSplit moves the separator between the file tree and the preview to column x
+func (m *Model) resizeSplit(x int) {
+	availableWidth := m.width - ui.FileTreePaddingL - ui.FileTreePaddingR
+	innerWidth := availableWidth - ui.PanelGap - (4 * ui.BorderSize)
+	if innerWidth <= 0 {
+		return
+	}
+
+	ratio := float64(x-ui.FileTreePaddingL-ui.BorderSize) / float64(innerWidth)
+	if ratio < minFileTreeRatio {
+		ratio = minFileTreeRatio
+	} else if ratio > maxFileTreeRatio {
+		ratio = maxFileTreeRatio
+	}
+	m.fileTreeRatio = ratio
+	m.calculateLayout()
+	m.refreshViewportContent()
+}
+
+// treeRatio returns the share of the width given to the file tree next to the preview
+func (m Model) treeRatio() float64 {
+	if m.fileTreeRatio == 0 {
+		return defaultFileTreePreviewRatio
+	}
+	return m.fileTreeRatio
+}
+
+// fileTreeTop returns the screen row of the first line of the file tree
+func (m Model) fileTreeTop() int {
+	panelHeader := ui.GetStyleFileTreePanelHeader().Render("📚 Files")
+	return lipgloss.Height(m.renderHeader()) + lipgloss.Height(panelHeader) + ui.BorderSize
+}
+
+// separatorLeft returns the screen column of the right border of the file tree, where
+// the separator between the file tree and the preview starts
+func (m Model) separatorLeft() int {
+	return ui.FileTreePaddingL + ui.BorderSize + m.viewport.Width
+}
+
+// previewLeft returns the screen column of the left border of the preview
+func (m Model) previewLeft() int {
+	return m.separatorLeft() + ui.BorderSize + ui.PanelGap
+}
@@ -0,0 +1,448 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/epilande/codegrab/internal/generator"
+	"github.com/epilande/codegrab/internal/keymap"
+	"github.com/epilande/codegrab/internal/ui"
+	"github.com/epilande/codegrab/internal/ui/highlight"
+	"github.com/epilande/codegrab/internal/ui/themes"
+)
+
+const (
+	// outputPreviewMinPanelWidth is the narrowest window for which the token panel is shown
+	outputPreviewMinPanelWidth = 60
+	// outputPreviewMaxPanelWidth is the widest the token panel grows
+	outputPreviewMaxPanelWidth = 40
+)
+
+// outputPreview holds the state of the full-screen preview of the generated output
+type outputPreview struct {
+	err         error
+	cancel      context.CancelFunc
+	doc         *highlight.Document
+	format      string
+	files       []generator.FileTokenCount
+	matches     []int
+	viewport    viewport.Model
+	searchInput textinput.Model
+	seq         int
+	tokenCount  int
+	secretCount int
+	matchIndex  int
+	active      bool
+	loading     bool
+	searching   bool
+}
+
+// outputPreviewMsg carries the result of rendering the output preview
+type outputPreviewMsg struct {
+	err         error
+	content     string
+	format      string
+	files       []generator.FileTokenCount
+	seq         int
+	tokenCount  int
+	secretCount int
+}
+
+// openOutputPreview shows the output preview and starts rendering the current selection
+func (m *Model) openOutputPreview() tea.Cmd {
+	if len(m.selected) == 0 {
+		m.warningMsg = "No files selected"
+		return nil
+	}
+
+	searchInput := ui.NewSearchInput()
+	searchInput.Placeholder = "Search output..."
+	m.output = outputPreview{
+		active:      true,
+		seq:         m.output.seq,
+		cancel:      m.output.cancel,
+		searchInput: searchInput,
+	}
+	m.layoutOutputPreview()
+	return m.renderOutputPreview()
+}
+
+// closeOutputPreview hides the output preview and cancels a render in progress
+func (m *Model) closeOutputPreview() {
+	if m.output.cancel != nil {
+		m.output.cancel()
+	}
+	m.output = outputPreview{seq: m.output.seq}
+}
+
+// renderOutputPreview renders the output of the current selection in the background.
+// A render in progress is cancelled, and results of earlier renders are ignored.
+func (m *Model) renderOutputPreview() tea.Cmd {
+	if m.output.cancel != nil {
+		m.output.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.output.cancel = cancel
+	m.output.seq++
+	m.output.loading = true
+	m.output.err = nil
+
+	// Render with a copy of the generator and the selection, so that the render
+	// does not race with the selection changing in the meantime
+	gen := *m.generator
+	gen.SelectedFiles = copySelection(m.selected)
+	gen.DeselectedFiles = copySelection(m.deselected)
+	gen.LineRanges = copyLineRanges(m.lineRanges)
+	seq := m.output.seq
+
+	return func() tea.Msg {
+		done := make(chan outputPreviewMsg, 1)
+		go func() {
+			content, tokenCount, secretCount, err := gen.GenerateString(ctx)
+			done <- outputPreviewMsg{
+				err:         err,
+				content:     content,
+				format:      gen.GetFormatName(),
+				files:       gen.FileTokenCounts(),
+				seq:         seq,
+				tokenCount:  tokenCount,
+				secretCount: secretCount,
+			}
+		}()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-done:
+			return msg
+		}
+	}
+}
+
+// copySelection returns a copy of a selection map
+func copySelection(selection map[string]bool) map[string]bool {
+	copied := make(map[string]bool, len(selection))
+	for path, selected := range selection {
+		copied[path] = selected
+	}
+	return copied
+}
+
+// handleOutputPreviewResult shows a finished render, unless the preview has since been
+// closed or re-rendered
+func (m *Model) handleOutputPreviewResult(msg outputPreviewMsg) {
+	if !m.output.active || msg.seq != m.output.seq {
+		return
+	}
+
+	m.output.loading = false
+	m.output.cancel = nil
+	m.output.err = msg.err
+	m.output.format = msg.format
+	m.output.tokenCount = msg.tokenCount
+	m.output.secretCount = msg.secretCount
+	m.output.doc = nil
+	m.output.files = nil
+	if msg.err != nil {
+		m.output.viewport.SetContent("")
+		return
+	}
+
+	m.output.files = append([]generator.FileTokenCount(nil), msg.files...)
+	sort.SliceStable(m.output.files, func(i, j int) bool {
+		return m.output.files[i].Tokens > m.output.files[j].Tokens
+	})
+
+	m.output.doc = highlight.NewDocument(msg.content, outputLanguage(msg.format))
+	m.output.viewport.SetContent(m.output.doc.String())
+	m.output.viewport.GotoTop()
+	m.applyOutputSearch()
+}
+
+// outputLanguage returns the language used to highlight output in the named format
+func outputLanguage(format string) string {
+	switch format {
+	case "markdown":
+		return "markdown"
+	case "xml":
+		return "xml"
+	default:
+		return "text"
+	}
+}
+
+// handleOutputPreviewKey handles a key press while the output preview is shown
+func (m Model) handleOutputPreviewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.output.searching {
+		switch msg.String() {
+		case "ctrl+c":
+			m.closeOutputPreview()
+			return m, tea.Quit
+		case "esc":
+			m.output.searching = false
+			m.output.searchInput.Blur()
+			m.output.searchInput.SetValue("")
+			m.applyOutputSearch()
+			m.layoutOutputPreview()
+			return m, nil
+		case "enter":
+			m.output.searching = false
+			m.output.searchInput.Blur()
+			m.layoutOutputPreview()
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.output.searchInput, cmd = m.output.searchInput.Update(msg)
+		m.applyOutputSearch()
+		return m, cmd
+	}
+
+	vp := &m.output.viewport
+	if msg.String() == keymap.QuitKey {
+		m.closeOutputPreview()
+		return m, tea.Quit
+	}
+	switch m.resolveKey(msg.String()) {
+	case keymap.Quit, keymap.Cancel, keymap.PreviewOutput:
+		m.closeOutputPreview()
+		m.refreshViewportContent()
+	case keymap.CursorDown:
+		vp.LineDown(1)
+	case keymap.CursorUp:
+		vp.LineUp(1)
+	case keymap.HalfPageDown:
+		vp.HalfViewDown()
+	case keymap.HalfPageUp:
+		vp.HalfViewUp()
+	case keymap.GoToTop:
+		vp.GotoTop()
+	case keymap.GoToBottom:
+		vp.GotoBottom()
+	case keymap.Search:
+		m.output.searching = true
+		m.layoutOutputPreview()
+		return m, m.output.searchInput.Focus()
+	case keymap.NextMatch:
+		m.jumpToOutputMatch(m.output.matchIndex + 1)
+	case keymap.PrevMatch:
+		m.jumpToOutputMatch(m.output.matchIndex - 1)
+	case keymap.Copy:
+		m.closeOutputPreview()
+		cmd := m.copyOutputToClipboard()
+		return m, cmd
+	case keymap.Generate:
+		m.closeOutputPreview()
+		cmd := m.generateOutput()
+		return m, cmd
+	default:
+		// The paging keys cannot be rebound
+		switch msg.String() {
+		case "pgdown", "ctrl+f":
+			vp.ViewDown()
+		case "pgup", "ctrl+b":
+			vp.ViewUp()
+		case "home":
+			vp.GotoTop()
+		case "end":
+			vp.GotoBottom()
+		}
+	}
+	return m, nil
+}
+
+// applyOutputSearch highlights the search query in the output and jumps to the first
+// match at or below the top of the view
+func (m *Model) applyOutputSearch() {
+	m.output.matches = nil
+	m.output.matchIndex = 0
+	if m.output.doc == nil {
+		return
+	}
+
+	query := m.output.searchInput.Value()
+	m.output.doc.SetSearch(query)
+	m.output.matches = m.output.doc.Search(query)
+	if len(m.output.matches) == 0 {
+		return
+	}
+	for i, line := range m.output.matches {
+		if line >= m.output.viewport.YOffset {
+			m.jumpToOutputMatch(i)
+			return
+		}
+	}
+	m.jumpToOutputMatch(0)
+}
+
+// jumpToOutputMatch scrolls the output to the i-th search match, wrapping around at
+// either end
+func (m *Model) jumpToOutputMatch(i int) {
+	count := len(m.output.matches)
+	if count == 0 {
+		return
+	}
+	i = ((i % count) + count) % count
+	m.output.matchIndex = i
+	m.output.viewport.SetYOffset(m.output.matches[i])
+}
+
+// outputPreviewHeader returns the title line of the output preview
+func (m Model) outputPreviewHeader() string {
+	title := "📦 Output Preview"
+	switch {
+	case m.output.loading:
+		title += " · ⏳ Rendering..."
+	case m.output.err != nil:
+	default:
+		title += fmt.Sprintf(" · %s · %d tokens", m.output.format, m.output.tokenCount)
+		if m.output.secretCount > 0 && m.redactSecrets {
+			title += fmt.Sprintf(" · ℹ️ %d secrets redacted", m.output.secretCount)
+		} else if m.output.secretCount > 0 {
+			title += fmt.Sprintf(" · ⚠️ %d secrets NOT redacted", m.output.secretCount)
+		}
+	}
+	return ui.GetStyleHeader().Render(title)
+}
+
+// outputPreviewFooter returns the help line of the output preview, or the search input
+// while searching
+func (m Model) outputPreviewFooter() string {
+	if m.output.searching {
+		return m.output.searchInput.View()
+	}
+
+	help := fmt.Sprintf("Scroll: %s/%s | Search: %s | Next/Prev: %s/%s | Copy: %s | Generate: %s | Close: %s",
+		m.keyHint(keymap.CursorDown), m.keyHint(keymap.CursorUp), m.keyHint(keymap.Search),
+		m.keyHint(keymap.NextMatch), m.keyHint(keymap.PrevMatch), m.keyHint(keymap.Copy),
+		m.keyHint(keymap.Generate), m.keyHint(keymap.Cancel))
+	if query := m.output.searchInput.Value(); query != "" {
+		matches := "No matches"
+		if len(m.output.matches) > 0 {
+			matches = fmt.Sprintf("%d/%d", m.output.matchIndex+1, len(m.output.matches))
+		}
+		help = ui.GetStyleSearchCount().Render(fmt.Sprintf("🔍 %s (%s)", query, matches)) + " " + ui.GetStyleHelp().Render(help)
+		return help
+	}
+	return ui.GetStyleHelp().Render(help)
+}
+
+// outputPreviewLayout returns the inner widths of the output and token panels, and the
+// inner height of both. The token panel is hidden in narrow windows.
+func (m Model) outputPreviewLayout() (int, int, int) {
+	height := m.height - lipgloss.Height(m.outputPreviewHeader()) - lipgloss.Height(m.outputPreviewFooter()) - (2 * ui.BorderSize)
+	if height < 0 {
+		height = 0
+	}
+
+	availableWidth := m.width - ui.FileTreePaddingL - ui.FileTreePaddingR
+	panelWidth := 0
+	if availableWidth >= outputPreviewMinPanelWidth {
+		panelWidth = availableWidth / 3
+		if panelWidth > outputPreviewMaxPanelWidth {
+			panelWidth = outputPreviewMaxPanelWidth
+		}
+	}
+
+	contentWidth := availableWidth - (2 * ui.BorderSize)
+	if panelWidth > 0 {
+		contentWidth -= panelWidth + (2 * ui.BorderSize) + ui.PanelGap
+	}
+	if contentWidth < 0 {
+		contentWidth = 0
+	}
+	return contentWidth, panelWidth, height
+}
+
+// layoutOutputPreview sizes the output viewport to the window
+func (m *Model) layoutOutputPreview() {
+	if !m.output.active {
+		return
+	}
+	contentWidth, _, height := m.outputPreviewLayout()
+	m.output.viewport.Width = contentWidth
+	m.output.viewport.Height = height
+}
+
+// viewOutputPreview renders the full-screen output preview
+func (m Model) viewOutputPreview() string {
+	header := m.outputPreviewHeader()
+	footer := m.outputPreviewFooter()
+	contentWidth, panelWidth, height := m.outputPreviewLayout()
+
+	var content string
+	switch {
+	case m.output.loading:
+		content = ui.GetStyleInfo().Render("Rendering output...")
+	case m.output.err != nil:
+		content = ui.GetStyleError().Render(m.output.err.Error())
+	case m.output.doc != nil:
+		vp := m.output.viewport
+		anchorBottom := vp.YOffset > 0 && vp.AtBottom()
+		content = m.output.doc.Render(vp.YOffset, height, contentWidth, true, anchorBottom)
+	}
+	panelStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(themes.CurrentTheme.Colors().Border).
+		Height(height)
+	panels := panelStyle.Width(contentWidth).Render(content)
+
+	if panelWidth > 0 {
+		tokenPanel := panelStyle.Width(panelWidth).Render(m.renderFileTokens(panelWidth, height))
+		panels = lipgloss.JoinHorizontal(lipgloss.Top, panels, strings.Repeat(" ", ui.PanelGap), tokenPanel)
+	}
+
+	return lipgloss.NewStyle().
+		PaddingLeft(ui.FileTreePaddingL).
+		PaddingRight(ui.FileTreePaddingR).
+		Render(header + "\n" + panels + "\n" + footer)
+}
+
+// renderFileTokens lists the token subtotal of each file in the output, largest first
+func (m Model) renderFileTokens(width, height int) string {
+	if height <= 0 || len(m.output.files) == 0 {
+		return ""
+	}
+
+	lines := []string{ui.GetStyleFileTreePanelHeader().Render("🔢 Tokens by file")}
+	visible := m.output.files
+	if len(visible) > height-1 {
+		visible = visible[:max(height-2, 0)]
+	}
+	tokenStyle := lipgloss.NewStyle().Foreground(themes.CurrentTheme.Colors().Info)
+	rowWidth := width - ui.FileTreePaddingL - ui.FileTreePaddingR
+	for _, file := range visible {
+		tokens := fmt.Sprintf(" %d", file.Tokens)
+		name := truncatePathLeft(filepath.ToSlash(file.Path), rowWidth-runewidth.StringWidth(tokens))
+		padding := max(rowWidth-runewidth.StringWidth(name)-runewidth.StringWidth(tokens), 0)
+		lines = append(lines, " "+name+strings.Repeat(" ", padding)+tokenStyle.Render(tokens))
+	}
+	if hidden := len(m.output.files) - len(visible); hidden > 0 {
+		lines = append(lines, ui.GetStyleHelp().Render(fmt.Sprintf(" … %d more", hidden)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncatePathLeft shortens path to at most width columns by dropping leading
+// characters, so that the file name stays visible
+func truncatePathLeft(path string, width int) string {
+	if runewidth.StringWidth(path) <= width {
+		return path
+	}
+	if width <= 1 {
+		return "…"
+	}
+	runes := []rune(path)
+	for len(runes) > 0 && runewidth.StringWidth(string(runes))+1 > width {
+		runes = runes[1:]
+	}
+	return "…" + string(runes)
+}
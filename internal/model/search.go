@@ -1,11 +1,42 @@
 package model
 
 import (
+	"context"
+	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/epilande/codegrab/internal/filesystem"
+)
+
+// searchMode selects how the search query is matched against files
+type searchMode int
+
+const (
+	// fuzzySearch fuzzy-matches the query against file paths
+	fuzzySearch searchMode = iota
+	// contentSearch matches the query as a regular expression against file contents
+	contentSearch
 )
 
+// contentSearchDebounce is how long to wait after the last keystroke before searching file contents
+const contentSearchDebounce = 150 * time.Millisecond
+
+// maxGrepPreviewWidth limits the length of the first matching line shown next to a result
+const maxGrepPreviewWidth = 40
+
+type contentSearchDebounceMsg struct {
+	seq int
+}
+
+type contentSearchResultsMsg struct {
+	matches []filesystem.GrepMatch
+	seq     int
+}
+
 // fuzzyMatch checks if query fuzzy matches the target string
 func fuzzyMatch(query, target string) bool {
 	// Normalize query and target strings
@@ -38,7 +69,14 @@ func (m *Model) updateSearchResults() {
 	// First find all matching file paths
 	matchedFiles := make(map[string]bool)
 	for _, node := range m.displayNodes {
-		if !node.IsDir && fuzzyMatch(query, node.Path) {
+		if node.IsDir {
+			continue
+		}
+		if m.searchMode == contentSearch {
+			if _, ok := m.grepMatches[node.Path]; ok {
+				matchedFiles[node.Path] = true
+			}
+		} else if fuzzyMatch(query, node.Path) {
 			matchedFiles[node.Path] = true
 		}
 	}
@@ -82,3 +120,116 @@ func (m *Model) isInSearchResults(path string) bool {
 	}
 	return false
 }
+
+// startSearch enters search mode using the given matching mode
+func (m *Model) startSearch(mode searchMode) {
+	m.isSearching = true
+	m.visualMode = false
+	m.searchMode = mode
+	m.grepMatches = nil
+	m.searchInput.Focus()
+	m.searchInput.SetValue("")
+	m.searchInput.Placeholder = searchPlaceholder(mode)
+	m.searchResults = nil
+	m.cursor = 0
+	m.viewport.GotoTop()
+	m.expandAllDirectories()
+	m.refreshViewportContent()
+}
+
+// switchSearchMode toggles between fuzzy path search and content search, keeping the query
+func (m *Model) switchSearchMode() tea.Cmd {
+	if m.searchMode == contentSearch {
+		m.searchMode = fuzzySearch
+	} else {
+		m.searchMode = contentSearch
+	}
+	m.grepMatches = nil
+	m.searchInput.Placeholder = searchPlaceholder(m.searchMode)
+	m.cursor = 0
+	m.viewport.GotoTop()
+	m.updateSearchResults()
+	m.refreshViewportContent()
+	return m.scheduleContentSearch()
+}
+
+// searchPlaceholder returns the search input placeholder for a search mode
+func searchPlaceholder(mode searchMode) string {
+	if mode == contentSearch {
+		return "Search file contents (regex)..."
+	}
+	return "Search files..."
+}
+
+// scheduleContentSearch debounces a content search for the current query
+func (m *Model) scheduleContentSearch() tea.Cmd {
+	// The query changed, so a search still running for the previous one is of no use
+	m.cancelContentSearch()
+	if m.searchMode != contentSearch {
+		return nil
+	}
+	m.contentSearchSeq++
+	seq := m.contentSearchSeq
+	return tea.Tick(contentSearchDebounce, func(time.Time) tea.Msg {
+		return contentSearchDebounceMsg{seq: seq}
+	})
+}
+
+// runContentSearch searches the contents of all loaded files in the background.
+// Queries that are not valid regular expressions are matched literally. A search in
+// progress is cancelled.
+func (m *Model) runContentSearch(seq int) tea.Cmd {
+	m.cancelContentSearch()
+	query := strings.TrimSpace(m.searchInput.Value())
+	if query == "" {
+		return func() tea.Msg {
+			return contentSearchResultsMsg{seq: seq}
+		}
+	}
+
+	re, err := filesystem.CompileGrepPattern(query, false)
+	if err != nil {
+		re, err = filesystem.CompileGrepPattern(query, true)
+		if err != nil {
+			return nil
+		}
+	}
+
+	rootPath := m.rootPath
+	paths := make([]string, 0, len(m.files))
+	for _, f := range m.files {
+		if !f.IsDir {
+			paths = append(paths, f.Path)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.contentSearchCancel = cancel
+
+	return func() tea.Msg {
+		// A cancelled search has been superseded, its results are ignored
+		matches, _ := filesystem.GrepFiles(ctx, rootPath, paths, re)
+		return contentSearchResultsMsg{seq: seq, matches: matches}
+	}
+}
+
+// cancelContentSearch stops the content search in progress, if any
+func (m *Model) cancelContentSearch() {
+	if m.contentSearchCancel != nil {
+		m.contentSearchCancel()
+		m.contentSearchCancel = nil
+	}
+}
+
+// formatGrepSuffix renders the hit count and first matching line for a content search result
+func formatGrepSuffix(match filesystem.GrepMatch) string {
+	text := match.FirstText
+	if runes := []rune(text); len(runes) > maxGrepPreviewWidth {
+		text = string(runes[:maxGrepPreviewWidth-3]) + "..."
+	}
+	hits := "hits"
+	if match.Count == 1 {
+		hits = "hit"
+	}
+	return fmt.Sprintf(" (%d %s) L%d: %s", match.Count, hits, match.FirstLine, text)
+}
@@ -1,9 +1,15 @@
 package model
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/epilande/codegrab/internal/filesystem"
 )
 
 func TestFuzzyMatch(t *testing.T) {
@@ -187,3 +193,84 @@ func TestIsInSearchResults(t *testing.T) {
 		t.Errorf("Expected isInSearchResults to return false with empty search results")
 	}
 }
+
+func TestContentSearch(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "content-search-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"a.go":     "package a\n\nfunc Handler() {}\n",
+		"b/b.go":   "package b\n\n// Handler docs\nfunc Handler() {}\n",
+		"c/readme": "no match here\n",
+	}
+	for file, content := range files {
+		path := filepath.Join(tempDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	m := NewModel(Config{
+		RootPath:    tempDir,
+		FilterMgr:   filesystem.NewFilterManager(),
+		Format:      "markdown",
+		MaxFileSize: 1024 * 1024,
+	})
+	defer m.tokenCache.Close()
+
+	m.files, err = filesystem.WalkDirectory(context.Background(), tempDir, m.gitIgnoreMgr, m.filterMgr, true, false, m.maxFileSize)
+	if err != nil {
+		t.Fatalf("Failed to walk directory: %v", err)
+	}
+	m.buildDisplayNodes()
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlF})
+	m = updated.(Model)
+	if !m.isSearching || m.searchMode != contentSearch {
+		t.Fatalf("Expected ctrl+f to start content search")
+	}
+
+	m.searchInput.SetValue("func Handler")
+	msg := m.runContentSearch(m.contentSearchSeq)()
+	updated, _ = m.Update(msg)
+	m = updated.(Model)
+
+	if len(m.grepMatches) != 2 {
+		t.Fatalf("Expected 2 files to match, got %d", len(m.grepMatches))
+	}
+	if m.grepMatches["b/b.go"].FirstLine != 4 {
+		t.Errorf("Expected first match in b/b.go on line 4, got %d", m.grepMatches["b/b.go"].FirstLine)
+	}
+
+	m.refreshViewportContent()
+	if !strings.Contains(m.viewport.View(), "(1 hit) L3") {
+		t.Errorf("Expected hit count and line to be rendered, got:\n%s", m.viewport.View())
+	}
+
+	// Stale results are ignored
+	updated, _ = m.Update(contentSearchResultsMsg{seq: m.contentSearchSeq - 1})
+	m = updated.(Model)
+	if len(m.grepMatches) != 2 {
+		t.Errorf("Expected stale results to be ignored")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlA})
+	m = updated.(Model)
+	if !m.selected["a.go"] || !m.selected["b/b.go"] || m.selected["c/readme"] {
+		t.Errorf("Expected only matching files to be selected, got %v", m.selected)
+	}
+
+	// A new query cancels the search still running for the previous one
+	search := m.runContentSearch(m.contentSearchSeq)
+	m.searchInput.SetValue("func Other")
+	m.scheduleContentSearch()
+	if results := search().(contentSearchResultsMsg); results.matches != nil {
+		t.Errorf("Expected the superseded search to be cancelled, got %v", results.matches)
+	}
+}
@@ -8,14 +8,12 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/epilande/codegrab/internal/dependencies"
+	"github.com/epilande/codegrab/internal/filesystem"
+	"github.com/epilande/codegrab/internal/project"
+	"github.com/epilande/codegrab/internal/symbols"
 	"github.com/epilande/codegrab/internal/utils"
 )
 
-type QueuedDep struct {
-	Path  string
-	Depth int
-}
-
 // filterSelections removes items from selection/deselection maps if they
 // are filtered out by gitignore or hidden file settings.
 func (m *Model) filterSelections() {
@@ -59,8 +57,7 @@ func findParentDirectory(path, root string, selected map[string]bool) string {
 // It reads the file content and uses the appropriate resolver.
 // Returns a slice of dependency paths relative to the project root, or an error.
 func (m *Model) getDirectDependencies(filePath string) ([]string, error) {
-	resolver := dependencies.GetResolver(filePath)
-	if resolver == nil {
+	if dependencies.GetResolver(filePath) == nil {
 		return nil, nil // No resolver for this file type
 	}
 
@@ -74,20 +71,12 @@ func (m *Model) getDirectDependencies(filePath string) ([]string, error) {
 		return nil, fmt.Errorf("cannot read file %s for dependency resolution: %w", filePath, err)
 	}
 
-	deps, err := resolver.Resolve(content, filePath, m.rootPath, m.projectModuleName)
+	deps, err := m.depResolver.Resolve(m.ctx, filePath, content)
 	if err != nil {
 		return nil, fmt.Errorf("error resolving dependencies for %s: %w", filePath, err)
 	}
 
-	normalizedDeps := make([]string, 0, len(deps))
-	for _, depPath := range deps {
-		cleanDepPath := filepath.ToSlash(filepath.Clean(depPath))
-		if cleanDepPath != "." && !filepath.IsAbs(cleanDepPath) {
-			normalizedDeps = append(normalizedDeps, cleanDepPath)
-		}
-	}
-
-	return normalizedDeps, nil
+	return deps, nil
 }
 
 func (m *Model) toggleSelection(path string, isDir bool) tea.Cmd {
@@ -105,7 +94,7 @@ func (m *Model) toggleSelection(path string, isDir bool) tea.Cmd {
 		maxDepth = m.maxDepth
 	}
 
-	depQueue := []QueuedDep{}
+	depLevel := []string{}
 	depProcessed := make(map[string]bool)
 
 	if isDir {
@@ -127,6 +116,7 @@ func (m *Model) toggleSelection(path string, isDir bool) tea.Cmd {
 						}
 						delete(m.selected, f.Path)
 						delete(m.isDependency, f.Path)
+						delete(m.lineRanges, f.Path)
 						m.deselected[f.Path] = true
 					}
 				}
@@ -186,7 +176,7 @@ func (m *Model) toggleSelection(path string, isDir bool) tea.Cmd {
 
 					if newlySelected && !f.IsDir && m.resolveDeps && maxDepth > 0 {
 						if !depProcessed[f.Path] {
-							depQueue = append(depQueue, QueuedDep{Path: f.Path, Depth: 0})
+							depLevel = append(depLevel, f.Path)
 							depProcessed[f.Path] = true
 						}
 					}
@@ -194,7 +184,8 @@ func (m *Model) toggleSelection(path string, isDir bool) tea.Cmd {
 			}
 		}
 	} else {
-		// Selecting/Deselecting a File
+		// Selecting/Deselecting a File, which drops the lines it was limited to
+		delete(m.lineRanges, path)
 		if m.selected[path] {
 			// Deselecting file
 			delete(m.selected, path)
@@ -226,59 +217,90 @@ func (m *Model) toggleSelection(path string, isDir bool) tea.Cmd {
 
 			if m.resolveDeps && maxDepth > 0 {
 				if !depProcessed[path] {
-					depQueue = append(depQueue, QueuedDep{Path: path, Depth: 0})
+					depLevel = append(depLevel, path)
 					depProcessed[path] = true
 				}
 			}
 		}
 	}
 
-	// This runs only if m.resolveDeps is true and items were added to depQueue
-	i := 0
-	for i < len(depQueue) {
-		currentItem := depQueue[i]
-		i++
+	// This runs only if m.resolveDeps is true and items were added to depLevel.
+	// Each level of dependencies is resolved concurrently.
+	if len(depLevel) == 0 {
+		return nil
+	}
+	rules := project.DependencyRules{
+		GitIgnore:    m.gitIgnoreMgr,
+		Filter:       m.filterMgr,
+		MaxFileSize:  m.maxFileSize,
+		UseGitIgnore: m.useGitIgnore,
+		ShowHidden:   m.showHidden,
+	}
+	deps, err := project.ResolveDependencies(m.ctx, m.depResolver, m.rootPath, depLevel, maxDepth, rules, func(path string, err error) {
+		fmt.Fprintf(os.Stderr, "Warning resolving deps for %s: %v\n", path, err)
+	})
+	if err != nil {
+		// Only quitting cancels the resolution
+		return nil
+	}
+	for _, dep := range deps {
+		if !m.selected[dep.Path] {
+			m.selected[dep.Path] = true
+			m.isDependency[dep.Path] = true
+		}
+		delete(m.deselected, dep.Path)
+	}
 
-		filePath := currentItem.Path
-		currentDepth := currentItem.Depth
+	return nil
+}
 
-		if currentDepth >= maxDepth {
-			continue
-		}
+// applyPendingSelection selects the files from an explicit file list once the
+// file tree has been loaded, expanding their parent directories so they are visible.
+func (m *Model) applyPendingSelection() {
+	if m.pendingSelection == nil {
+		return
+	}
 
-		directDeps, err := m.getDirectDependencies(filePath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning resolving deps for %s: %v\n", filePath, err)
-			continue
+	accepted, skipped, err := filesystem.ValidateFileList(m.ctx, m.rootPath, m.pendingSelection, m.gitIgnoreMgr, m.useGitIgnore, m.showHidden, m.maxFileSize)
+	m.pendingSelection = nil
+	if err != nil {
+		m.err = fmt.Errorf("failed to select the listed files: %w", err)
+		return
+	}
+
+	for _, path := range accepted {
+		if !m.selected[path] {
+			m.toggleSelection(path, false)
+		}
+		if ranges := m.pendingLineRanges[path]; len(ranges) > 0 {
+			m.lineRanges[path] = ranges
+		}
+		dir := filepath.Dir(path)
+		for dir != "." && dir != "/" && dir != "" {
+			delete(m.collapsed, dir)
+			dir = filepath.Dir(dir)
 		}
+	}
 
-		for _, depPath := range directDeps {
-			depFullPath := filepath.Join(m.rootPath, depPath)
-			info, statErr := os.Stat(depFullPath)
-			if statErr != nil || info.IsDir() ||
-				(m.useGitIgnore && m.gitIgnoreMgr.IsIgnored(depFullPath)) ||
-				(!m.showHidden && utils.IsHiddenPath(depPath)) ||
-				(info.Size() > m.maxFileSize) ||
-				!m.filterMgr.ShouldInclude(depPath) {
-				continue
-			}
+	m.pendingLineRanges = nil
 
-			if !m.selected[depPath] {
-				m.selected[depPath] = true
-				m.isDependency[depPath] = true
-				delete(m.deselected, depPath)
+	m.successMsg = fmt.Sprintf("Selected %d files from list", len(accepted))
+	if len(skipped) > 0 {
+		m.warningMsg = fmt.Sprintf("⚠️ %d listed files skipped", len(skipped))
+	}
+}
 
-				if !depProcessed[depPath] {
-					depQueue = append(depQueue, QueuedDep{Path: depPath, Depth: currentDepth + 1})
-					depProcessed[depPath] = true
-				}
-			} else {
-				delete(m.deselected, depPath)
-			}
+// selectAllSearchResults selects every file in the current search results
+func (m *Model) selectAllSearchResults() int {
+	count := 0
+	for _, node := range m.searchResults {
+		if node.IsDir || m.selected[node.Path] {
+			continue
 		}
+		m.toggleSelection(node.Path, false)
+		count++
 	}
-
-	return nil
+	return count
 }
 
 func (m *Model) expandAllDirectories() {
@@ -298,3 +320,161 @@ func (m *Model) collapseAllDirectories() {
 	}
 	m.buildDisplayNodes()
 }
+
+// isSelectableFile reports whether a file may be added to the selection under the
+// current gitignore, hidden file, size and glob filters
+func (m *Model) isSelectableFile(path string) bool {
+	fullPath := filepath.Join(m.rootPath, path)
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return !(m.useGitIgnore && m.gitIgnoreMgr.IsIgnored(fullPath)) &&
+		!(!m.showHidden && utils.IsHiddenPath(path)) &&
+		info.Size() <= m.maxFileSize &&
+		m.filterMgr.ShouldInclude(path)
+}
+
+// startVisualSelection anchors a visual range at the cursor
+func (m *Model) startVisualSelection() {
+	m.visualMode = true
+	m.visualAnchor = m.cursor
+}
+
+// visualRange returns the first and last display node indexes of the visual range
+func (m *Model) visualRange() (int, int) {
+	anchor := m.visualAnchor
+	if anchor >= len(m.displayNodes) {
+		anchor = len(m.displayNodes) - 1
+	}
+	if anchor < m.cursor {
+		return anchor, m.cursor
+	}
+	return m.cursor, anchor
+}
+
+// applyVisualSelection ends visual mode, selecting the nodes in the range. If every
+// node in the range is already selected, they are deselected instead. It returns the
+// number of nodes changed and whether they were selected.
+func (m *Model) applyVisualSelection() (int, bool) {
+	m.visualMode = false
+	if len(m.displayNodes) == 0 {
+		return 0, false
+	}
+
+	start, end := m.visualRange()
+	nodes := append([]FileNode(nil), m.displayNodes[start:end+1]...)
+
+	selecting := false
+	for _, node := range nodes {
+		if !m.selected[node.Path] {
+			selecting = true
+			break
+		}
+	}
+
+	// Selection state is checked again before each toggle, since toggling a
+	// directory also toggles the files and directories inside it
+	count := 0
+	for _, node := range nodes {
+		if m.selected[node.Path] == selecting {
+			continue
+		}
+		m.toggleSelection(node.Path, node.IsDir)
+		count++
+	}
+	return count, selecting
+}
+
+// selectAllVisible selects every file shown in the file tree
+func (m *Model) selectAllVisible() int {
+	count := 0
+	for _, node := range m.displayNodes {
+		if node.IsDir || m.selected[node.Path] || !m.isSelectableFile(node.Path) {
+			continue
+		}
+		m.toggleSelection(node.Path, false)
+		count++
+	}
+	return count
+}
+
+// cursorDirectory returns the directory at the cursor, or the directory containing
+// the file at the cursor. The project root is returned as ".".
+func (m *Model) cursorDirectory() string {
+	if m.cursor < 0 || m.cursor >= len(m.displayNodes) {
+		return "."
+	}
+	node := m.displayNodes[m.cursor]
+	if node.IsDir {
+		return node.Path
+	}
+	return filepath.Dir(node.Path)
+}
+
+// invertSelectionInDirectory inverts the selection of the files inside the directory
+// at the cursor, or the directory containing the file at the cursor. It returns the
+// directory and the number of files changed.
+func (m *Model) invertSelectionInDirectory() (string, int) {
+	dir := m.cursorDirectory()
+
+	var toSelect, toDeselect []string
+	for _, f := range m.files {
+		if f.IsDir || (dir != "." && !strings.HasPrefix(f.Path, dir+"/")) {
+			continue
+		}
+		if m.selected[f.Path] {
+			toDeselect = append(toDeselect, f.Path)
+		} else if m.isSelectableFile(f.Path) {
+			toSelect = append(toSelect, f.Path)
+		}
+	}
+
+	// Deselect first, so that dependencies pulled in by the newly selected files
+	// are not deselected again
+	for _, path := range toDeselect {
+		if m.selected[path] {
+			m.toggleSelection(path, false)
+		}
+	}
+	for _, path := range toSelect {
+		if !m.selected[path] {
+			m.toggleSelection(path, false)
+		}
+	}
+	return dir, len(toSelect) + len(toDeselect)
+}
+
+// selectByExtension selects every file with the same extension as the file at the
+// cursor. It returns the extension and the number of files selected.
+func (m *Model) selectByExtension() (string, int) {
+	if m.cursor < 0 || m.cursor >= len(m.displayNodes) || m.displayNodes[m.cursor].IsDir {
+		return "", 0
+	}
+	ext := filepath.Ext(m.displayNodes[m.cursor].Path)
+	if ext == "" {
+		return "", 0
+	}
+
+	count := 0
+	for _, f := range m.files {
+		if f.IsDir || m.selected[f.Path] || !strings.EqualFold(filepath.Ext(f.Path), ext) || !m.isSelectableFile(f.Path) {
+			continue
+		}
+		m.toggleSelection(f.Path, false)
+		count++
+	}
+	return ext, count
+}
+
+// clearSelection deselects every file and directory without reloading the file tree.
+// It returns the number of files that were selected.
+func (m *Model) clearSelection() int {
+	count := m.getSelectedFileCount()
+	m.selected = make(map[string]bool)
+	m.deselected = make(map[string]bool)
+	m.isDependency = make(map[string]bool)
+	m.lineRanges = make(map[string][]symbols.LineRange)
+	m.visualMode = false
+	return count
+}
@@ -0,0 +1,182 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/epilande/codegrab/internal/filesystem"
+	"github.com/epilande/codegrab/internal/git"
+	"github.com/epilande/codegrab/internal/utils"
+)
+
+// SortMode selects the order of the entries within each directory of the file tree
+type SortMode int
+
+const (
+	// SortByName sorts alphabetically, directories first
+	SortByName SortMode = iota
+	// SortBySize sorts by size, largest first. Directories are sorted by the total size of their files.
+	SortBySize
+	// SortByTokens sorts by estimated tokens, most first. Directories are sorted by the total of their files.
+	SortByTokens
+	// SortByModTime sorts by modification time, newest first. Directories are sorted by their newest file.
+	SortByModTime
+)
+
+var sortModeNames = []string{"name", "size", "tokens", "mtime"}
+
+// SortModeNames returns the names of the sort modes, in the order they are cycled through
+func SortModeNames() []string {
+	return append([]string(nil), sortModeNames...)
+}
+
+// ParseSortMode returns the sort mode with the given name
+func ParseSortMode(name string) (SortMode, error) {
+	for i, modeName := range sortModeNames {
+		if strings.EqualFold(name, modeName) {
+			return SortMode(i), nil
+		}
+	}
+	return SortByName, fmt.Errorf("unknown sort mode %q (available: %s)", name, strings.Join(sortModeNames, ", "))
+}
+
+// String returns the name of the sort mode
+func (s SortMode) String() string {
+	if s < 0 || int(s) >= len(sortModeNames) {
+		return sortModeNames[SortByName]
+	}
+	return sortModeNames[s]
+}
+
+// next returns the sort mode that follows s when cycling through the modes
+func (s SortMode) next() SortMode {
+	return SortMode((int(s) + 1) % len(sortModeNames))
+}
+
+// sortMetrics returns the value each file and directory is sorted by in the given mode.
+// Directories get the total size or tokens of their files, or the time of their newest file.
+func (m *Model) sortMetrics(mode SortMode) map[string]int64 {
+	metrics := make(map[string]int64)
+	if mode == SortByName {
+		return metrics
+	}
+
+	for _, f := range m.files {
+		if f.IsDir {
+			continue
+		}
+		var value int64
+		switch mode {
+		case SortBySize:
+			value = f.Size
+		case SortByTokens:
+			value = utils.EstimateTokensForSize(f.Size)
+		case SortByModTime:
+			value = f.ModTime.UnixNano()
+		}
+		metrics[f.Path] = value
+
+		dir := filepath.Dir(f.Path)
+		for dir != "." && dir != "/" && dir != "" {
+			if mode == SortByModTime {
+				if value > metrics[dir] {
+					metrics[dir] = value
+				}
+			} else {
+				metrics[dir] += value
+			}
+			dir = filepath.Dir(dir)
+		}
+	}
+	return metrics
+}
+
+// sortItems sorts the entries of a directory: directories first, then by the metric of
+// the sort mode in descending order, then by path
+func sortItems(items []filesystem.FileItem, mode SortMode, metrics map[string]int64) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].IsDir != items[j].IsDir {
+			return items[i].IsDir
+		}
+		if mode != SortByName && metrics[items[i].Path] != metrics[items[j].Path] {
+			return metrics[items[i].Path] > metrics[items[j].Path]
+		}
+		return items[i].Path < items[j].Path
+	})
+}
+
+// largestFilesMode returns the sort mode of the largest files view, which sorts by size
+// unless another metric than the name is selected
+func (m Model) largestFilesMode() SortMode {
+	if m.sortMode == SortByName {
+		return SortBySize
+	}
+	return m.sortMode
+}
+
+// buildLargestFilesNodes fills displayNodes with a flat list of all files in the project,
+// sorted by size, tokens or modification time
+func (m *Model) buildLargestFilesNodes() {
+	mode := m.largestFilesMode()
+	metrics := m.sortMetrics(mode)
+
+	var items []filesystem.FileItem
+	for _, f := range m.files {
+		if f.IsDir || (m.changedOnly && m.gitStatus[f.Path] == git.StatusUnmodified) {
+			continue
+		}
+		items = append(items, f)
+	}
+	sortItems(items, mode, metrics)
+
+	for i, item := range items {
+		node := m.newFileNode(item, 0)
+		node.Name = item.Path
+		node.IsLast = i == len(items)-1
+		m.displayNodes = append(m.displayNodes, node)
+	}
+}
+
+// sortMetricSuffix returns the size, tokens or modification time of a file in the largest
+// files view, shown after its name
+func (m Model) sortMetricSuffix(node FileNode) string {
+	switch m.largestFilesMode() {
+	case SortByTokens:
+		return fmt.Sprintf(" [~%d tokens]", utils.EstimateTokensForSize(node.Size))
+	case SortByModTime:
+		return " [" + node.ModTime.Format("2006-01-02 15:04") + "]"
+	default:
+		return " [" + utils.FormatSize(node.Size) + "]"
+	}
+}
+
+// sortIndicator returns the footer label of the sort mode and view, or "" for the default tree
+func (m Model) sortIndicator() string {
+	if m.largestFiles {
+		return "📊 Files by " + m.largestFilesMode().String()
+	}
+	if m.sortMode != SortByName {
+		return "↕ Sorted by " + m.sortMode.String()
+	}
+	return ""
+}
+
+// rebuildKeepingCursor rebuilds the display nodes and moves the cursor back to the node it was on
+func (m *Model) rebuildKeepingCursor() {
+	path := ""
+	if m.cursor >= 0 && m.cursor < len(m.displayNodes) {
+		path = m.displayNodes[m.cursor].Path
+	}
+
+	m.buildDisplayNodes()
+
+	m.cursor = 0
+	for i, node := range m.displayNodes {
+		if node.Path == path {
+			m.cursor = i
+			break
+		}
+	}
+}
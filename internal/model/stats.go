@@ -0,0 +1,83 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/epilande/codegrab/internal/filesystem"
+	"github.com/epilande/codegrab/internal/stats"
+	"github.com/epilande/codegrab/internal/ui"
+)
+
+// statsComputedMsg carries the project statistics computed in the background
+type statsComputedMsg struct {
+	err    error
+	report *stats.Report
+}
+
+// openStats shows the statistics view and starts computing the report
+func (m *Model) openStats() tea.Cmd {
+	m.showStats = true
+	m.viewport.SetContent(ui.GetStyleHelp().Render("Computing statistics..."))
+	m.viewport.GotoTop()
+	return m.computeStats()
+}
+
+// closeStats returns from the statistics view to the file tree
+func (m *Model) closeStats() {
+	m.showStats = false
+	m.refreshViewportContent()
+}
+
+// computeStats returns a command that computes the statistics of the files as currently
+// filtered and selected, including what the filters exclude
+func (m *Model) computeStats() tea.Cmd {
+	root := m.rootPath
+	files := append([]filesystem.FileItem(nil), m.files...)
+	selected := make(map[string]bool)
+	for path, isSelected := range m.selected {
+		if isSelected && !m.deselected[path] {
+			selected[path] = true
+		}
+	}
+	filters := stats.Filters{
+		GitIgnore:    m.gitIgnoreMgr,
+		Filter:       m.filterMgr,
+		UseGitIgnore: m.useGitIgnore,
+		ShowHidden:   m.showHidden,
+		MaxFileSize:  m.maxFileSize,
+	}
+
+	return func() tea.Msg {
+		report := stats.Compute(root, files, selected, stats.DefaultTopFiles)
+		excluded, err := stats.Excluded(root, files, filters)
+		if err != nil {
+			return statsComputedMsg{err: err}
+		}
+		report.Excluded = excluded
+		return statsComputedMsg{report: report}
+	}
+}
+
+// handleStatsComputed shows the computed statistics, unless the view was closed meanwhile
+func (m *Model) handleStatsComputed(msg statsComputedMsg) {
+	if !m.showStats {
+		return
+	}
+
+	var content string
+	if msg.err != nil {
+		content = ui.GetStyleError().Render(fmt.Sprintf("Error computing statistics: %v", msg.err))
+	} else {
+		var b strings.Builder
+		if err := msg.report.WriteTable(&b); err != nil {
+			content = ui.GetStyleError().Render(fmt.Sprintf("Error rendering statistics: %v", err))
+		} else {
+			content = ui.GetStyleHelp().Render(strings.TrimRight(b.String(), "\n"))
+		}
+	}
+	m.viewport.SetContent(content)
+	m.viewport.GotoTop()
+}
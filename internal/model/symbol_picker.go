@@ -0,0 +1,210 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/epilande/codegrab/internal/keymap"
+	"github.com/epilande/codegrab/internal/symbols"
+	"github.com/epilande/codegrab/internal/ui"
+)
+
+// symbolPicker lists the symbols of the previewed file in the preview pane, so that
+// only their lines, or a typed line range, are selected
+type symbolPicker struct {
+	err         error
+	chosen      map[int]bool
+	path        string
+	symbols     []symbols.Symbol
+	rangeInput  textinput.Model
+	cursor      int
+	active      bool
+	typingRange bool
+}
+
+// openSymbolPicker shows the symbol picker for the file in the preview pane. Symbols
+// whose lines are already selected start checked.
+func (m *Model) openSymbolPicker() tea.Cmd {
+	if !m.showPreview || m.currentPreviewPath == "" || m.currentPreviewIsDir {
+		m.warningMsg = fmt.Sprintf("Preview a file (%s) to pick its symbols", m.keyHint(keymap.TogglePreview))
+		return nil
+	}
+
+	path := m.currentPreviewPath
+	content, err := os.ReadFile(filepath.Join(m.rootPath, path))
+	if err != nil {
+		m.err = fmt.Errorf("failed to read %s: %w", path, err)
+		return nil
+	}
+
+	picker := symbolPicker{
+		path:       path,
+		chosen:     make(map[int]bool),
+		rangeInput: textinput.New(),
+		active:     true,
+	}
+	picker.rangeInput.Prompt = "Lines: "
+	picker.rangeInput.Placeholder = "120-180, 200-210"
+	if symbols.Supported(path) {
+		picker.symbols, picker.err = symbols.Find(path, content)
+	}
+	for i, symbol := range picker.symbols {
+		if rangesCover(m.lineRanges[path], symbol.Lines) {
+			picker.chosen[i] = true
+		}
+	}
+	m.picker = picker
+
+	// Without symbols, only a line range can be picked
+	if len(picker.symbols) == 0 {
+		m.picker.typingRange = true
+		return m.picker.rangeInput.Focus()
+	}
+	return nil
+}
+
+// handleSymbolPickerKey handles a key press while the symbol picker is shown
+func (m Model) handleSymbolPickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == keymap.QuitKey {
+		return m, m.quit()
+	}
+
+	if m.picker.typingRange {
+		switch msg.String() {
+		case "esc":
+			if len(m.picker.symbols) == 0 {
+				m.picker = symbolPicker{}
+				return m, nil
+			}
+			m.picker.typingRange = false
+			m.picker.rangeInput.Blur()
+			return m, nil
+		case "enter":
+			var ranges []symbols.LineRange
+			for _, part := range strings.Split(m.picker.rangeInput.Value(), ",") {
+				r, err := symbols.ParseLineRange(part)
+				if err != nil {
+					m.warningMsg = err.Error()
+					return m, nil
+				}
+				ranges = append(ranges, r)
+			}
+			m.applyLineRanges(m.picker.path, ranges)
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.picker.rangeInput, cmd = m.picker.rangeInput.Update(msg)
+		return m, cmd
+	}
+
+	// ':' and enter belong to the picker, the other keys follow the keymap
+	switch msg.String() {
+	case ":":
+		m.picker.typingRange = true
+		return m, m.picker.rangeInput.Focus()
+	case "enter":
+		var ranges []symbols.LineRange
+		for i, symbol := range m.picker.symbols {
+			if m.picker.chosen[i] {
+				ranges = append(ranges, symbol.Lines)
+			}
+		}
+		if len(ranges) == 0 {
+			m.warningMsg = "Check symbols with space, or type a line range after ':'"
+			return m, nil
+		}
+		m.applyLineRanges(m.picker.path, ranges)
+		return m, nil
+	}
+
+	switch m.keymap.Action(msg.String()) {
+	case keymap.Cancel, keymap.Quit, keymap.PickSymbols:
+		m.picker = symbolPicker{}
+	case keymap.CursorDown:
+		if m.picker.cursor < len(m.picker.symbols)-1 {
+			m.picker.cursor++
+		}
+	case keymap.CursorUp:
+		if m.picker.cursor > 0 {
+			m.picker.cursor--
+		}
+	case keymap.ToggleSelect:
+		m.picker.chosen[m.picker.cursor] = !m.picker.chosen[m.picker.cursor]
+	}
+	return m, nil
+}
+
+// applyLineRanges selects only the given lines of a file and closes the picker
+func (m *Model) applyLineRanges(path string, ranges []symbols.LineRange) {
+	before := m.snapshotSelection()
+	merged := symbols.MergeRanges(ranges)
+	m.lineRanges[path] = merged
+	m.selected[path] = true
+	delete(m.deselected, path)
+	m.recordUndo(before)
+
+	m.picker = symbolPicker{}
+	m.buildDisplayNodes()
+	m.refreshViewportContent()
+	m.successMsg = fmt.Sprintf("Selected lines %s of %s", symbols.FormatRanges(merged), path)
+}
+
+// renderSymbolPicker renders the symbols of the picker, one per line, scrolled to
+// keep the cursor visible
+func (m Model) renderSymbolPicker(width, height int) string {
+	var lines []string
+	if m.picker.typingRange {
+		lines = append(lines, m.picker.rangeInput.View())
+	} else {
+		lines = append(lines, ui.GetStyleHelp().Render(fmt.Sprintf("%d symbols, ':' for a line range", len(m.picker.symbols))))
+	}
+	if m.picker.err != nil {
+		lines = append(lines, ui.GetStyleError().Render(m.picker.err.Error()))
+	}
+
+	listHeight := max(height-len(lines), 1)
+	offset := max(m.picker.cursor-listHeight+1, 0)
+	end := min(offset+listHeight, len(m.picker.symbols))
+	for i := offset; i < end; i++ {
+		symbol := m.picker.symbols[i]
+		checkbox := "[ ]"
+		if m.picker.chosen[i] {
+			checkbox = "[x]"
+		}
+		lineRange := " " + symbol.Lines.String()
+		label := fmt.Sprintf("%s %-9s %s", checkbox, symbol.Kind, symbol.Name)
+		label = runewidth.Truncate(label, max(width-len(lineRange), 0), "…")
+		line := label + strings.Repeat(" ", max(width-runewidth.StringWidth(label)-len(lineRange), 0)) + lineRange
+
+		if i == m.picker.cursor && !m.picker.typingRange {
+			line = ui.GetStyleInfo().Bold(true).Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rangesCover reports whether all the lines of r are within ranges
+func rangesCover(ranges []symbols.LineRange, r symbols.LineRange) bool {
+	for _, covering := range ranges {
+		if covering.Start <= r.Start && r.End <= covering.End {
+			return true
+		}
+	}
+	return false
+}
+
+// copyLineRanges returns a copy of the line ranges of the selected files
+func copyLineRanges(lineRanges map[string][]symbols.LineRange) map[string][]symbols.LineRange {
+	copied := make(map[string][]symbols.LineRange, len(lineRanges))
+	for path, ranges := range lineRanges {
+		copied[path] = append([]symbols.LineRange(nil), ranges...)
+	}
+	return copied
+}
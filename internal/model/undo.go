@@ -0,0 +1,98 @@
+package model
+
+import (
+	"reflect"
+
+	"github.com/epilande/codegrab/internal/symbols"
+)
+
+// maxUndoHistory is the number of selection changes that can be undone
+const maxUndoHistory = 100
+
+// selectionSnapshot is a copy of the selection state, recorded before each change
+// to the selection so that it can be undone
+type selectionSnapshot struct {
+	selected     map[string]bool
+	deselected   map[string]bool
+	isDependency map[string]bool
+	lineRanges   map[string][]symbols.LineRange
+}
+
+// snapshotSelection returns a copy of the current selection state
+func (m *Model) snapshotSelection() selectionSnapshot {
+	return selectionSnapshot{
+		selected:     copySelection(m.selected),
+		deselected:   copySelection(m.deselected),
+		isDependency: copySelection(m.isDependency),
+		lineRanges:   copyLineRanges(m.lineRanges),
+	}
+}
+
+// restoreSelection replaces the selection state with a copy of snapshot
+func (m *Model) restoreSelection(snapshot selectionSnapshot) {
+	m.selected = copySelection(snapshot.selected)
+	m.deselected = copySelection(snapshot.deselected)
+	m.isDependency = copySelection(snapshot.isDependency)
+	m.lineRanges = copyLineRanges(snapshot.lineRanges)
+}
+
+// recordUndo pushes the selection state from before a change onto the undo stack, so
+// that the change is undone as one unit, including the dependencies it selected.
+// Nothing is recorded if the selection did not change.
+func (m *Model) recordUndo(before selectionSnapshot) {
+	if before.equal(m.snapshotSelection()) {
+		return
+	}
+	m.undoStack = append(m.undoStack, before)
+	if len(m.undoStack) > maxUndoHistory {
+		m.undoStack = m.undoStack[len(m.undoStack)-maxUndoHistory:]
+	}
+	m.redoStack = nil
+}
+
+// undoSelection restores the selection from before the last change. It returns false
+// if there is nothing to undo.
+func (m *Model) undoSelection() bool {
+	if len(m.undoStack) == 0 {
+		return false
+	}
+	last := len(m.undoStack) - 1
+	m.redoStack = append(m.redoStack, m.snapshotSelection())
+	m.restoreSelection(m.undoStack[last])
+	m.undoStack = m.undoStack[:last]
+	return true
+}
+
+// redoSelection reapplies the last undone change. It returns false if there is
+// nothing to redo.
+func (m *Model) redoSelection() bool {
+	if len(m.redoStack) == 0 {
+		return false
+	}
+	last := len(m.redoStack) - 1
+	m.undoStack = append(m.undoStack, m.snapshotSelection())
+	m.restoreSelection(m.redoStack[last])
+	m.redoStack = m.redoStack[:last]
+	return true
+}
+
+// equal reports whether two snapshots hold the same selection state
+func (s selectionSnapshot) equal(other selectionSnapshot) bool {
+	return equalSelection(s.selected, other.selected) &&
+		equalSelection(s.deselected, other.deselected) &&
+		equalSelection(s.isDependency, other.isDependency) &&
+		reflect.DeepEqual(s.lineRanges, other.lineRanges)
+}
+
+// equalSelection reports whether two selection maps hold the same entries
+func equalSelection(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, value := range a {
+		if other, ok := b[path]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
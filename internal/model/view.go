@@ -8,15 +8,25 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/epilande/codegrab/internal/filesystem"
+	"github.com/epilande/codegrab/internal/keymap"
+	"github.com/epilande/codegrab/internal/symbols"
 	"github.com/epilande/codegrab/internal/ui"
 	"github.com/epilande/codegrab/internal/ui/themes"
 )
 
 // View renders the entire UI model.
 func (m Model) View() string {
-	// If help is shown, render a simple help view.
-	if m.showHelp {
-		header := ui.GetStyleHeader().Render("❔ Help Menu")
+	if m.output.active {
+		return m.viewOutputPreview()
+	}
+
+	// If help or the statistics are shown, render a simple full screen view.
+	if m.showHelp || m.showStats {
+		title := "❔ Help Menu"
+		if m.showStats {
+			title = "📊 Project Statistics"
+		}
+		header := ui.GetStyleHeader().Render(title)
 		headerHeight := lipgloss.Height(header)
 		footerText := "Exit: esc" // Example footer text for height calculation
 		footer := ui.GetStyleHelp().Render(footerText)
@@ -70,7 +80,7 @@ func (m Model) View() string {
 		}
 
 		// Distribute the available inner width between the file tree and preview content
-		fileTreeInnerWidth := int(float64(availableInnerContentWidth) * defaultFileTreePreviewRatio)
+		fileTreeInnerWidth := int(float64(availableInnerContentWidth) * m.treeRatio())
 		previewInnerWidth := availableInnerContentWidth - fileTreeInnerWidth
 
 		// Apply minimum width constraints
@@ -109,13 +119,7 @@ func (m Model) View() string {
 		}
 
 		// Prepare preview header text
-		previewHeaderText := "No file selected"
-		if m.currentPreviewPath != "" {
-			previewHeaderText = "📄 " + m.currentPreviewPath
-			if m.currentPreviewIsDir {
-				previewHeaderText = "📁 " + m.currentPreviewPath
-			}
-		}
+		previewHeaderText := m.previewHeaderText()
 
 		// Set preview header width
 		previewOuterWidth := previewInnerWidth + (2 * ui.BorderSize)
@@ -179,7 +183,7 @@ func (m Model) View() string {
 			renderedFileTreeContent)
 
 		// Render preview content
-		renderedPreviewContent := previewContentBoxStyle.Render(m.previewViewport.View())
+		renderedPreviewContent := previewContentBoxStyle.Render(m.renderPreviewContent(previewInnerWidth))
 
 		// Combine preview header and content
 		renderedPreview := lipgloss.JoinVertical(lipgloss.Left,
@@ -269,7 +273,7 @@ func (m Model) View() string {
 // renderHeader renders the header part of the UI.
 func (m Model) renderHeader() string {
 	headerIcon := "✋"
-	if m.isGrabbing {
+	if m.isGrabbing || m.generation.active {
 		headerIcon = "✊"
 	}
 	leftContent := ui.GetStyleHeader().Render(headerIcon + " Code Grab")
@@ -319,8 +323,8 @@ func (m Model) renderHeader() string {
 
 // renderFooter renders the footer part of the UI.
 func (m Model) renderFooter() string {
-	// If help is shown, the main View() handles a minimal footer
-	if m.showHelp {
+	// If help or the statistics are shown, the main View() handles a minimal footer
+	if m.showHelp || m.showStats {
 		return ""
 	}
 
@@ -329,14 +333,27 @@ func (m Model) renderFooter() string {
 
 	// Left side: Status/Error/Help prompts
 	if m.isSearching {
-		searchHelp := "Next: ctrl+n | Prev: ctrl+p | Select: tab | Exit: esc"
+		searchHelp := "Next: ctrl+n | Prev: ctrl+p | Select: tab | All: ctrl+a | Mode: ctrl+f | Exit: esc"
 		leftParts = append(leftParts, ui.GetStyleHelp().Render(searchHelp))
+	} else if m.picker.active {
+		pickerHelp := fmt.Sprintf("Move: %s/%s | Check: %s | Lines: : | Select: enter | Cancel: %s",
+			m.keyHint(keymap.CursorDown), m.keyHint(keymap.CursorUp), m.keyHint(keymap.ToggleSelect), m.keyHint(keymap.Cancel))
+		leftParts = append(leftParts, ui.GetStyleInfo().Render(pickerHelp))
+	} else if m.visualMode {
+		visualHelp := fmt.Sprintf("-- VISUAL -- Extend: %s/%s | Select: %s | Cancel: %s",
+			m.keyHint(keymap.CursorDown), m.keyHint(keymap.CursorUp), m.keyHint(keymap.ToggleSelect), m.keyHint(keymap.Cancel))
+		leftParts = append(leftParts, ui.GetStyleInfo().Render(visualHelp))
+	} else if m.generation.active {
+		leftParts = append(leftParts, m.renderGenerationProgress())
+	} else if m.walkFound > 0 {
+		leftParts = append(leftParts, m.renderWalkProgress())
 	} else if m.err != nil {
 		leftParts = append(leftParts, ui.GetStyleError().Render(m.err.Error()))
 	} else if m.successMsg != "" {
 		leftParts = append(leftParts, ui.GetStyleSuccess().Render(m.successMsg))
 	} else {
-		helpText := "Press '?' for help | Select: space | Generate: ctrl+g | Copy: y"
+		helpText := fmt.Sprintf("Press '%s' for help | Select: %s | Generate: %s | Copy: %s",
+			m.keyHint(keymap.Help), m.keyHint(keymap.ToggleSelect), m.keyHint(keymap.Generate), m.keyHint(keymap.Copy))
 		leftParts = append(leftParts, ui.GetStyleHelp().Render(helpText))
 	}
 
@@ -354,6 +371,11 @@ func (m Model) renderFooter() string {
 		rightParts = append(rightParts, ui.GetStyleInfo().Render(" | 🔗 Deps"))
 	}
 
+	// Sort mode and largest files view
+	if indicator := m.sortIndicator(); indicator != "" {
+		rightParts = append(rightParts, ui.GetStyleInfo().Render(" | "+indicator))
+	}
+
 	leftContent := lipgloss.JoinHorizontal(lipgloss.Top, leftParts...)
 	rightContent := lipgloss.JoinHorizontal(lipgloss.Top, rightParts...)
 
@@ -457,6 +479,11 @@ func (m *Model) refreshViewportContent() {
 		}
 	}
 
+	rangeStart, rangeEnd := -1, -1
+	if m.visualMode {
+		rangeStart, rangeEnd = m.visualRange()
+	}
+
 	var lines []string
 	parentIsLast := make(map[int]bool) // Tracks if parent at a certain level is the last child
 
@@ -505,7 +532,9 @@ func (m *Model) refreshViewportContent() {
 				rawCheckbox = "[~]" // Directory partially selected
 			}
 		} else {
-			if node.Selected {
+			if node.Selected && len(m.lineRanges[node.Path]) > 0 {
+				rawCheckbox = "[~]" // Only some lines selected
+			} else if node.Selected {
 				rawCheckbox = "[x]"
 			}
 		}
@@ -524,6 +553,17 @@ func (m *Model) refreshViewportContent() {
 			if node.IsDependency {
 				rawSuffix += " [dep]"
 			}
+			if ranges := m.lineRanges[node.Path]; node.Selected && len(ranges) > 0 {
+				rawSuffix += fmt.Sprintf(" [lines %s]", symbols.FormatRanges(ranges))
+			}
+			if m.isSearching && m.searchMode == contentSearch {
+				if match, ok := m.grepMatches[node.Path]; ok {
+					rawSuffix += formatGrepSuffix(match)
+				}
+			}
+			if m.largestFiles {
+				rawSuffix += m.sortMetricSuffix(node)
+			}
 			if m.showTokenCount {
 				// Use cached tokens for non-blocking UI rendering
 				tokensFormatted := m.tokenCache.GetTokensFormatted(node.Path)
@@ -531,6 +571,7 @@ func (m *Model) refreshViewportContent() {
 			}
 		}
 		isCursorLine := i == m.cursor
+		isInRange := m.visualMode && !m.isSearching && i >= rangeStart && i <= rangeEnd
 
 		// Render the full line with proper left padding applied
 		rendered := ui.StyleFileLine(
@@ -539,10 +580,12 @@ func (m *Model) refreshViewportContent() {
 			icon,
 			iconColor,
 			rawName,
+			node.GitStatus,
 			rawSuffix,
 			node.IsDir,
 			m.selected[node.Path] || isPartialDir,
 			isCursorLine,
+			isInRange,
 			isPartialDir,
 			m.viewport.Width,
 		)
@@ -566,7 +609,7 @@ func (m *Model) ensureCursorVisible() {
 
 // showHelpScreen prepares and displays the help content in the main viewport.
 func (m *Model) showHelpScreen() {
-	helpContent := ui.GetStyleHelp().Render(ui.HelpText + "\n\nPress '?' or 'esc' to close this help menu.")
+	helpContent := ui.GetStyleHelp().Render(ui.HelpText(m.keymap) + "\n\nPress '?' or 'esc' to close this help menu.")
 	m.viewport.SetContent(helpContent)
 	m.viewport.GotoTop() // Reset scroll to the top when help is shown
 }
@@ -582,6 +625,15 @@ func (m *Model) getTotalFileCount() int {
 	return count
 }
 
+// keyHint returns the first key bound to an action, for the hints in the footer
+func (m Model) keyHint(action keymap.Action) string {
+	keys := m.keymap.Keys(action)
+	if len(keys) == 0 {
+		return "unbound"
+	}
+	return keymap.DisplayKey(keys[0])
+}
+
 // getSelectedFileCount calculates the effective number of selected files.
 func (m *Model) getSelectedFileCount() int {
 	effectiveSelection := make(map[string]bool)
@@ -177,7 +177,10 @@ func TestRefreshViewportContent(t *testing.T) {
 
 func TestView(t *testing.T) {
 	// Create a minimal model for testing View()
-	gen := generator.NewGenerator(".", nil, nil, "", false)
+	gen, err := generator.NewGenerator(".", nil, nil, "", false)
+	if err != nil {
+		t.Fatalf("NewGenerator returned an error: %v", err)
+	}
 	format := formats.GetFormat("markdown")
 	gen.SetFormat(format)
 
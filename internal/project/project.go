@@ -0,0 +1,554 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/epilande/codegrab/internal/cache"
+	"github.com/epilande/codegrab/internal/dependencies"
+	"github.com/epilande/codegrab/internal/filesystem"
+	"github.com/epilande/codegrab/internal/generator"
+	"github.com/epilande/codegrab/internal/generator/formats"
+	"github.com/epilande/codegrab/internal/secrets"
+	"github.com/epilande/codegrab/internal/symbols"
+	"github.com/epilande/codegrab/internal/utils"
+)
+
+// Project gives the servers access to the files of a project directory. It is safe
+// for concurrent use.
+type Project struct {
+	Scanner       secrets.Scanner
+	Root          string
+	ModuleName    string
+	MaxFileSize   int64
+	RedactSecrets bool
+}
+
+// New returns the project at root. Secrets are redacted from everything it returns
+// unless redactSecrets is false.
+func New(root string, redactSecrets bool) (*Project, error) {
+	scanner, err := secrets.NewGitleaksScanner()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize secret scanner: %w", err)
+	}
+
+	return &Project{
+		Scanner:       scanner,
+		Root:          root,
+		ModuleName:    dependencies.ReadGoModFile(root),
+		MaxFileSize:   math.MaxInt64,
+		RedactSecrets: redactSecrets,
+	}, nil
+}
+
+// Filters select the files below a path of the project
+type Filters struct {
+	Path           string   `json:"path"`
+	MaxFileSize    string   `json:"max_file_size"`
+	Glob           []string `json:"glob"`
+	IncludeHidden  bool     `json:"include_hidden"`
+	IncludeIgnored bool     `json:"include_ignored"`
+}
+
+// RelPath converts a path into a path relative to the project root, rejecting paths
+// outside of it, including symlinks that point outside of it. An empty path is the
+// project root.
+func (p *Project) RelPath(path string) (string, error) {
+	if path == "" || path == "." {
+		return ".", nil
+	}
+	fullPath := path
+	if !filepath.IsAbs(fullPath) {
+		fullPath = filepath.Join(p.Root, path)
+	}
+	fullPath = filepath.Clean(fullPath)
+	rel, err := filepath.Rel(p.Root, fullPath)
+	if err != nil || isOutside(rel) {
+		return "", fmt.Errorf("path %q is outside of the project", path)
+	}
+
+	if resolved, err := filepath.EvalSymlinks(fullPath); err == nil {
+		root := p.Root
+		if resolvedRoot, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolvedRoot
+		}
+		if target, err := filepath.Rel(root, resolved); err != nil || isOutside(target) {
+			return "", fmt.Errorf("path %q is outside of the project", path)
+		}
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// isOutside reports whether a relative path leads out of the directory it is relative to
+func isOutside(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// maxFileSize returns the size limit of filters, or of the project if they set none
+func (p *Project) maxFileSize(filters Filters) (int64, error) {
+	if filters.MaxFileSize == "" {
+		return p.MaxFileSize, nil
+	}
+	maxFileSize, err := utils.ParseSizeString(filters.MaxFileSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max file size: %w", err)
+	}
+	return maxFileSize, nil
+}
+
+// Walk returns the files and directories below the path of filters that pass them,
+// sorted by path
+func (p *Project) Walk(ctx context.Context, filters Filters) ([]filesystem.FileItem, error) {
+	dir, err := p.RelPath(filters.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	maxFileSize, err := p.maxFileSize(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	gitIgnoreMgr, err := filesystem.NewGitIgnoreManager(p.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+	filterMgr := filesystem.NewFilterManager()
+	for _, pattern := range filters.Glob {
+		if normalizedPattern, isValid := utils.NormalizeGlobPattern(pattern, p.Root); isValid {
+			filterMgr.AddGlobPattern(normalizedPattern)
+		}
+	}
+
+	files, err := filesystem.WalkDirectory(ctx, p.Root, gitIgnoreMgr, filterMgr, !filters.IncludeIgnored, filters.IncludeHidden, maxFileSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk the project: %w", err)
+	}
+
+	var matched []filesystem.FileItem
+	for _, f := range files {
+		if dir == "." || f.Path == dir || strings.HasPrefix(f.Path, dir+"/") {
+			matched = append(matched, f)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].Path < matched[j].Path
+	})
+	return matched, nil
+}
+
+// ReadFile returns the relative path and the content of a text file, with its secrets
+// redacted. Hidden, gitignored and oversized files cannot be read, as with --files-from.
+func (p *Project) ReadFile(path string) (string, string, error) {
+	rel, err := p.RelPath(path)
+	if err != nil {
+		return "", "", err
+	}
+	fullPath := filepath.Join(p.Root, rel)
+
+	gitIgnoreMgr, err := filesystem.NewGitIgnoreManager(p.Root)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+	_, skipped, err := filesystem.ValidateFileList(context.Background(), p.Root, []string{rel}, gitIgnoreMgr, true, false, p.MaxFileSize)
+	if err != nil {
+		return "", "", err
+	}
+	if len(skipped) > 0 {
+		return "", "", fmt.Errorf("cannot read %s: %s", rel, skipped[0].Reason)
+	}
+
+	fileCache := cache.GetGlobalFileCache()
+	if isText, err := fileCache.GetTextFileStatus(fullPath, utils.IsTextFile); err != nil || !isText {
+		return "", "", fmt.Errorf("%s is not a text file", rel)
+	}
+	text, err := fileCache.Get(fullPath)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot read %s: %w", rel, err)
+	}
+	return rel, p.Redact(text), nil
+}
+
+// Redact returns text with its secrets redacted, unless redaction is disabled
+func (p *Project) Redact(text string) string {
+	if !p.RedactSecrets {
+		return text
+	}
+	findings, err := p.Scanner.Scan(text)
+	if err != nil || len(findings) == 0 {
+		return text
+	}
+	return p.Scanner.Redact(text, findings)
+}
+
+// Warm loads the text files of the project into the file cache, so that later requests
+// read them from memory. It returns the number of files loaded.
+func (p *Project) Warm(ctx context.Context) (int, error) {
+	files, err := p.Walk(ctx, Filters{})
+	if err != nil {
+		return 0, err
+	}
+
+	fileCache := cache.GetGlobalFileCache()
+	var loaded int
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return loaded, err
+		}
+		if f.IsDir {
+			continue
+		}
+		fullPath := filepath.Join(p.Root, f.Path)
+		if isText, err := fileCache.GetTextFileStatus(fullPath, utils.IsTextFile); err != nil || !isText {
+			continue
+		}
+		if _, err := fileCache.Get(fullPath); err == nil {
+			loaded++
+		}
+	}
+	return loaded, nil
+}
+
+// Dependency is a file a resolved file depends on
+type Dependency struct {
+	Path       string `json:"path"`
+	RequiredBy string `json:"required_by"`
+	Depth      int    `json:"depth"`
+}
+
+// DependencyRules are the rules a dependency must pass to be added, the same as the
+// files selected along with it
+type DependencyRules struct {
+	GitIgnore    *filesystem.GitIgnoreManager
+	Filter       *filesystem.FilterManager
+	MaxFileSize  int64
+	UseGitIgnore bool
+	ShowHidden   bool
+}
+
+// allows reports whether a dependency, relative to root, passes the rules
+func (r DependencyRules) allows(root, path string) bool {
+	fullPath := filepath.Join(root, path)
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return (!r.UseGitIgnore || r.GitIgnore == nil || !r.GitIgnore.IsIgnored(fullPath)) &&
+		(r.ShowHidden || !utils.IsHiddenPath(path)) &&
+		info.Size() <= r.MaxFileSize &&
+		(r.Filter == nil || r.Filter.ShouldInclude(path))
+}
+
+// ResolveDependencies resolves the dependencies of paths with resolver, one level at a
+// time up to maxDepth levels (-1 for all), in the order they are found. Dependencies
+// that are missing, directories or break the rules are left out, as are the paths
+// themselves. Files that cannot be resolved are passed to onError, if it is not nil.
+func ResolveDependencies(ctx context.Context, resolver *dependencies.ConcurrentResolver, root string, paths []string, maxDepth int, rules DependencyRules, onError func(path string, err error)) ([]Dependency, error) {
+	if maxDepth < 0 {
+		maxDepth = math.MaxInt
+	}
+
+	processed := make(map[string]bool)
+	for _, path := range paths {
+		processed[path] = true
+	}
+
+	var deps []Dependency
+	level := paths
+	for depth := 0; depth < maxDepth && len(level) > 0; depth++ {
+		results := resolver.ResolveAll(ctx, level)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var next []string
+		for _, result := range results {
+			if result.Err != nil {
+				if onError != nil {
+					onError(result.Path, result.Err)
+				}
+				continue
+			}
+			for _, depPath := range result.Deps {
+				if processed[depPath] || !rules.allows(root, depPath) {
+					continue
+				}
+				processed[depPath] = true
+				deps = append(deps, Dependency{Path: depPath, RequiredBy: result.Path, Depth: depth + 1})
+				next = append(next, depPath)
+			}
+		}
+		level = next
+	}
+	return deps, nil
+}
+
+// Dependencies resolves the dependencies of paths up to maxDepth levels (-1 for all),
+// leaving out the files that filters exclude. The path and glob of filters are not
+// used, a dependency may be of any type and anywhere in the project.
+func (p *Project) Dependencies(ctx context.Context, paths []string, maxDepth int, filters Filters) ([]Dependency, error) {
+	maxFileSize, err := p.maxFileSize(filters)
+	if err != nil {
+		return nil, err
+	}
+	gitIgnoreMgr, err := filesystem.NewGitIgnoreManager(p.Root)
+	if err != nil {
+		gitIgnoreMgr = nil
+	}
+
+	rules := DependencyRules{
+		GitIgnore:    gitIgnoreMgr,
+		MaxFileSize:  maxFileSize,
+		UseGitIgnore: !filters.IncludeIgnored,
+		ShowHidden:   filters.IncludeHidden,
+	}
+	resolver := dependencies.NewConcurrentResolver(p.Root, p.ModuleName)
+	return ResolveDependencies(ctx, resolver, p.Root, paths, maxDepth, rules, nil)
+}
+
+// FileDependencies returns the relative path of a file and its dependencies
+func (p *Project) FileDependencies(ctx context.Context, path string, maxDepth int) (string, []Dependency, error) {
+	rel, err := p.RelPath(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if info, err := os.Stat(filepath.Join(p.Root, rel)); err != nil || info.IsDir() {
+		return "", nil, fmt.Errorf("%s is not a file of the project", path)
+	}
+	if dependencies.GetResolver(rel) == nil {
+		return "", nil, fmt.Errorf("dependency resolution is not supported for %s (Go, JS/TS and Python only)", rel)
+	}
+
+	deps, err := p.Dependencies(ctx, []string{rel}, maxDepth, Filters{})
+	if err != nil {
+		return "", nil, err
+	}
+	return rel, deps, nil
+}
+
+// Secret is a secret found in a file of the project
+type Secret struct {
+	Path   string `json:"path"`
+	RuleID string `json:"rule_id"`
+	Match  string `json:"match"`
+	Line   int    `json:"line,omitempty"`
+}
+
+// ScanSecrets returns the secrets found in the files that pass filters. Their matches
+// are redacted, unless redaction is disabled.
+func (p *Project) ScanSecrets(ctx context.Context, filters Filters) ([]Secret, error) {
+	files, err := p.Walk(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	fileCache := cache.GetGlobalFileCache()
+	var found []Secret
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if f.IsDir {
+			continue
+		}
+		fullPath := filepath.Join(p.Root, f.Path)
+		if isText, err := fileCache.GetTextFileStatus(fullPath, utils.IsTextFile); err != nil || !isText {
+			continue
+		}
+		text, err := fileCache.Get(fullPath)
+		if err != nil {
+			continue
+		}
+		findings, err := p.Scanner.Scan(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", f.Path, err)
+		}
+		for _, finding := range findings {
+			// Rules often need the context around a match to find it again, so the
+			// match is redacted with the finding itself rather than scanned on its own
+			match := finding.Match
+			if p.RedactSecrets {
+				match = p.Scanner.Redact(match, []secrets.Finding{finding})
+			}
+			// A finding stands for every occurrence of its match, each reported on
+			// its own line. Without an occurrence the line is unknown.
+			lines := matchLines(text, finding.Match)
+			if len(lines) == 0 {
+				lines = []int{0}
+			}
+			for _, line := range lines {
+				found = append(found, Secret{Path: f.Path, RuleID: finding.RuleID, Match: match, Line: line})
+			}
+		}
+	}
+	return found, nil
+}
+
+// matchLines returns the line of each occurrence of match in text, counted from 1
+func matchLines(text, match string) []int {
+	if match == "" {
+		return nil
+	}
+	var lines []int
+	line, offset := 1, 0
+	for {
+		index := strings.Index(text[offset:], match)
+		if index < 0 {
+			return lines
+		}
+		line += strings.Count(text[offset:offset+index], "\n")
+		lines = append(lines, line)
+		line += strings.Count(match, "\n")
+		offset += index + len(match)
+	}
+}
+
+// BundleOptions select the files of a bundle and how it is rendered
+type BundleOptions struct {
+	Filters
+	Format      string   `json:"format"`
+	Paths       []string `json:"paths"`
+	TokenBudget int      `json:"token_budget"`
+	MaxDepth    int      `json:"max_depth"`
+	Deps        bool     `json:"deps"`
+}
+
+// DefaultBundleOptions returns the options of a markdown bundle of the whole project
+func DefaultBundleOptions() BundleOptions {
+	return BundleOptions{Format: "markdown", MaxDepth: 1}
+}
+
+// Bundle is the rendered output of a set of files
+type Bundle struct {
+	Output  string
+	Format  string
+	Files   []generator.FileTokenCount
+	Omitted []string
+	Tokens  int
+	Secrets int
+}
+
+// Bundle renders the selected files with the generator, leaving out the largest files
+// until the output fits the token budget
+func (p *Project) Bundle(ctx context.Context, opts BundleOptions) (*Bundle, error) {
+	if !formats.IsRegistered(opts.Format) {
+		return nil, fmt.Errorf("unknown format %q (available: %s)", opts.Format, strings.Join(formats.GetFormatNames(), ", "))
+	}
+
+	selected := make(map[string]bool)
+	entries := opts.Paths
+	if len(entries) == 0 {
+		entries = []string{opts.Path}
+	}
+	// Files may be narrowed to line ranges or symbols, as with --files-from
+	targets, lineRanges, skipped := symbols.ResolveEntries(p.Root, entries)
+	if len(skipped) > 0 {
+		return nil, fmt.Errorf("invalid path %s: %s", skipped[0].Entry, skipped[0].Reason)
+	}
+	for _, target := range targets {
+		filters := opts.Filters
+		filters.Path = target
+		files, err := p.Walk(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if !f.IsDir {
+				selected[f.Path] = true
+			}
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no files match the given paths and filters")
+	}
+
+	if opts.Deps {
+		paths := make([]string, 0, len(selected))
+		for path := range selected {
+			paths = append(paths, path)
+		}
+		deps, err := p.Dependencies(ctx, paths, opts.MaxDepth, opts.Filters)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range deps {
+			selected[dep.Path] = true
+		}
+	}
+
+	gitIgnoreMgr, err := filesystem.NewGitIgnoreManager(p.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+	gen, err := generator.NewGenerator(p.Root, gitIgnoreMgr, filesystem.NewFilterManager(), "", false)
+	if err != nil {
+		return nil, err
+	}
+	gen.SetFormatFunc(formats.Constructor(opts.Format))
+	gen.SetRedactionMode(p.RedactSecrets)
+	gen.SecretScanner = p.Scanner
+	gen.UseGitIgnore = !opts.IncludeIgnored
+	gen.ShowHidden = opts.IncludeHidden
+
+	bundle := &Bundle{Format: opts.Format}
+	gen.LineRanges = lineRanges
+
+	render := func() error {
+		gen.SelectedFiles = copySelection(selected)
+		output, tokens, secretCount, err := gen.GenerateString(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to generate bundle: %w", err)
+		}
+		bundle.Output, bundle.Tokens, bundle.Secrets = output, tokens, secretCount
+		bundle.Files = gen.FileTokenCounts()
+		return nil
+	}
+
+	if err := render(); err != nil {
+		return nil, err
+	}
+
+	for opts.TokenBudget > 0 && bundle.Tokens > opts.TokenBudget {
+		counts := append([]generator.FileTokenCount(nil), bundle.Files...)
+		if len(counts) <= 1 {
+			return nil, fmt.Errorf("the bundle needs ~%d tokens, more than the budget of %d even with a single file", bundle.Tokens, opts.TokenBudget)
+		}
+
+		// Leave out the largest files until enough tokens are freed, keeping at least one file
+		sort.SliceStable(counts, func(i, j int) bool {
+			return counts[i].Tokens > counts[j].Tokens
+		})
+		excess := bundle.Tokens - opts.TokenBudget
+		for _, count := range counts[:len(counts)-1] {
+			delete(selected, count.Path)
+			bundle.Omitted = append(bundle.Omitted, count.Path)
+			excess -= count.Tokens
+			if excess <= 0 {
+				break
+			}
+		}
+
+		if err := render(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(bundle.Omitted)
+	return bundle, nil
+}
+
+func copySelection(selected map[string]bool) map[string]bool {
+	copied := make(map[string]bool, len(selected))
+	for path, isSelected := range selected {
+		copied[path] = isSelected
+	}
+	return copied
+}
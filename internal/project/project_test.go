@@ -0,0 +1,241 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/epilande/codegrab/internal/secrets"
+)
+
+func TestRelPath(t *testing.T) {
+	root := t.TempDir()
+	p := &Project{Root: root}
+
+	tests := []struct {
+		path     string
+		expected string
+		wantErr  bool
+	}{
+		{path: "", expected: "."},
+		{path: "src/main.go", expected: "src/main.go"},
+		{path: "./src/../main.go", expected: "main.go"},
+		{path: filepath.Join(root, "lib", "a.go"), expected: "lib/a.go"},
+		{path: "../outside.go", wantErr: true},
+		{path: "/etc/passwd", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := p.RelPath(tt.path)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("RelPath(%q): expected an error, got %q", tt.path, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("RelPath(%q): expected %q, got %q (err: %v)", tt.path, tt.expected, got, err)
+		}
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	p, err := New(root, true)
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := p.Walk(ctx, Filters{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Walk: expected context.Canceled, got %v", err)
+	}
+	if _, err := p.Dependencies(ctx, []string{"main.go"}, -1, Filters{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Dependencies: expected context.Canceled, got %v", err)
+	}
+	if _, err := p.Bundle(ctx, DefaultBundleOptions()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Bundle: expected context.Canceled, got %v", err)
+	}
+}
+
+// contextScanner finds a password only with its key in front of it, as many gitleaks
+// rules need the context of a secret to find it
+type contextScanner struct{}
+
+func (contextScanner) Scan(content string) ([]secrets.Finding, error) {
+	if !strings.Contains(content, `password = "hunter2"`) {
+		return nil, nil
+	}
+	return []secrets.Finding{{RuleID: "password", Match: `"hunter2"`, Secret: "hunter2"}}, nil
+}
+
+func (contextScanner) Redact(content string, findings []secrets.Finding) string {
+	for _, f := range findings {
+		content = strings.ReplaceAll(content, f.Secret, "[REDACTED_"+f.RuleID+"]")
+	}
+	return content
+}
+
+func TestScanSecretsRedactsMatches(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "config.py"), []byte("password = \"hunter2\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	p := &Project{Root: root, Scanner: contextScanner{}, MaxFileSize: math.MaxInt64, RedactSecrets: true}
+
+	found, err := p.ScanSecrets(context.Background(), Filters{})
+	if err != nil {
+		t.Fatalf("ScanSecrets returned an error: %v", err)
+	}
+	if len(found) != 1 || found[0].Line != 1 {
+		t.Fatalf("Expected one secret on line 1, got %+v", found)
+	}
+	if strings.Contains(found[0].Match, "hunter2") {
+		t.Errorf("Expected the secret to be redacted from the match, got %q", found[0].Match)
+	}
+}
+
+func TestScanSecretsLines(t *testing.T) {
+	root := t.TempDir()
+	content := "password = \"hunter2\"\n\nretry = \"hunter2\"\n"
+	if err := os.WriteFile(filepath.Join(root, "config.py"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "data.bin"), []byte("password = \"hunter2\"\x00\x01"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	p := &Project{Root: root, Scanner: contextScanner{}, MaxFileSize: math.MaxInt64, RedactSecrets: true}
+
+	found, err := p.ScanSecrets(context.Background(), Filters{})
+	if err != nil {
+		t.Fatalf("ScanSecrets returned an error: %v", err)
+	}
+	if len(found) != 2 || found[0].Path != "config.py" || found[0].Line != 1 || found[1].Line != 3 {
+		t.Errorf("Expected the secret on lines 1 and 3 of config.py only, got %+v", found)
+	}
+}
+
+func TestReadFileFilters(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	files := map[string]string{
+		"main.go":     "package main\n",
+		".env":        "TOKEN=1\n",
+		".git/config": "[core]\n",
+		".gitignore":  "ignored.txt\n",
+		"ignored.txt": "ignored\n",
+		"large.txt":   strings.Repeat("x", 100),
+		outside:       "outside\n",
+	}
+	for name, content := range files {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, name)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link.txt")); err != nil {
+		t.Skipf("Symlinks are not supported: %v", err)
+	}
+
+	p, err := New(root, true)
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+	p.MaxFileSize = 50
+
+	if _, content, err := p.ReadFile("main.go"); err != nil || content != "package main\n" {
+		t.Errorf("ReadFile(main.go) = %q, %v", content, err)
+	}
+	for _, path := range []string{".env", ".git/config", "ignored.txt", "large.txt", "link.txt", "."} {
+		if _, content, err := p.ReadFile(path); err == nil {
+			t.Errorf("ReadFile(%s): expected an error, got %q", path, content)
+		}
+	}
+	if _, err := p.RelPath("link.txt"); err == nil {
+		t.Errorf("RelPath(link.txt): expected a symlink out of the project to be rejected")
+	}
+}
+
+func TestBundleLineRanges(t *testing.T) {
+	root := t.TempDir()
+	content := "package main\n\nfunc main() {\n}\n\nfunc helper() {\n}\n"
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	p, err := New(root, true)
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+
+	opts := DefaultBundleOptions()
+	opts.Paths = []string{"main.go#helper"}
+	bundle, err := p.Bundle(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Bundle returned an error: %v", err)
+	}
+	if !strings.Contains(bundle.Output, "(partial: lines 6-7)") || strings.Contains(bundle.Output, "func main") {
+		t.Errorf("Expected only the lines of helper in the bundle, got:\n%s", bundle.Output)
+	}
+
+	opts.Paths = []string{"main.go#missing"}
+	if _, err := p.Bundle(context.Background(), opts); err == nil || !strings.Contains(err.Error(), "main.go#missing") {
+		t.Errorf("Expected an error naming the unknown symbol, got %v", err)
+	}
+}
+
+func TestDependenciesSizeLimit(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":         "module example.com/demo\n\ngo 1.23\n",
+		"main.go":        "package main\n\nimport (\n\t\"example.com/demo/big\"\n\t\"example.com/demo/small\"\n)\n\nfunc main() { big.Run(); small.Run() }\n",
+		"big/big.go":     "package big\n\nfunc Run() {}\n\n// " + strings.Repeat("x", 2048) + "\n",
+		"small/small.go": "package small\n\nfunc Run() {}\n",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	p, err := New(root, true)
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+
+	deps, err := p.Dependencies(context.Background(), []string{"main.go"}, 1, Filters{MaxFileSize: "1kb"})
+	if err != nil {
+		t.Fatalf("Dependencies returned an error: %v", err)
+	}
+	if len(deps) != 1 || deps[0].Path != "small/small.go" {
+		t.Errorf("Expected only small/small.go as a dependency, got %+v", deps)
+	}
+
+	opts := DefaultBundleOptions()
+	opts.Paths = []string{"main.go"}
+	opts.Deps = true
+	opts.MaxFileSize = "1kb"
+	bundle, err := p.Bundle(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Bundle returned an error: %v", err)
+	}
+	if strings.Contains(bundle.Output, "big/big.go") || !strings.Contains(bundle.Output, "small/small.go") {
+		t.Errorf("Expected the bundle to leave out the large dependency, got:\n%s", bundle.Output)
+	}
+}
@@ -15,7 +15,8 @@ type Finding struct {
 	Secret string
 }
 
-// Scanner defines the interface for secret scanning operations.
+// Scanner defines the interface for secret scanning operations. Redact should keep
+// the line breaks of the secrets so the lines of the content keep their numbers.
 type Scanner interface {
 	Scan(content string) ([]Finding, error)
 	Redact(content string, findings []Finding) string
@@ -77,6 +78,7 @@ func (s *GitleaksScanner) Scan(content string) ([]Finding, error) {
 }
 
 // Redact replaces *only the Secret part* within each occurrence of a Match string.
+// The placeholder of a secret spanning several lines is followed by its line breaks.
 func (s *GitleaksScanner) Redact(content string, findings []Finding) string {
 	if len(findings) == 0 {
 		return content
@@ -95,7 +97,7 @@ func (s *GitleaksScanner) Redact(content string, findings []Finding) string {
 	}
 
 	for _, f := range uniqueReplacements {
-		placeholder := fmt.Sprintf("[REDACTED_%s]", f.RuleID)
+		placeholder := fmt.Sprintf("[REDACTED_%s]", f.RuleID) + strings.Repeat("\n", strings.Count(f.Secret, "\n"))
 
 		startIndex := 0
 		for {
@@ -149,3 +151,28 @@ func shorten(s string, maxLen int) string {
 	}
 	return s[:maxLen-3] + "..."
 }
+
+// MultiScanner combines several scanners: it reports the findings of all of them,
+// and lets each of them redact the content in turn.
+type MultiScanner []Scanner
+
+// Scan returns the findings of all scanners, in order.
+func (m MultiScanner) Scan(content string) ([]Finding, error) {
+	var findings []Finding
+	for _, scanner := range m {
+		found, err := scanner.Scan(content)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, found...)
+	}
+	return findings, nil
+}
+
+// Redact passes the content through the Redact method of each scanner.
+func (m MultiScanner) Redact(content string, findings []Finding) string {
+	for _, scanner := range m {
+		content = scanner.Redact(content, findings)
+	}
+	return content
+}
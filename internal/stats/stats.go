@@ -0,0 +1,276 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/epilande/codegrab/internal/filesystem"
+	"github.com/epilande/codegrab/internal/generator"
+	"github.com/epilande/codegrab/internal/utils"
+)
+
+// DefaultTopFiles is the number of largest files listed in a report by default
+const DefaultTopFiles = 10
+
+// rootDirectory is the name of the group of files at the top level of the project
+const rootDirectory = "."
+
+// Reasons for which the filters exclude a file, in the order they are checked
+const (
+	ExcludedHidden    = "hidden"
+	ExcludedGitIgnore = "gitignore"
+	ExcludedGlob      = "glob"
+	ExcludedSize      = "size"
+	ExcludedOther     = "other"
+)
+
+// Bucket sums up a group of files
+type Bucket struct {
+	Name   string `json:"name"`
+	Files  int    `json:"files"`
+	Bytes  int64  `json:"bytes"`
+	Tokens int64  `json:"tokens"`
+}
+
+// FileStat describes a single file of a report
+type FileStat struct {
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Bytes    int64  `json:"bytes"`
+	Tokens   int64  `json:"tokens"`
+}
+
+// Report holds the statistics of the files of a project. Tokens are estimated from
+// the size of the files.
+type Report struct {
+	Root        string     `json:"root"`
+	Total       Bucket     `json:"total"`
+	Selected    Bucket     `json:"selected"`
+	Unselected  Bucket     `json:"unselected"`
+	Languages   []Bucket   `json:"languages"`
+	Directories []Bucket   `json:"directories"`
+	Largest     []FileStat `json:"largest"`
+	Excluded    []Bucket   `json:"excluded"`
+}
+
+// Filters are the filters the files of a report were walked with
+type Filters struct {
+	GitIgnore    *filesystem.GitIgnoreManager
+	Filter       *filesystem.FilterManager
+	UseGitIgnore bool
+	ShowHidden   bool
+	MaxFileSize  int64
+}
+
+// add counts a file in the bucket
+func (b *Bucket) add(size int64) {
+	b.Files++
+	b.Bytes += size
+	b.Tokens += utils.EstimateTokensForSize(size)
+}
+
+// Compute builds the report of the files of a project, as walked by filesystem.WalkDirectory.
+// Files in selected count as selected, the others as unselected. The top largest files are listed.
+func Compute(root string, files []filesystem.FileItem, selected map[string]bool, top int) *Report {
+	report := &Report{
+		Root:       root,
+		Total:      Bucket{Name: "total"},
+		Selected:   Bucket{Name: "selected"},
+		Unselected: Bucket{Name: "unselected"},
+	}
+
+	languages := make(map[string]*Bucket)
+	directories := make(map[string]*Bucket)
+	var largest []FileStat
+
+	for _, f := range files {
+		if f.IsDir {
+			continue
+		}
+
+		report.Total.add(f.Size)
+		if selected[f.Path] {
+			report.Selected.add(f.Size)
+		} else {
+			report.Unselected.add(f.Size)
+		}
+
+		language := generator.DetermineLanguage(f.Path)
+		bucketFor(languages, language).add(f.Size)
+		bucketFor(directories, topLevelDirectory(f.Path)).add(f.Size)
+
+		largest = append(largest, FileStat{
+			Path:     f.Path,
+			Language: language,
+			Bytes:    f.Size,
+			Tokens:   utils.EstimateTokensForSize(f.Size),
+		})
+	}
+
+	report.Languages = sortedBuckets(languages)
+	report.Directories = sortedBuckets(directories)
+
+	sort.Slice(largest, func(i, j int) bool {
+		if largest[i].Bytes != largest[j].Bytes {
+			return largest[i].Bytes > largest[j].Bytes
+		}
+		return largest[i].Path < largest[j].Path
+	})
+	if top >= 0 && len(largest) > top {
+		largest = largest[:top]
+	}
+	report.Largest = largest
+
+	return report
+}
+
+// Excluded walks root without filters and returns the files that the filters exclude
+// from included, grouped by the first filter that excludes them
+func Excluded(root string, included []filesystem.FileItem, filters Filters) ([]Bucket, error) {
+	all, err := filesystem.WalkDirectory(context.Background(), root, nil, filesystem.NewFilterManager(), false, true, math.MaxInt64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s without filters: %w", root, err)
+	}
+
+	isIncluded := make(map[string]bool, len(included))
+	for _, f := range included {
+		isIncluded[f.Path] = true
+	}
+
+	reasons := make(map[string]*Bucket)
+	for _, f := range all {
+		if f.IsDir || isIncluded[f.Path] {
+			continue
+		}
+		bucketFor(reasons, exclusionReason(root, f, filters)).add(f.Size)
+	}
+
+	excluded := []Bucket{}
+	for _, reason := range []string{ExcludedHidden, ExcludedGitIgnore, ExcludedGlob, ExcludedSize, ExcludedOther} {
+		if bucket, ok := reasons[reason]; ok {
+			excluded = append(excluded, *bucket)
+		}
+	}
+	return excluded, nil
+}
+
+// exclusionReason returns the first filter that excludes a file
+func exclusionReason(root string, f filesystem.FileItem, filters Filters) string {
+	switch {
+	case !filters.ShowHidden && utils.IsHiddenPath(f.Path):
+		return ExcludedHidden
+	case filters.UseGitIgnore && filters.GitIgnore != nil && isIgnored(root, f.Path, filters.GitIgnore):
+		return ExcludedGitIgnore
+	case filters.Filter != nil && !filters.Filter.ShouldInclude(f.Path):
+		return ExcludedGlob
+	case f.Size > filters.MaxFileSize:
+		return ExcludedSize
+	default:
+		return ExcludedOther
+	}
+}
+
+// isIgnored reports whether a file or one of its parent directories is ignored,
+// as the walker skips ignored directories entirely
+func isIgnored(root, path string, gitIgnore *filesystem.GitIgnoreManager) bool {
+	for p := path; p != "." && p != "/" && p != ""; p = filepath.Dir(p) {
+		if gitIgnore.IsIgnored(filepath.Join(root, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// topLevelDirectory returns the directory at the top of the project that contains path
+func topLevelDirectory(path string) string {
+	first, _, found := strings.Cut(filepath.ToSlash(path), "/")
+	if !found {
+		return rootDirectory
+	}
+	return first + "/"
+}
+
+func bucketFor(buckets map[string]*Bucket, name string) *Bucket {
+	bucket, ok := buckets[name]
+	if !ok {
+		bucket = &Bucket{Name: name}
+		buckets[name] = bucket
+	}
+	return bucket
+}
+
+// sortedBuckets returns the buckets with the most tokens first
+func sortedBuckets(buckets map[string]*Bucket) []Bucket {
+	sorted := make([]Bucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		sorted = append(sorted, *bucket)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Tokens != sorted[j].Tokens {
+			return sorted[i].Tokens > sorted[j].Tokens
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode statistics: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// WriteTable writes the report as aligned text tables
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Project: %s\n\n", r.Root)
+	writeBuckets(tw, "Files", []Bucket{r.Total, r.Selected, r.Unselected})
+	writeBuckets(tw, "By language", r.Languages)
+	writeBuckets(tw, "By top-level directory", r.Directories)
+
+	fmt.Fprintf(tw, "Largest files\n")
+	if len(r.Largest) == 0 {
+		fmt.Fprintf(tw, "  (none)\n")
+	}
+	for _, f := range r.Largest {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t~%d tokens\n", f.Path, f.Language, utils.FormatSize(f.Bytes), f.Tokens)
+	}
+	fmt.Fprintln(tw)
+
+	if r.Excluded != nil {
+		var total Bucket
+		for _, bucket := range r.Excluded {
+			total.Files += bucket.Files
+			total.Bytes += bucket.Bytes
+			total.Tokens += bucket.Tokens
+		}
+		total.Name = "total"
+		writeBuckets(tw, "Excluded by filters", append(r.Excluded, total))
+	}
+
+	return tw.Flush()
+}
+
+// writeBuckets writes a titled table of buckets
+func writeBuckets(w io.Writer, title string, buckets []Bucket) {
+	fmt.Fprintf(w, "%s\n", title)
+	if len(buckets) == 0 {
+		fmt.Fprintf(w, "  (none)\n")
+	}
+	for _, b := range buckets {
+		fmt.Fprintf(w, "  %s\t%d files\t%s\t~%d tokens\n", b.Name, b.Files, utils.FormatSize(b.Bytes), b.Tokens)
+	}
+	fmt.Fprintln(w)
+}
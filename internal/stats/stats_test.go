@@ -0,0 +1,169 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/epilande/codegrab/internal/filesystem"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]int) {
+	t.Helper()
+	for path, size := range files {
+		fullPath := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	files := []filesystem.FileItem{
+		{Path: "cmd", IsDir: true},
+		{Path: "cmd/main.go", Size: 400},
+		{Path: "internal", IsDir: true},
+		{Path: "internal/a.go", Size: 800},
+		{Path: "internal/b.py", Size: 100},
+		{Path: "README.md", Size: 40},
+	}
+	selected := map[string]bool{"internal/a.go": true, "README.md": true}
+
+	report := Compute("/project", files, selected, 2)
+
+	if report.Total.Files != 4 || report.Total.Bytes != 1340 || report.Total.Tokens != 335 {
+		t.Errorf("Unexpected total: %+v", report.Total)
+	}
+	if report.Selected.Files != 2 || report.Selected.Bytes != 840 {
+		t.Errorf("Unexpected selected: %+v", report.Selected)
+	}
+	if report.Unselected.Files != 2 || report.Unselected.Bytes != 500 {
+		t.Errorf("Unexpected unselected: %+v", report.Unselected)
+	}
+
+	expectedLanguages := []Bucket{
+		{Name: "go", Files: 2, Bytes: 1200, Tokens: 300},
+		{Name: "python", Files: 1, Bytes: 100, Tokens: 25},
+		{Name: "markdown", Files: 1, Bytes: 40, Tokens: 10},
+	}
+	if len(report.Languages) != len(expectedLanguages) {
+		t.Fatalf("Expected %d languages, got %+v", len(expectedLanguages), report.Languages)
+	}
+	for i, expected := range expectedLanguages {
+		if report.Languages[i] != expected {
+			t.Errorf("Language %d: expected %+v, got %+v", i, expected, report.Languages[i])
+		}
+	}
+
+	var directories []string
+	for _, bucket := range report.Directories {
+		directories = append(directories, bucket.Name)
+	}
+	if got := strings.Join(directories, ","); got != "internal/,cmd/,." {
+		t.Errorf("Expected directories internal/,cmd/,., got %s", got)
+	}
+
+	if len(report.Largest) != 2 || report.Largest[0].Path != "internal/a.go" || report.Largest[1].Path != "cmd/main.go" {
+		t.Errorf("Expected the 2 largest files to be internal/a.go and cmd/main.go, got %+v", report.Largest)
+	}
+}
+
+func TestExcluded(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]int{
+		"main.go":            100,
+		"notes.txt":          50,
+		"big.go":             5000,
+		".env":               20,
+		".config/settings":   30,
+		"build/output.go":    200,
+		"node_modules/x.js":  300,
+		"internal/helper.go": 80,
+	})
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("build/\nnode_modules\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .gitignore: %v", err)
+	}
+
+	gitIgnore, err := filesystem.NewGitIgnoreManager(root)
+	if err != nil {
+		t.Fatalf("Failed to read .gitignore: %v", err)
+	}
+	filter := filesystem.NewFilterManager()
+	filter.AddGlobPattern("!*.txt")
+
+	filters := Filters{
+		GitIgnore:    gitIgnore,
+		Filter:       filter,
+		UseGitIgnore: true,
+		MaxFileSize:  1000,
+	}
+	included, err := filesystem.WalkDirectory(context.Background(), root, gitIgnore, filter, true, false, filters.MaxFileSize)
+	if err != nil {
+		t.Fatalf("Failed to walk directory: %v", err)
+	}
+
+	excluded, err := Excluded(root, included, filters)
+	if err != nil {
+		t.Fatalf("Excluded returned an error: %v", err)
+	}
+
+	expected := map[string]int{
+		ExcludedHidden:    3, // .env, .config/settings, .gitignore
+		ExcludedGitIgnore: 2,
+		ExcludedGlob:      1,
+		ExcludedSize:      1,
+	}
+	if len(excluded) != len(expected) {
+		t.Fatalf("Expected %d exclusion reasons, got %+v", len(expected), excluded)
+	}
+	for _, bucket := range excluded {
+		if bucket.Files != expected[bucket.Name] {
+			t.Errorf("Expected %d files excluded by %s, got %d", expected[bucket.Name], bucket.Name, bucket.Files)
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]int{"main.go": 100, "pkg/util.go": 2048})
+
+	files, err := filesystem.WalkDirectory(context.Background(), root, nil, filesystem.NewFilterManager(), false, false, math.MaxInt64)
+	if err != nil {
+		t.Fatalf("Failed to walk directory: %v", err)
+	}
+	report := Compute(root, files, nil, DefaultTopFiles)
+	report.Excluded, err = Excluded(root, files, Filters{MaxFileSize: math.MaxInt64})
+	if err != nil {
+		t.Fatalf("Excluded returned an error: %v", err)
+	}
+
+	var table bytes.Buffer
+	if err := report.WriteTable(&table); err != nil {
+		t.Fatalf("WriteTable returned an error: %v", err)
+	}
+	for _, expected := range []string{"By language", "By top-level directory", "Largest files", "Excluded by filters", "pkg/util.go", "2.0 KB", "~512 tokens"} {
+		if !strings.Contains(table.String(), expected) {
+			t.Errorf("Expected the table to contain %q, got:\n%s", expected, table.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON returned an error: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Failed to decode JSON report: %v", err)
+	}
+	if decoded.Total.Files != 2 || decoded.Unselected.Files != 2 || len(decoded.Largest) != 2 {
+		t.Errorf("Unexpected JSON report: %+v", decoded)
+	}
+}
@@ -0,0 +1,197 @@
+package symbols
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LineRange is a range of lines of a file, from Start to End inclusive, counted from 1
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// String returns the range as "start-end", or "start" for a single line
+func (r LineRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// ParseLineRange parses a range such as "120-180", or "42" for a single line
+func ParseLineRange(s string) (LineRange, error) {
+	startStr, endStr, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	if !isRange {
+		endStr = startStr
+	}
+	start, err := strconv.Atoi(strings.TrimSpace(startStr))
+	if err != nil {
+		return LineRange{}, fmt.Errorf("invalid line range %q", s)
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(endStr))
+	if err != nil {
+		return LineRange{}, fmt.Errorf("invalid line range %q", s)
+	}
+	if start < 1 || end < start {
+		return LineRange{}, fmt.Errorf("invalid line range %q: lines are counted from 1 and the end must not come before the start", s)
+	}
+	return LineRange{Start: start, End: end}, nil
+}
+
+// MergeRanges sorts ranges and joins the ones that overlap or touch
+func MergeRanges(ranges []LineRange) []LineRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sorted := make([]LineRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	merged := []LineRange{sorted[0]}
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End+1 {
+			last.End = max(last.End, r.End)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// FormatRanges returns the ranges as a comma separated list
+func FormatRanges(ranges []LineRange) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Entry is an entry of a selection: a file path, optionally narrowed to line ranges
+// (path:120-180,200-210) or to named symbols (path#FuncName)
+type Entry struct {
+	Path    string
+	Ranges  []LineRange
+	Symbols []string
+}
+
+// Partial reports whether the entry selects only part of the file
+func (e Entry) Partial() bool {
+	return len(e.Ranges) > 0 || len(e.Symbols) > 0
+}
+
+// ParseEntry splits a selection entry into its path and its line ranges or symbol.
+// Entries naming an existing file below root are taken literally, so that paths
+// containing ':' or '#' keep working.
+func ParseEntry(root, entry string) (Entry, error) {
+	fullPath := entry
+	if !filepath.IsAbs(fullPath) {
+		fullPath = filepath.Join(root, filepath.FromSlash(entry))
+	}
+	if _, err := os.Stat(fullPath); err == nil {
+		return Entry{Path: entry}, nil
+	}
+
+	if path, symbol, ok := strings.Cut(entry, "#"); ok && path != "" {
+		if symbol == "" {
+			return Entry{}, fmt.Errorf("missing symbol name in %q", entry)
+		}
+		return Entry{Path: path, Symbols: []string{symbol}}, nil
+	}
+
+	if i := strings.LastIndex(entry, ":"); i > 0 {
+		var ranges []LineRange
+		for _, part := range strings.Split(entry[i+1:], ",") {
+			r, err := ParseLineRange(part)
+			if err != nil {
+				return Entry{}, err
+			}
+			ranges = append(ranges, r)
+		}
+		return Entry{Path: entry[:i], Ranges: ranges}, nil
+	}
+
+	return Entry{Path: entry}, nil
+}
+
+// SkippedEntry records a selection entry that could not be resolved to a file and its
+// lines
+type SkippedEntry struct {
+	Entry  string
+	Reason string
+}
+
+// ResolveEntries splits selection entries into the paths to select and the line ranges
+// that partial entries (path:120-180, path#FuncName) narrow them to, by path relative
+// to root in slash form. Symbols are resolved to their lines, and entries that cannot
+// be are skipped. Listing a file whole wins over listing parts of it.
+func ResolveEntries(root string, entries []string) ([]string, map[string][]LineRange, []SkippedEntry) {
+	var paths []string
+	var skipped []SkippedEntry
+	lineRanges := make(map[string][]LineRange)
+	whole := make(map[string]bool)
+	seen := make(map[string]bool)
+
+	for _, raw := range entries {
+		entry, err := ParseEntry(root, raw)
+		if err != nil {
+			skipped = append(skipped, SkippedEntry{Entry: raw, Reason: err.Error()})
+			continue
+		}
+
+		fullPath := entry.Path
+		if !filepath.IsAbs(fullPath) {
+			fullPath = filepath.Join(root, filepath.FromSlash(entry.Path))
+		}
+		// Files outside of root are never read, even to find their symbols
+		relPath, err := filepath.Rel(root, fullPath)
+		if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			skipped = append(skipped, SkippedEntry{Entry: raw, Reason: "outside of the root directory"})
+			continue
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		ranges := entry.Ranges
+		if len(entry.Symbols) > 0 {
+			content, err := os.ReadFile(fullPath)
+			if err != nil {
+				skipped = append(skipped, SkippedEntry{Entry: raw, Reason: err.Error()})
+				continue
+			}
+			found, err := Find(entry.Path, content)
+			if err != nil {
+				skipped = append(skipped, SkippedEntry{Entry: raw, Reason: err.Error()})
+				continue
+			}
+			for _, name := range entry.Symbols {
+				symbol, err := Lookup(found, name)
+				if err != nil {
+					skipped = append(skipped, SkippedEntry{Entry: raw, Reason: err.Error()})
+					continue
+				}
+				ranges = append(ranges, symbol.Lines)
+			}
+			if len(ranges) == 0 {
+				continue
+			}
+		}
+
+		if !seen[relPath] {
+			seen[relPath] = true
+			paths = append(paths, entry.Path)
+		}
+		if !entry.Partial() {
+			whole[relPath] = true
+			delete(lineRanges, relPath)
+		} else if !whole[relPath] {
+			lineRanges[relPath] = MergeRanges(append(lineRanges[relPath], ranges...))
+		}
+	}
+	return paths, lineRanges, skipped
+}
@@ -0,0 +1,228 @@
+// Package symbols finds the named declarations of source files, so that a selection
+// can be narrowed to the lines of a function, type or class.
+package symbols
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+)
+
+// Symbol is a named declaration of a file
+type Symbol struct {
+	// Name is qualified by the enclosing type or class, as in "Server.Start"
+	Name  string
+	Kind  string
+	Lines LineRange
+}
+
+// language describes how the declarations of a language appear in its syntax tree
+type language struct {
+	lang *sitter.Language
+	// declarations maps the node types of declarations to the kind of symbol they declare
+	declarations map[string]string
+	// containers are declarations whose members are symbols named after them
+	containers map[string]bool
+	// wrappers are nodes around a declaration that belong to its lines, such as
+	// export statements and decorators
+	wrappers map[string]bool
+}
+
+var (
+	goLanguage = &language{
+		lang: golang.GetLanguage(),
+		declarations: map[string]string{
+			"function_declaration": "func",
+			"method_declaration":   "method",
+			"type_spec":            "type",
+		},
+	}
+	tsLanguage = &language{
+		lang: tsx.GetLanguage(),
+		declarations: map[string]string{
+			"function_declaration":           "function",
+			"generator_function_declaration": "function",
+			"class_declaration":              "class",
+			"abstract_class_declaration":     "class",
+			"interface_declaration":          "interface",
+			"type_alias_declaration":         "type",
+			"enum_declaration":               "enum",
+			"method_definition":              "method",
+			"variable_declarator":            "function",
+			"public_field_definition":        "method",
+		},
+		containers: map[string]bool{"class_declaration": true, "abstract_class_declaration": true},
+		wrappers:   map[string]bool{"export_statement": true},
+	}
+	pyLanguage = &language{
+		lang: python.GetLanguage(),
+		declarations: map[string]string{
+			"function_definition": "def",
+			"class_definition":    "class",
+		},
+		containers: map[string]bool{"class_definition": true},
+		wrappers:   map[string]bool{"decorated_definition": true},
+	}
+)
+
+// languageFor returns the language of the file at path, or nil if it has no symbols
+func languageFor(path string) *language {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		return goLanguage
+	case ".js", ".jsx", ".ts", ".tsx":
+		return tsLanguage
+	case ".py":
+		return pyLanguage
+	default:
+		return nil
+	}
+}
+
+// Supported reports whether the symbols of the file at path can be found
+func Supported(path string) bool {
+	return languageFor(path) != nil
+}
+
+// Find returns the symbols declared in a Go, TS/JS or Python file, in order. Their
+// lines include their doc comments, decorators and export keywords.
+func Find(path string, content []byte) ([]Symbol, error) {
+	l := languageFor(path)
+	if l == nil {
+		return nil, fmt.Errorf("symbols are only supported in Go, TS/JS and Python files, not %s", path)
+	}
+
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(l.lang)
+	tree := parser.Parse(nil, content)
+	if tree == nil {
+		return nil, fmt.Errorf("failed to parse %s", path)
+	}
+	defer tree.Close()
+
+	var symbols []Symbol
+	l.collect(tree.RootNode(), content, "", &symbols)
+	return symbols, nil
+}
+
+// Lookup finds a symbol by its qualified name ("Server.Start"), or by its own name
+// ("Start") if no other symbol has it
+func Lookup(symbols []Symbol, name string) (Symbol, error) {
+	var matches []Symbol
+	for _, symbol := range symbols {
+		if symbol.Name == name {
+			return symbol, nil
+		}
+		if strings.HasSuffix(symbol.Name, "."+name) {
+			matches = append(matches, symbol)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return Symbol{}, fmt.Errorf("symbol %q not found", name)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, match := range matches {
+			names[i] = match.Name
+		}
+		return Symbol{}, fmt.Errorf("symbol %q is ambiguous: %s", name, strings.Join(names, ", "))
+	}
+}
+
+// collect appends the symbols declared below node, prefixing their names with prefix
+func (l *language) collect(node *sitter.Node, content []byte, prefix string, symbols *[]Symbol) {
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		child := node.NamedChild(i)
+		kind, name := l.declaration(child, content)
+		if kind == "" {
+			l.collect(child, content, prefix, symbols)
+			continue
+		}
+
+		name = prefix + name
+		*symbols = append(*symbols, Symbol{Name: name, Kind: kind, Lines: l.lines(child)})
+		if l.containers[child.Type()] {
+			if body := child.ChildByFieldName("body"); body != nil {
+				l.collect(body, content, name+".", symbols)
+			}
+		}
+	}
+}
+
+// declaration returns the kind and the name of the symbol node declares, if any
+func (l *language) declaration(node *sitter.Node, content []byte) (string, string) {
+	kind := l.declarations[node.Type()]
+	if kind == "" {
+		return "", ""
+	}
+	nameNode := node.ChildByFieldName("name")
+	if nameNode == nil {
+		return "", ""
+	}
+	name := nameNode.Content(content)
+
+	switch node.Type() {
+	case "variable_declarator", "public_field_definition":
+		// Only variables and fields holding functions are symbols
+		value := node.ChildByFieldName("value")
+		if value == nil || (value.Type() != "arrow_function" && value.Type() != "function_expression" && value.Type() != "function") {
+			return "", ""
+		}
+	case "method_declaration":
+		if receiver := node.ChildByFieldName("receiver"); receiver != nil {
+			if recvType := findType(receiver, "type_identifier"); recvType != nil {
+				name = recvType.Content(content) + "." + name
+			}
+		}
+	}
+	return kind, name
+}
+
+// lines returns the lines of a declaration, extended to the statement holding it
+// and to the comments right above it
+func (l *language) lines(node *sitter.Node) LineRange {
+	// A lone type spec or variable declarator spans its whole declaration
+	if parent := node.Parent(); parent != nil && parent.NamedChildCount() == 1 {
+		switch parent.Type() {
+		case "type_declaration", "lexical_declaration", "variable_declaration":
+			node = parent
+		}
+	}
+	for parent := node.Parent(); parent != nil && l.wrappers[parent.Type()]; parent = node.Parent() {
+		node = parent
+	}
+
+	start := int(node.StartPoint().Row)
+	for prev := node.PrevNamedSibling(); prev != nil && prev.Type() == "comment" && int(prev.EndPoint().Row) == start-1; prev = prev.PrevNamedSibling() {
+		start = int(prev.StartPoint().Row)
+	}
+
+	end := node.EndPoint()
+	endRow := int(end.Row)
+	if end.Column == 0 && endRow > start {
+		endRow--
+	}
+	return LineRange{Start: start + 1, End: endRow + 1}
+}
+
+// findType returns the first node of type nodeType below node, depth first
+func findType(node *sitter.Node, nodeType string) *sitter.Node {
+	if node.Type() == nodeType {
+		return node
+	}
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		if found := findType(node.NamedChild(i), nodeType); found != nil {
+			return found
+		}
+	}
+	return nil
+}
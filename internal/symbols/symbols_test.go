@@ -0,0 +1,253 @@
+package symbols
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	testCases := []struct {
+		name     string
+		path     string
+		content  string
+		expected []Symbol
+	}{
+		{
+			name: "Go",
+			path: "server.go",
+			content: `package server
+
+// Server serves requests
+type Server struct {
+	addr string
+}
+
+// Start starts the server
+func (s *Server) Start() error {
+	return nil
+}
+
+type (
+	A int
+	B int
+)
+
+func New() *Server {
+	return &Server{}
+}
+`,
+			expected: []Symbol{
+				{Name: "Server", Kind: "type", Lines: LineRange{Start: 3, End: 6}},
+				{Name: "Server.Start", Kind: "method", Lines: LineRange{Start: 8, End: 11}},
+				{Name: "A", Kind: "type", Lines: LineRange{Start: 14, End: 14}},
+				{Name: "B", Kind: "type", Lines: LineRange{Start: 15, End: 15}},
+				{Name: "New", Kind: "func", Lines: LineRange{Start: 18, End: 20}},
+			},
+		},
+		{
+			name: "TypeScript",
+			path: "app.ts",
+			content: `import { run } from './run';
+
+// Greets the user
+export function greet(name: string) {
+  return name;
+}
+
+export const add = (a: number, b: number) => a + b;
+
+class App {
+  start() {
+    run();
+  }
+}
+
+interface Props {}
+`,
+			expected: []Symbol{
+				{Name: "greet", Kind: "function", Lines: LineRange{Start: 3, End: 6}},
+				{Name: "add", Kind: "function", Lines: LineRange{Start: 8, End: 8}},
+				{Name: "App", Kind: "class", Lines: LineRange{Start: 10, End: 14}},
+				{Name: "App.start", Kind: "method", Lines: LineRange{Start: 11, End: 13}},
+				{Name: "Props", Kind: "interface", Lines: LineRange{Start: 16, End: 16}},
+			},
+		},
+		{
+			name: "Python",
+			path: "app.py",
+			content: `import os
+
+
+@cached
+def load():
+    return os.getcwd()
+
+
+class App:
+    def run(self):
+        pass
+`,
+			expected: []Symbol{
+				{Name: "load", Kind: "def", Lines: LineRange{Start: 4, End: 6}},
+				{Name: "App", Kind: "class", Lines: LineRange{Start: 9, End: 11}},
+				{Name: "App.run", Kind: "def", Lines: LineRange{Start: 10, End: 11}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			symbols, err := Find(tc.path, []byte(tc.content))
+			if err != nil {
+				t.Fatalf("Find failed: %v", err)
+			}
+			if !reflect.DeepEqual(symbols, tc.expected) {
+				t.Errorf("Find() =\n%+v\nwant\n%+v", symbols, tc.expected)
+			}
+		})
+	}
+
+	if _, err := Find("README.md", []byte("# Title")); err == nil {
+		t.Errorf("Expected an error for an unsupported file")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	symbols := []Symbol{
+		{Name: "Server", Kind: "type"},
+		{Name: "Server.Start", Kind: "method"},
+		{Name: "Client.Close", Kind: "method"},
+		{Name: "Server.Close", Kind: "method"},
+	}
+
+	testCases := []struct {
+		name     string
+		expected string
+		errMsg   string
+	}{
+		{name: "Server.Start", expected: "Server.Start"},
+		{name: "Start", expected: "Server.Start"},
+		{name: "Server", expected: "Server"},
+		{name: "Close", errMsg: "ambiguous"},
+		{name: "Missing", errMsg: "not found"},
+	}
+
+	for _, tc := range testCases {
+		symbol, err := Lookup(symbols, tc.name)
+		if tc.errMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+				t.Errorf("Lookup(%q) error = %v, want it to contain %q", tc.name, err, tc.errMsg)
+			}
+			continue
+		}
+		if err != nil || symbol.Name != tc.expected {
+			t.Errorf("Lookup(%q) = %q, %v, want %q", tc.name, symbol.Name, err, tc.expected)
+		}
+	}
+}
+
+func TestParseLineRange(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected LineRange
+		wantErr  bool
+	}{
+		{input: "120-180", expected: LineRange{Start: 120, End: 180}},
+		{input: "42", expected: LineRange{Start: 42, End: 42}},
+		{input: " 3 - 5 ", expected: LineRange{Start: 3, End: 5}},
+		{input: "0-5", wantErr: true},
+		{input: "10-5", wantErr: true},
+		{input: "a-b", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		r, err := ParseLineRange(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseLineRange(%q) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+			continue
+		}
+		if !tc.wantErr && r != tc.expected {
+			t.Errorf("ParseLineRange(%q) = %v, want %v", tc.input, r, tc.expected)
+		}
+	}
+}
+
+func TestMergeRanges(t *testing.T) {
+	ranges := []LineRange{{Start: 40, End: 50}, {Start: 1, End: 10}, {Start: 5, End: 12}, {Start: 13, End: 20}}
+	expected := []LineRange{{Start: 1, End: 20}, {Start: 40, End: 50}}
+	if merged := MergeRanges(ranges); !reflect.DeepEqual(merged, expected) {
+		t.Errorf("MergeRanges() = %v, want %v", merged, expected)
+	}
+	if got := FormatRanges(expected); got != "1-20, 40-50" {
+		t.Errorf("FormatRanges() = %q, want %q", got, "1-20, 40-50")
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "odd:1-2.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	testCases := []struct {
+		entry    string
+		expected Entry
+		wantErr  bool
+	}{
+		{entry: "main.go", expected: Entry{Path: "main.go"}},
+		{entry: "main.go:120-180", expected: Entry{Path: "main.go", Ranges: []LineRange{{Start: 120, End: 180}}}},
+		{entry: "main.go:1-5,9", expected: Entry{Path: "main.go", Ranges: []LineRange{{Start: 1, End: 5}, {Start: 9, End: 9}}}},
+		{entry: "main.go#Server.Start", expected: Entry{Path: "main.go", Symbols: []string{"Server.Start"}}},
+		{entry: "odd:1-2.txt", expected: Entry{Path: "odd:1-2.txt"}},
+		{entry: "main.go:x", wantErr: true},
+		{entry: "main.go#", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		entry, err := ParseEntry(root, tc.entry)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseEntry(%q) error = %v, wantErr %v", tc.entry, err, tc.wantErr)
+			continue
+		}
+		if !tc.wantErr && !reflect.DeepEqual(entry, tc.expected) {
+			t.Errorf("ParseEntry(%q) = %+v, want %+v", tc.entry, entry, tc.expected)
+		}
+	}
+}
+
+func TestResolveEntries(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.go": "package main\n\nfunc main() {\n}\n\nfunc helper() {\n}\n",
+		"util.go": "package main\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	paths, lineRanges, skipped := ResolveEntries(root, []string{
+		"main.go:1-2",
+		"main.go#helper",
+		"util.go:1",
+		"util.go",
+		"main.go#missing",
+		"main.go:x",
+		"../outside.go#main",
+	})
+
+	if expected := []string{"main.go", "util.go"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected paths %v, got %v", expected, paths)
+	}
+	expectedRanges := map[string][]LineRange{"main.go": {{Start: 1, End: 2}, {Start: 6, End: 7}}}
+	if !reflect.DeepEqual(lineRanges, expectedRanges) {
+		t.Errorf("Expected line ranges %v, got %v", expectedRanges, lineRanges)
+	}
+	if len(skipped) != 3 || skipped[0].Entry != "main.go#missing" || skipped[1].Entry != "main.go:x" || skipped[2].Reason != "outside of the root directory" {
+		t.Errorf("Expected the unknown symbol, the invalid range and the outside file to be skipped, got %+v", skipped)
+	}
+}
@@ -1,48 +1,44 @@
 package ui
 
-const HelpText = `Navigation:
-  j / ↓                    Move cursor down
-  k / ↑                    Move cursor up
-  h / ←                    Collapse directory or return focus to file tree
-  l / →                    Expand directory or focus preview panel
-  e                        Toggle expand/collapse all directories
-
-Search:
-  /                        Start search
-  ctrl+n / ↓               Next search result
-  ctrl+p / ↑               Previous search result
-  tab / enter              Select/deselect file in search results
-  esc                      Exit search mode
-
-Selection & Output:
-  space / tab              Select/deselect file or directory
-  y                        Copy generated output to clipboard
-  ctrl+g                   Generate output file
-  D                        Toggle automatic dependency resolution (Go, TS/JS)
-  F                        Cycle through output formats (md, txt, xml)
-  S                        Toggle secret redaction (Default: On)
-
-View Options:
-  i                        Toggle .gitignore filter
-  .                        Toggle hidden files
-  P                        Toggle file preview pane
-  r                        Refresh file list & reset selection
-  ?                        Toggle help screen
-  q / ctrl+c               Quit
-
-Navigation (Vim Style):
-  gg                       Go to top (file tree or preview)
-  G                        Go to bottom (file tree or preview)
-  ctrl+u                   Scroll half page up (file tree or preview)
-  ctrl+d                   Scroll half page down (file tree or preview)
-
-Preview Navigation:
-  J                        Scroll preview down (when preview not focused)
-  K                        Scroll preview up (when preview not focused)
-  j / k                    Scroll preview when preview is focused`
+import (
+	"fmt"
+	"strings"
+
+	"github.com/epilande/codegrab/internal/keymap"
+)
+
+// HelpText returns the help screen, listing the keys bound to each action in keys
+func HelpText(keys *keymap.Keymap) string {
+	var b strings.Builder
+	for i, section := range keymap.Sections {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(section.Title + ":")
+		for _, binding := range section.Bindings {
+			bound := binding.Keys
+			if binding.Action != keymap.None {
+				bound = keys.Keys(binding.Action)
+			}
+			if len(bound) == 0 {
+				continue
+			}
+			names := make([]string, len(bound))
+			for j, key := range bound {
+				names[j] = keymap.DisplayKey(key)
+			}
+			fmt.Fprintf(&b, "\n  %-24s %s", strings.Join(names, " / "), binding.Help)
+		}
+	}
+	return b.String()
+}
 
 const UsageText = `Usage:
   grab [options] [directory]
+  grab cache prune [--older-than <age>]
+  grab stats [--json] [--top <n>] [directory]
+  grab mcp [--skip-redaction] [directory]
+  grab serve [--addr <host:port>] [--token <token>] [directory]
 
   Options:
     -h, --help               Display this help information.
@@ -50,12 +46,24 @@ const UsageText = `Usage:
     -n, --non-interactive    Run in non-interactive mode (selects all valid files).
     -o, --output <file>      Output file path (default: "./codegrab-output.<format>").
     -t, --temp               Use system temporary directory for output file.
+    --stdout                 Write the output to stdout (same as "-o -"). Status messages go to stderr.
     -g, --glob <pattern>     Include/exclude files using glob patterns. Can be used multiple times.
                              Prefix with '!' to exclude (e.g., -g="*.go" -g="\!*_test.go").
                              Supports brace expansion (e.g., -g="*.{ts,tsx}").
     -f, --format <format>    Output format. Available: markdown, text, xml (default: "markdown").
     -S, --skip-redaction     Skip automatic secret redaction via gitleaks (Default: false).
                              WARNING: This may expose sensitive information!
+    --cache                  Reuse and update a cached clone of Git URLs between runs.
+    --remote                 Treat the argument as a Git remote and clone it, even if it is not
+                             recognized as a Git URL (e.g. a self-hosted forge).
+    --ref <ref>              Branch, tag or commit to check out when cloning a Git URL.
+    --subdir <path>          Only check out and grab this subdirectory when cloning a Git URL.
+    --files-from <file|->    Read the files to select from a file, or from stdin with "-" (newline or NUL
+                             separated). Selects only these files in non-interactive mode and uses them
+                             as the initial selection in interactive mode. Entries can be narrowed to
+                             lines (path:120-180) or to a Go, TS/JS or Python symbol (path#FuncName).
+    --grep <pattern>         Only select files whose contents match a regular expression. Invalid
+                             expressions are matched literally.
     --deps                   Automatically include direct dependencies for selected files (Go, JS/TS, Python).
     --max-depth <depth>      Maximum depth for dependency resolution (-1 for unlimited, default: 1).
                              Only effective when --deps is used.
@@ -64,8 +72,16 @@ const UsageText = `Usage:
     --theme <name>           Set the UI theme. Available: catppuccin-latte, catppuccin-frappe,
                              catppuccin-macchiato, catppuccin-mocha, rose-pine, rose-pine-dawn,
                              rose-pine-moon, dracula, nord. (default: "catppuccin-mocha").
+    --metadata               Include a metadata header with the repository name, branch, HEAD commit,
+                             dirty state, timestamp, codegrab version and filter settings.
+    --line-numbers           Prefix each line of file content with its line number (line blocks
+                             with start and end attributes in XML).
+    --history <n>            Include the last n commits that touched each file (hash, author date, subject).
+    --history-diff           Include the diff of each commit in the file history (requires --history).
     --show-tokens            Show the number of tokens for each file in file tree.
+    --sort <mode>            Sort order of the file tree: name, size, tokens or mtime (default: "name").
     --icons                  Display Nerd Font icons.
+    --no-mouse               Disable mouse support, keeping the native text selection of the terminal.
 
   Examples:
     # Run interactively in the current directory
@@ -83,6 +99,18 @@ const UsageText = `Usage:
     # Generate XML output in a temporary file
     grab --temp -f xml
 
+    # Grab only the files changed on this branch, plus their dependencies
+    git diff --name-only main | grab -n --deps --files-from -
+
+    # Pipe the output straight into another tool
+    grab -n -o - | llm "Explain this code"
+
+    # Include the last 5 commits of each file, with diffs
+    grab -n --history 5 --history-diff
+
+    # Grab a subdirectory of a remote repository at a tag
+    grab --ref v2.0.0 --subdir pkg/foo https://github.com/user/repo
+
     # Filter files using glob pattern, skipping files > 50kb
     grab -g="*.go" --max-file-size 50kb
 
@@ -0,0 +1,238 @@
+package highlight
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/epilande/codegrab/internal/ui/themes"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	// tabWidth is the number of columns between tab stops
+	tabWidth = 4
+	// minContentWidth is the narrowest content column for which line numbers are shown
+	minContentWidth = 10
+)
+
+// Document holds the lines of a previewed file and highlights them on demand, so
+// that only the lines in view are styled
+type Document struct {
+	syntax *Syntax
+	lines  []string
+	// states[i] is the lexer state at the start of line i, computed up to the
+	// furthest line highlighted so far
+	states []State
+	search string
+}
+
+// NewDocument splits content into lines highlighted as language, a language name as
+// returned by generator.DetermineLanguage
+func NewDocument(content, language string) *Document {
+	content = strings.TrimSuffix(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	return &Document{
+		syntax: ForLanguage(language),
+		lines:  strings.Split(content, "\n"),
+		states: []State{{}},
+	}
+}
+
+// LineCount returns the number of lines in the document
+func (d *Document) LineCount() int {
+	return len(d.lines)
+}
+
+// String returns the lines of the document joined by newlines
+func (d *Document) String() string {
+	return strings.Join(d.lines, "\n")
+}
+
+// SetSearch marks the case-insensitive occurrences of query in rendered lines.
+// An empty query clears the marks.
+func (d *Document) SetSearch(query string) {
+	d.search = strings.ToLower(query)
+}
+
+// Search returns the indexes of the lines containing query, ignoring case
+func (d *Document) Search(query string) []int {
+	query = strings.ToLower(query)
+	if query == "" {
+		return nil
+	}
+	var matches []int
+	for i, line := range d.lines {
+		if strings.Contains(strings.ToLower(line), query) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// Line returns the spans of line i
+func (d *Document) Line(i int) []Span {
+	state := State{}
+	if d.syntax.mode != modePlain && d.syntax.mode != modeDiff {
+		for len(d.states) <= i {
+			last := len(d.states) - 1
+			_, next := d.syntax.HighlightLine(d.lines[last], d.states[last])
+			d.states = append(d.states, next)
+		}
+		state = d.states[i]
+	}
+	spans, _ := d.syntax.HighlightLine(d.lines[i], state)
+	if d.search != "" {
+		spans = markMatches(spans, d.search)
+	}
+	return spans
+}
+
+// Render returns the rows shown in a window of height rows and width columns, starting
+// at line offset. Long lines are wrapped by display width. With anchorBottom, the window
+// ends at the last line instead, so that the end of the file is visible even when lines
+// above it wrap.
+func (d *Document) Render(offset, height, width int, lineNumbers, anchorBottom bool) string {
+	if height <= 0 || width <= 0 || len(d.lines) == 0 {
+		return ""
+	}
+
+	digits := len(strconv.Itoa(len(d.lines)))
+	gutterWidth := digits + 3 // " │ "
+	if !lineNumbers || width-gutterWidth < minContentWidth {
+		lineNumbers = false
+		gutterWidth = 0
+	}
+	contentWidth := width - gutterWidth
+
+	skipRows := 0
+	if anchorBottom {
+		offset, skipRows = d.bottomOffset(height, contentWidth)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	styles := spanStyles()
+	gutterStyle := lipgloss.NewStyle().Foreground(themes.CurrentTheme.Colors().Muted)
+
+	rows := make([]string, 0, height)
+	for i := offset; i < len(d.lines) && len(rows) < height; i++ {
+		for r, row := range wrapSpans(d.Line(i), contentWidth) {
+			if i == offset && r < skipRows {
+				continue
+			}
+			if len(rows) == height {
+				break
+			}
+			var b strings.Builder
+			if lineNumbers {
+				if r == 0 {
+					b.WriteString(gutterStyle.Render(fmt.Sprintf("%*d │ ", digits, i+1)))
+				} else {
+					b.WriteString(gutterStyle.Render(strings.Repeat(" ", digits) + " │ "))
+				}
+			}
+			for _, span := range row {
+				b.WriteString(styles[span.Kind].Render(span.Text))
+			}
+			rows = append(rows, b.String())
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+// bottomOffset returns the first line of a window that ends with the last line, and
+// the number of wrapped rows of that line that do not fit in the window
+func (d *Document) bottomOffset(height, width int) (int, int) {
+	rows := 0
+	for offset := len(d.lines) - 1; offset >= 0; offset-- {
+		rows += len(wrapSpans([]Span{{Text: d.lines[offset]}}, width))
+		if rows >= height {
+			return offset, rows - height
+		}
+	}
+	return 0, 0
+}
+
+// wrapSpans splits spans into rows of at most width display columns. Tabs are expanded
+// to the next tab stop and control characters are replaced, so the rendered width
+// matches the computed one.
+func wrapSpans(spans []Span, width int) [][]Span {
+	if width < 1 {
+		width = 1
+	}
+
+	var rows [][]Span
+	var row []Span
+	var text strings.Builder
+	col := 0
+	kind := Plain
+
+	flushSpan := func() {
+		if text.Len() > 0 {
+			row = append(row, Span{Text: text.String(), Kind: kind})
+			text.Reset()
+		}
+	}
+	flushRow := func() {
+		flushSpan()
+		rows = append(rows, row)
+		row = nil
+		col = 0
+	}
+	write := func(s string, w int) {
+		if col+w > width && col > 0 {
+			flushRow()
+		}
+		text.WriteString(s)
+		col += w
+	}
+
+	for _, span := range spans {
+		flushSpan()
+		kind = span.Kind
+		for _, r := range span.Text {
+			switch {
+			case r == '\t':
+				n := tabWidth - col%tabWidth
+				if col+n > width {
+					flushRow()
+					n = tabWidth
+				}
+				write(strings.Repeat(" ", n), n)
+			case r == '\r':
+				continue
+			case r < 0x20 || r == 0x7f:
+				write("?", 1)
+			default:
+				write(string(r), runewidth.RuneWidth(r))
+			}
+		}
+	}
+	flushRow()
+	return rows
+}
+
+// spanStyles returns the style of each token kind, colored from the active theme
+func spanStyles() map[TokenKind]lipgloss.Style {
+	colors := themes.CurrentTheme.Colors()
+	style := func(color lipgloss.Color) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(color)
+	}
+	return map[TokenKind]lipgloss.Style{
+		Plain:       style(colors.Text),
+		Keyword:     style(colors.Primary),
+		Type:        style(colors.Tertiary),
+		Function:    style(colors.Info),
+		String:      style(colors.Success),
+		Number:      style(colors.Warning),
+		Comment:     style(colors.Muted).Italic(true),
+		Tag:         style(colors.Secondary),
+		Redacted:    style(colors.Error).Background(colors.HighlightBackground).Bold(true),
+		DiffAdded:   style(colors.Success),
+		DiffRemoved: style(colors.Error),
+		DiffHunk:    style(colors.Info),
+		Match:       style(colors.Background).Background(colors.Warning),
+	}
+}
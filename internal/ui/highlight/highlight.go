@@ -0,0 +1,569 @@
+package highlight
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenKind classifies a span of highlighted text
+type TokenKind int
+
+const (
+	Plain TokenKind = iota
+	Keyword
+	Type
+	Function
+	String
+	Number
+	Comment
+	Tag
+	Redacted
+	DiffAdded
+	DiffRemoved
+	DiffHunk
+	Match
+)
+
+// Span is a run of text of a single kind
+type Span struct {
+	Text string
+	Kind TokenKind
+}
+
+// State is the lexer state carried from the end of one line to the start of the next
+type State struct {
+	end     string    // delimiter closing an open block comment or multi-line string
+	kind    TokenKind // kind of the open block
+	inTag   bool      // inside a markup tag
+	inFence bool      // inside a fenced markdown code block
+}
+
+// redactedPrefix starts the placeholder that replaces redacted secrets
+const redactedPrefix = "[REDACTED_"
+
+// HighlightLine splits one line of content into spans, starting from the state at the
+// end of the previous line. It returns the spans and the state at the end of the line.
+func (s *Syntax) HighlightLine(line string, state State) ([]Span, State) {
+	l := &lexer{syntax: s, line: line, state: state}
+	switch s.mode {
+	case modeDiff:
+		l.diff()
+	case modeMarkdown:
+		l.markdown()
+	case modeMarkup:
+		l.markup()
+	case modeCode:
+		l.code()
+	default:
+		l.plain()
+	}
+	return markRedacted(l.spans), l.state
+}
+
+type lexer struct {
+	syntax *Syntax
+	line   string
+	pos    int
+	state  State
+	spans  []Span
+}
+
+// emit appends the text up to end as a span of kind, merging it with the previous span
+// of the same kind
+func (l *lexer) emit(end int, kind TokenKind) {
+	if end <= l.pos {
+		return
+	}
+	text := l.line[l.pos:end]
+	l.pos = end
+	if n := len(l.spans); n > 0 && l.spans[n-1].Kind == kind {
+		l.spans[n-1].Text += text
+		return
+	}
+	l.spans = append(l.spans, Span{Text: text, Kind: kind})
+}
+
+// emitRest emits the remainder of the line as a span of kind
+func (l *lexer) emitRest(kind TokenKind) {
+	l.emit(len(l.line), kind)
+}
+
+// emitRune emits the next rune as a span of kind
+func (l *lexer) emitRune(kind TokenKind) {
+	_, size := utf8.DecodeRuneInString(l.line[l.pos:])
+	l.emit(l.pos+size, kind)
+}
+
+// closeBlock consumes the rest of an open block comment or multi-line string and
+// reports whether the block was closed on this line
+func (l *lexer) closeBlock() bool {
+	if l.state.end == "" {
+		return true
+	}
+	idx := strings.Index(l.line[l.pos:], l.state.end)
+	if idx < 0 {
+		l.emitRest(l.state.kind)
+		return false
+	}
+	l.emit(l.pos+idx+len(l.state.end), l.state.kind)
+	l.state.end = ""
+	return true
+}
+
+// openBlock emits a block that starts at the current position with the start
+// delimiter, keeping it open across lines until the end delimiter is found
+func (l *lexer) openBlock(delims [2]string, kind TokenKind) {
+	start := l.pos + len(delims[0])
+	if idx := strings.Index(l.line[start:], delims[1]); idx >= 0 {
+		l.emit(start+idx+len(delims[1]), kind)
+		return
+	}
+	l.emitRest(kind)
+	l.state.end = delims[1]
+	l.state.kind = kind
+}
+
+func (l *lexer) plain() {
+	l.emitRest(Plain)
+}
+
+func (l *lexer) code() {
+	if !l.closeBlock() {
+		return
+	}
+	l.key()
+
+	s := l.syntax
+	for l.pos < len(l.line) {
+		rest := l.line[l.pos:]
+		c := rest[0]
+
+		if delims, ok := hasDelimiter(rest, s.blockComments); ok {
+			l.openBlock(delims, Comment)
+			continue
+		}
+		if l.startsLineComment(rest) {
+			l.emitRest(Comment)
+			return
+		}
+		if delims, ok := hasDelimiter(rest, s.multilineStrings); ok {
+			l.openBlock(delims, String)
+			continue
+		}
+
+		switch {
+		case strings.IndexByte(s.quotes, c) >= 0:
+			l.emit(l.pos+quotedLength(rest), String)
+		case c >= '0' && c <= '9' && !l.afterWord():
+			l.emit(l.pos+numberLength(rest), Number)
+		case c == '#' && s.directives && strings.TrimSpace(l.line[:l.pos]) == "":
+			l.emit(l.pos+1+wordLength(rest[1:]), Keyword)
+		case s.commandPrefix != 0 && c == s.commandPrefix && wordLength(rest[1:]) > 0:
+			l.emit(l.pos+1+wordLength(rest[1:]), Keyword)
+		case isWordStart(rest):
+			n := wordLength(rest)
+			l.emit(l.pos+n, s.classifyWord(rest[:n], rest[n:]))
+		default:
+			l.emitRune(Plain)
+		}
+	}
+}
+
+// startsLineComment reports whether a line comment starts at the current position
+func (l *lexer) startsLineComment(rest string) bool {
+	for _, prefix := range l.syntax.lineComments {
+		if !strings.HasPrefix(rest, prefix) {
+			continue
+		}
+		// In shells, # only starts a comment at the start of a word (e.g. not in $#)
+		if l.syntax.commentNeedsSpace && l.pos > 0 && !isSpace(l.line[l.pos-1]) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// afterWord reports whether the previous character belongs to an identifier, so
+// that digits in names like utf8 are not highlighted as numbers
+func (l *lexer) afterWord() bool {
+	if l.pos == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(l.line[:l.pos])
+	return isWordRune(r)
+}
+
+// key highlights a leading "key:" or "key =" of config formats and "[section]" headers
+func (l *lexer) key() {
+	s := l.syntax
+	if s.keySeparators == "" && !s.sections {
+		return
+	}
+
+	trimmed := strings.TrimLeft(l.line, " ")
+	indent := len(l.line) - len(trimmed)
+	if s.sections && strings.HasPrefix(trimmed, "[") && strings.HasSuffix(strings.TrimSpace(trimmed), "]") {
+		l.emitRest(Tag)
+		return
+	}
+	if s.keySeparators == "" {
+		return
+	}
+	if strings.HasPrefix(trimmed, "- ") {
+		// YAML list items can hold mappings
+		indent += 2
+		trimmed = trimmed[2:]
+	}
+
+	n := 0
+	if trimmed != "" && (trimmed[0] == '"' || trimmed[0] == '\'') {
+		n = quotedLength(trimmed)
+	} else {
+		for n < len(trimmed) && !isSpace(trimmed[n]) && strings.IndexByte(s.keySeparators+"\"'{[(", trimmed[n]) < 0 {
+			n++
+		}
+	}
+	if n == 0 {
+		return
+	}
+	after := strings.TrimLeft(trimmed[n:], " ")
+	if after == "" || strings.IndexByte(s.keySeparators, after[0]) < 0 {
+		return
+	}
+	// A colon must be followed by a space or the end of the line, so that CSS
+	// selectors like a:hover and URLs are not mistaken for keys
+	if after[0] == ':' && len(after) > 1 && !isSpace(after[1]) {
+		return
+	}
+
+	l.emit(indent, Plain)
+	l.emit(indent+n, Tag)
+}
+
+func (l *lexer) markup() {
+	if !l.closeBlock() {
+		return
+	}
+	for l.pos < len(l.line) {
+		rest := l.line[l.pos:]
+		if l.state.inTag {
+			switch {
+			case strings.HasPrefix(rest, "/>") || strings.HasPrefix(rest, "?>"):
+				l.emit(l.pos+2, Tag)
+				l.state.inTag = false
+			case rest[0] == '>':
+				l.emit(l.pos+1, Tag)
+				l.state.inTag = false
+			case rest[0] == '"' || rest[0] == '\'':
+				l.emit(l.pos+quotedLength(rest), String)
+			case markupNameLength(rest) > 0:
+				l.emit(l.pos+markupNameLength(rest), Type)
+			default:
+				l.emitRune(Plain)
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			l.openBlock([2]string{"<!--", "-->"}, Comment)
+		case rest[0] == '<' && len(rest) > 1 && (isWordStart(rest[1:]) || strings.IndexByte("/!?", rest[1]) >= 0):
+			n := 1
+			if strings.IndexByte("/!?", rest[1]) >= 0 {
+				n++
+			}
+			n += markupNameLength(rest[n:])
+			l.emit(l.pos+n, Tag)
+			l.state.inTag = true
+		case rest[0] == '&':
+			if end := strings.IndexByte(rest, ';'); end > 0 && end < 10 {
+				l.emit(l.pos+end+1, Number)
+			} else {
+				l.emitRune(Plain)
+			}
+		default:
+			next := strings.IndexAny(rest[1:], "<&")
+			if next < 0 {
+				l.emitRest(Plain)
+			} else {
+				l.emit(l.pos+1+next, Plain)
+			}
+		}
+	}
+}
+
+func (l *lexer) markdown() {
+	trimmed := strings.TrimLeft(l.line, " ")
+	if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+		l.state.inFence = !l.state.inFence
+		l.emitRest(Comment)
+		return
+	}
+	if l.state.inFence {
+		l.emitRest(String)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(trimmed, "#"):
+		l.emitRest(Keyword)
+		return
+	case strings.HasPrefix(trimmed, ">"):
+		l.emitRest(Comment)
+		return
+	}
+
+	// List markers
+	indent := len(l.line) - len(trimmed)
+	if marker := listMarkerLength(trimmed); marker > 0 {
+		l.emit(indent, Plain)
+		l.emit(indent+marker, Tag)
+	}
+
+	for l.pos < len(l.line) {
+		rest := l.line[l.pos:]
+		if rest[0] == '`' {
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				l.emit(l.pos+end+2, String)
+				continue
+			}
+		}
+		next := strings.IndexByte(rest[1:], '`')
+		if next < 0 {
+			l.emitRest(Plain)
+		} else {
+			l.emit(l.pos+1+next, Plain)
+		}
+	}
+}
+
+func (l *lexer) diff() {
+	switch {
+	case strings.HasPrefix(l.line, "+++") || strings.HasPrefix(l.line, "---"),
+		strings.HasPrefix(l.line, "diff "), strings.HasPrefix(l.line, "index "):
+		l.emitRest(Keyword)
+	case strings.HasPrefix(l.line, "@@"):
+		l.emitRest(DiffHunk)
+	case strings.HasPrefix(l.line, "+"):
+		l.emitRest(DiffAdded)
+	case strings.HasPrefix(l.line, "-"):
+		l.emitRest(DiffRemoved)
+	default:
+		l.emitRest(Plain)
+	}
+}
+
+// classifyWord returns the kind of an identifier, given the text that follows it
+func (s *Syntax) classifyWord(word, after string) TokenKind {
+	lookup := word
+	if s.ignoreCase {
+		lookup = strings.ToLower(word)
+	}
+	switch {
+	case s.keywords[lookup]:
+		return Keyword
+	case s.types[lookup]:
+		return Type
+	case s.constants[lookup]:
+		return Number
+	case strings.HasPrefix(strings.TrimLeft(after, " "), "("):
+		return Function
+	default:
+		return Plain
+	}
+}
+
+// markRedacted splits out redaction placeholders so they stand out from the
+// surrounding code
+func markRedacted(spans []Span) []Span {
+	hasPlaceholder := false
+	for _, span := range spans {
+		if strings.Contains(span.Text, redactedPrefix) {
+			hasPlaceholder = true
+			break
+		}
+	}
+	if !hasPlaceholder {
+		return spans
+	}
+
+	result := make([]Span, 0, len(spans)+2)
+	for _, span := range spans {
+		text := span.Text
+		for {
+			start := strings.Index(text, redactedPrefix)
+			if start < 0 {
+				break
+			}
+			end := strings.IndexByte(text[start:], ']')
+			if end < 0 {
+				break
+			}
+			end += start + 1
+			if start > 0 {
+				result = append(result, Span{Text: text[:start], Kind: span.Kind})
+			}
+			result = append(result, Span{Text: text[start:end], Kind: Redacted})
+			text = text[end:]
+		}
+		if text != "" {
+			result = append(result, Span{Text: text, Kind: span.Kind})
+		}
+	}
+	return result
+}
+
+// markMatches splits out the case-insensitive occurrences of query as Match spans
+func markMatches(spans []Span, query string) []Span {
+	var line strings.Builder
+	for _, span := range spans {
+		line.WriteString(span.Text)
+	}
+	lower := strings.ToLower(line.String())
+	if len(lower) != line.Len() || !strings.Contains(lower, query) {
+		// Case folding changed byte offsets, or there is nothing to mark
+		return spans
+	}
+
+	// Find the byte ranges of the matches in the whole line
+	var ranges [][2]int
+	for start := 0; ; {
+		idx := strings.Index(lower[start:], query)
+		if idx < 0 {
+			break
+		}
+		ranges = append(ranges, [2]int{start + idx, start + idx + len(query)})
+		start += idx + len(query)
+	}
+
+	result := make([]Span, 0, len(spans)+2*len(ranges))
+	spanStart := 0
+	for _, span := range spans {
+		spanEnd := spanStart + len(span.Text)
+		for pos := spanStart; pos < spanEnd; {
+			next, kind := spanEnd, span.Kind
+			for _, r := range ranges {
+				if pos >= r[0] && pos < r[1] {
+					next, kind = min(spanEnd, r[1]), Match
+					break
+				}
+				if r[0] > pos && r[0] < next {
+					next = r[0]
+				}
+			}
+			result = append(result, Span{Text: span.Text[pos-spanStart : next-spanStart], Kind: kind})
+			pos = next
+		}
+		spanStart = spanEnd
+	}
+	return result
+}
+
+// hasDelimiter returns the delimiter pair whose start delimiter begins s
+func hasDelimiter(s string, delims [][2]string) ([2]string, bool) {
+	for _, d := range delims {
+		if strings.HasPrefix(s, d[0]) {
+			return d, true
+		}
+	}
+	return [2]string{}, false
+}
+
+// quotedLength returns the length of the quoted string at the start of s, including
+// its quotes. Unterminated strings extend to the end of the line.
+func quotedLength(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// numberLength returns the length of the numeric literal at the start of s
+func numberLength(s string) int {
+	n := 0
+	for n < len(s) {
+		c := s[n]
+		isDigit := c >= '0' && c <= '9'
+		isHex := (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+		isExponentSign := (c == '+' || c == '-') && n > 0 && (s[n-1] == 'e' || s[n-1] == 'E') && !strings.HasPrefix(s, "0x")
+		if !isDigit && !isHex && !isExponentSign && strings.IndexByte("xXoO_.", c) < 0 {
+			break
+		}
+		if c == '.' && (n+1 >= len(s) || s[n+1] < '0' || s[n+1] > '9') {
+			break
+		}
+		n++
+	}
+	// Allow type suffixes like 10u32, 1.5f or 42L
+	for n < len(s) && isASCIILetter(s[n]) {
+		n++
+	}
+	return n
+}
+
+// markupNameLength returns the length of the tag or attribute name at the start of s
+func markupNameLength(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !isWordRune(r) && r != '-' && r != ':' && r != '.' {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+// listMarkerLength returns the length of a markdown list marker at the start of s
+func listMarkerLength(s string) int {
+	if len(s) >= 2 && strings.IndexByte("-*+", s[0]) >= 0 && s[1] == ' ' {
+		return 1
+	}
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	if n > 0 && n+1 < len(s) && (s[n] == '.' || s[n] == ')') && s[n+1] == ' ' {
+		return n + 1
+	}
+	return 0
+}
+
+// isWordStart reports whether an identifier starts at the beginning of s
+func isWordStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || r == '$' || r == '@' || unicode.IsLetter(r)
+}
+
+// wordLength returns the length of the identifier at the start of s
+func wordLength(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !isWordRune(r) && !(n == 0 && (r == '$' || r == '@')) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
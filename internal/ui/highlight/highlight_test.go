@@ -0,0 +1,225 @@
+package highlight
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/epilande/codegrab/internal/ui/themes"
+)
+
+func TestMain(m *testing.M) {
+	themes.Initialize()
+	m.Run()
+}
+
+func TestHighlightLine(t *testing.T) {
+	tests := []struct {
+		language string
+		line     string
+		expected []Span
+	}{
+		{
+			language: "go",
+			line:     `func main() { x := "hi" // done`,
+			expected: []Span{
+				{"func", Keyword}, {" ", Plain}, {"main", Function}, {"() { x := ", Plain},
+				{`"hi"`, String}, {" ", Plain}, {"// done", Comment},
+			},
+		},
+		{
+			language: "go",
+			line:     "var utf8 int = 0x1F",
+			expected: []Span{
+				{"var", Keyword}, {" utf8 ", Plain}, {"int", Type}, {" = ", Plain}, {"0x1F", Number},
+			},
+		},
+		{
+			language: "python",
+			line:     `return None  # "not a string"`,
+			expected: []Span{
+				{"return", Keyword}, {" ", Plain}, {"None", Number}, {"  ", Plain}, {`# "not a string"`, Comment},
+			},
+		},
+		{
+			language: "bash",
+			line:     `echo $# done # comment`,
+			expected: []Span{
+				{"echo $# ", Plain}, {"done", Keyword}, {" ", Plain}, {"# comment", Comment},
+			},
+		},
+		{
+			language: "sql",
+			line:     "SELECT id FROM users",
+			expected: []Span{
+				{"SELECT", Keyword}, {" id ", Plain}, {"FROM", Keyword}, {" users", Plain},
+			},
+		},
+		{
+			language: "yaml",
+			line:     "  - name: app # the app",
+			expected: []Span{
+				{"  - ", Plain}, {"name", Tag}, {": app ", Plain}, {"# the app", Comment},
+			},
+		},
+		{
+			language: "css",
+			line:     "a:hover {",
+			expected: []Span{{"a:hover {", Plain}},
+		},
+		{
+			language: "json",
+			line:     `"enabled": true,`,
+			expected: []Span{{`"enabled"`, Tag}, {": ", Plain}, {"true", Number}, {",", Plain}},
+		},
+		{
+			language: "html",
+			line:     `<a href="/x">link</a>`,
+			expected: []Span{
+				{"<a", Tag}, {" ", Plain}, {"href", Type}, {"=", Plain}, {`"/x"`, String},
+				{">", Tag}, {"link", Plain}, {"</a>", Tag},
+			},
+		},
+		{
+			language: "diff",
+			line:     "+added line",
+			expected: []Span{{"+added line", DiffAdded}},
+		},
+		{
+			language: "text",
+			line:     `token = "[REDACTED_github-pat]"`,
+			expected: []Span{{`token = "`, Plain}, {"[REDACTED_github-pat]", Redacted}, {`"`, Plain}},
+		},
+	}
+
+	for _, test := range tests {
+		spans, _ := ForLanguage(test.language).HighlightLine(test.line, State{})
+		if !reflect.DeepEqual(spans, test.expected) {
+			t.Errorf("%s: HighlightLine(%q)\nexpected %v\n     got %v", test.language, test.line, test.expected, spans)
+		}
+	}
+}
+
+func TestDocumentMultilineState(t *testing.T) {
+	doc := NewDocument("x := 1\n/* start\nstill comment\nend */ y := `raw\nstring`\n", "go")
+
+	if doc.LineCount() != 5 {
+		t.Fatalf("Expected 5 lines, got %d", doc.LineCount())
+	}
+
+	expected := [][]Span{
+		{{"x := ", Plain}, {"1", Number}},
+		{{"/* start", Comment}},
+		{{"still comment", Comment}},
+		{{"end */", Comment}, {" y := ", Plain}, {"`raw", String}},
+		{{"string`", String}},
+	}
+	// Highlight out of order, so that line states are computed on demand
+	for _, i := range []int{3, 4, 0, 2, 1} {
+		if spans := doc.Line(i); !reflect.DeepEqual(spans, expected[i]) {
+			t.Errorf("Line %d: expected %v, got %v", i, expected[i], spans)
+		}
+	}
+}
+
+func TestMarkdownFences(t *testing.T) {
+	doc := NewDocument("# Title\n```go\nfunc main() {}\n```\n- item with `code`", "markdown")
+
+	expected := [][]Span{
+		{{"# Title", Keyword}},
+		{{"```go", Comment}},
+		{{"func main() {}", String}},
+		{{"```", Comment}},
+		{{"-", Tag}, {" item with ", Plain}, {"`code`", String}},
+	}
+	for i := range expected {
+		if spans := doc.Line(i); !reflect.DeepEqual(spans, expected[i]) {
+			t.Errorf("Line %d: expected %v, got %v", i, expected[i], spans)
+		}
+	}
+}
+
+func TestWrapSpans(t *testing.T) {
+	rows := wrapSpans([]Span{{"日本語テキスト", String}}, 5)
+	var texts []string
+	for _, row := range rows {
+		texts = append(texts, row[0].Text)
+	}
+	// Wide characters take two columns and are never split across rows
+	if strings.Join(texts, "|") != "日本|語テ|キス|ト" {
+		t.Errorf("Expected wide characters to wrap by display width, got %q", texts)
+	}
+
+	rows = wrapSpans([]Span{{"a\tb", Plain}}, 80)
+	if len(rows) != 1 || rows[0][0].Text != "a   b" {
+		t.Errorf("Expected tabs to expand to the next tab stop, got %v", rows)
+	}
+
+	rows = wrapSpans(nil, 80)
+	if len(rows) != 1 || len(rows[0]) != 0 {
+		t.Errorf("Expected an empty line to render as one empty row, got %v", rows)
+	}
+}
+
+func TestDocumentRender(t *testing.T) {
+	var lines []string
+	for i := 0; i < 10000; i++ {
+		lines = append(lines, "x := 1")
+	}
+	doc := NewDocument(strings.Join(lines, "\n"), "go")
+
+	output := doc.Render(0, 5, 40, true, false)
+	rows := strings.Split(output, "\n")
+	if len(rows) != 5 {
+		t.Fatalf("Expected 5 rows, got %d", len(rows))
+	}
+	if plain := ansi.Strip(rows[0]); plain != "    1 │ x := 1" {
+		t.Errorf("Expected a line number gutter, got %q", plain)
+	}
+	if len(doc.states) > 6 {
+		t.Errorf("Expected only the visible lines to be highlighted, computed %d line states", len(doc.states))
+	}
+
+	for _, row := range rows {
+		if width := ansi.StringWidth(row); width > 40 {
+			t.Errorf("Expected rows to fit in 40 columns, got %d", width)
+		}
+	}
+}
+
+func TestDocumentRenderWrapsAndAnchorsBottom(t *testing.T) {
+	doc := NewDocument("short\n"+strings.Repeat("long ", 10)+"\nlast", "text")
+
+	output := ansi.Strip(doc.Render(0, 10, 20, false, false))
+	expected := "short\nlong long long long \nlong long long long \nlong long \nlast"
+	if output != expected {
+		t.Errorf("Expected long lines to wrap\nexpected %q\n     got %q", expected, output)
+	}
+
+	// The last line stays visible even though the lines above it wrap
+	output = ansi.Strip(doc.Render(1, 3, 20, false, true))
+	expected = "long long long long \nlong long \nlast"
+	if output != expected {
+		t.Errorf("Expected the window to end at the last line\nexpected %q\n     got %q", expected, output)
+	}
+}
+
+func TestDocumentSearch(t *testing.T) {
+	doc := NewDocument("func Foo() {}\nvar x = foo\nbar", "go")
+
+	if matches := doc.Search("FOO"); !reflect.DeepEqual(matches, []int{0, 1}) {
+		t.Errorf("Expected case-insensitive matches on lines 0 and 1, got %v", matches)
+	}
+
+	doc.SetSearch("oo()")
+	expected := []Span{{"func", Keyword}, {" ", Plain}, {"F", Function}, {"oo", Match}, {"()", Match}, {" {}", Plain}}
+	if spans := doc.Line(0); !reflect.DeepEqual(spans, expected) {
+		t.Errorf("Expected matches to be marked across spans\nexpected %v\n     got %v", expected, spans)
+	}
+
+	doc.SetSearch("")
+	if spans := doc.Line(1); spans[len(spans)-1].Kind == Match {
+		t.Errorf("Expected an empty search to clear the marks, got %v", spans)
+	}
+}
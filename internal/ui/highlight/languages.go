@@ -0,0 +1,331 @@
+package highlight
+
+import "strings"
+
+type mode int
+
+const (
+	modePlain mode = iota
+	modeCode
+	modeMarkup
+	modeMarkdown
+	modeDiff
+)
+
+// Syntax describes how the lines of a language are split into spans
+type Syntax struct {
+	mode              mode
+	lineComments      []string
+	blockComments     [][2]string
+	multilineStrings  [][2]string
+	quotes            string
+	keywords          map[string]bool
+	types             map[string]bool
+	constants         map[string]bool
+	ignoreCase        bool
+	commentNeedsSpace bool
+	directives        bool   // # preprocessor directives at the start of a line
+	commandPrefix     byte   // prefix of commands such as \section in LaTeX
+	keySeparators     string // separators of leading keys in config formats
+	sections          bool   // [section] headers in config formats
+}
+
+var (
+	cBlock     = [][2]string{{"/*", "*/"}}
+	slashes    = []string{"//"}
+	hashes     = []string{"#"}
+	dashes     = []string{"--"}
+	semicolons = []string{";"}
+	percents   = []string{"%"}
+
+	plainSyntax    = &Syntax{mode: modePlain}
+	markupSyntax   = &Syntax{mode: modeMarkup}
+	markdownSyntax = &Syntax{mode: modeMarkdown}
+	diffSyntax     = &Syntax{mode: modeDiff}
+)
+
+// words builds a lookup set from a space-separated list of words
+func words(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(list) {
+		set[w] = true
+	}
+	return set
+}
+
+const (
+	cKeywords    = "auto break case const continue default do else enum extern for goto if inline register restrict return sizeof static struct switch typedef union volatile while"
+	cTypes       = "char double float int long short signed unsigned void bool size_t int8_t int16_t int32_t int64_t uint8_t uint16_t uint32_t uint64_t"
+	cppKeywords  = cKeywords + " class namespace template typename public private protected virtual override final new delete this try catch throw using operator friend constexpr noexcept explicit mutable static_cast dynamic_cast reinterpret_cast const_cast"
+	jsKeywords   = "break case catch class const continue debugger default delete do else export extends finally for function if import in instanceof let new of return super switch this throw try typeof var void while with yield async await static get set from as"
+	tsKeywords   = jsKeywords + " interface type enum implements namespace declare abstract readonly private protected public keyof infer is satisfies"
+	jsConstants  = "true false null undefined NaN Infinity"
+	javaKeywords = "abstract assert break case catch class continue default do else enum extends final finally for if implements import instanceof interface native new package private protected public return static strictfp super switch synchronized this throw throws transient try volatile while var record yield"
+	javaTypes    = "boolean byte char double float int long short void String Object Integer Long Boolean List Map Set"
+)
+
+// syntaxes maps the languages recognized by the output formats to their syntax
+var syntaxes = map[string]*Syntax{
+	"go": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock,
+		multilineStrings: [][2]string{{"`", "`"}}, quotes: `"'`,
+		keywords:  words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var"),
+		types:     words("bool byte complex64 complex128 error float32 float64 int int8 int16 int32 int64 rune string uint uint8 uint16 uint32 uint64 uintptr any comparable"),
+		constants: words("true false nil iota"),
+	},
+	"python": {
+		mode: modeCode, lineComments: hashes,
+		multilineStrings: [][2]string{{`"""`, `"""`}, {"'''", "'''"}}, quotes: `"'`,
+		keywords:  words("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield match case"),
+		types:     words("int float str bool list dict set tuple bytes object type complex frozenset"),
+		constants: words("True False None"),
+	},
+	"javascript": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock,
+		multilineStrings: [][2]string{{"`", "`"}}, quotes: `"'`,
+		keywords: words(jsKeywords), constants: words(jsConstants),
+	},
+	"typescript": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock,
+		multilineStrings: [][2]string{{"`", "`"}}, quotes: `"'`,
+		keywords:  words(tsKeywords),
+		types:     words("string number boolean any unknown never void object symbol bigint"),
+		constants: words(jsConstants),
+	},
+	"java": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock,
+		multilineStrings: [][2]string{{`"""`, `"""`}}, quotes: `"'`,
+		keywords: words(javaKeywords), types: words(javaTypes), constants: words("true false null"),
+	},
+	"c": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock, quotes: `"'`, directives: true,
+		keywords: words(cKeywords), types: words(cTypes), constants: words("NULL true false"),
+	},
+	"cpp": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock, quotes: `"'`, directives: true,
+		keywords:  words(cppKeywords),
+		types:     words(cTypes + " string vector map set unique_ptr shared_ptr std"),
+		constants: words("NULL nullptr true false"),
+	},
+	"csharp": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock, quotes: `"'`, directives: true,
+		keywords:  words("abstract as base break case catch class const continue default delegate do else enum event explicit extern finally fixed for foreach goto if implicit in interface internal is lock namespace new operator out override params private protected public readonly ref return sealed sizeof stackalloc static struct switch this throw try typeof unchecked unsafe using virtual volatile while var async await get set record init"),
+		types:     words("bool byte char decimal double float int long object sbyte short string uint ulong ushort void dynamic"),
+		constants: words("true false null"),
+	},
+	"rust": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock, quotes: `"`,
+		keywords:  words("as async await break const continue crate dyn else enum extern fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait type unsafe use where while"),
+		types:     words("i8 i16 i32 i64 i128 isize u8 u16 u32 u64 u128 usize f32 f64 bool char str String Vec Option Result Box"),
+		constants: words("true false None Some Ok Err"),
+	},
+	"swift": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock,
+		multilineStrings: [][2]string{{`"""`, `"""`}}, quotes: `"`,
+		keywords:  words("associatedtype class deinit enum extension fileprivate func import init inout internal let open operator private protocol public rethrows static struct subscript typealias var break case continue default defer do else fallthrough for guard if in repeat return switch where while as catch is super self Self throw throws try await async"),
+		types:     words("Int Double Float String Bool Character Array Dictionary Set Optional Any Void"),
+		constants: words("true false nil"),
+	},
+	"kotlin": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock,
+		multilineStrings: [][2]string{{`"""`, `"""`}}, quotes: `"'`,
+		keywords:  words("as break class continue do else for fun if in interface is object package return super this throw try typealias val var when while by catch constructor finally get import init set where abstract annotation companion data enum inline internal lateinit open override private protected public sealed suspend"),
+		types:     words("Int Long Short Byte Double Float Boolean Char String Unit Any Nothing List Map Set"),
+		constants: words("true false null"),
+	},
+	"scala": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock,
+		multilineStrings: [][2]string{{`"""`, `"""`}}, quotes: `"'`,
+		keywords:  words("abstract case catch class def do else extends final finally for forSome if implicit import lazy match new object override package private protected return sealed super this throw trait try type val var while with yield given using enum then"),
+		types:     words("Int Long Double Float Boolean Char String Unit Any Nothing Option List Map"),
+		constants: words("true false null None Some"),
+	},
+	"dart": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock,
+		multilineStrings: [][2]string{{`"""`, `"""`}, {"'''", "'''"}}, quotes: `"'`,
+		keywords:  words("abstract as assert async await break case catch class const continue default deferred do dynamic else enum export extends extension external factory final finally for get if implements import in is late library mixin new on operator part required rethrow return set static super switch sync this throw try typedef var while with yield"),
+		types:     words("int double num bool String List Map Set void Future Stream"),
+		constants: words("true false null"),
+	},
+	"php": {
+		mode: modeCode, lineComments: []string{"//", "#"}, blockComments: cBlock, quotes: `"'`,
+		keywords:  words("abstract and array as break callable case catch class clone const continue declare default do echo else elseif empty enddeclare endfor endforeach endif endswitch endwhile extends final finally fn for foreach function global goto if implements include include_once instanceof insteadof interface isset list match namespace new or print private protected public readonly require require_once return static switch throw trait try unset use var while yield"),
+		constants: words("true false null TRUE FALSE NULL"),
+	},
+	"ruby": {
+		mode: modeCode, lineComments: hashes, quotes: `"'`,
+		keywords:  words("alias and begin break case class def defined? do else elsif end ensure for if in module next not or redo rescue retry return self super then undef unless until when while yield require require_relative attr_accessor attr_reader attr_writer"),
+		constants: words("true false nil"),
+	},
+	"bash": {
+		mode: modeCode, lineComments: hashes, quotes: `"'`, commentNeedsSpace: true,
+		keywords:  words("if then else elif fi case esac for while until do done in function return local export readonly declare unset shift exit break continue source alias set eval exec trap"),
+		constants: words("true false"),
+	},
+	"fish": {
+		mode: modeCode, lineComments: hashes, quotes: `"'`, commentNeedsSpace: true,
+		keywords: words("if else end for in while function return switch case begin and or not set set_color echo break continue source"),
+	},
+	"powershell": {
+		mode: modeCode, lineComments: hashes, blockComments: [][2]string{{"<#", "#>"}}, quotes: `"'`, ignoreCase: true,
+		keywords:  words("begin break catch class continue data do dynamicparam else elseif end exit filter finally for foreach from function if in param process return switch throw trap try until using while"),
+		constants: words("$true $false $null"),
+	},
+	"batch": {
+		mode: modeCode, lineComments: []string{"::", "REM ", "rem "}, quotes: `"`, ignoreCase: true,
+		keywords: words("echo set if else goto call exit for in do not exist errorlevel defined setlocal endlocal shift pause"),
+	},
+	"sql": {
+		mode: modeCode, lineComments: dashes, blockComments: cBlock, quotes: `"'`, ignoreCase: true,
+		keywords:  words("select from where insert into values update set delete create table drop alter add column index primary key foreign references join inner left right outer full cross on as and or not is in like between group by order having limit offset union all distinct case when then else end exists view with returning default unique check constraint if begin commit rollback transaction grant revoke asc desc"),
+		types:     words("int integer varchar text boolean bool date time timestamp timestamptz serial bigserial bigint smallint numeric decimal float real double char uuid json jsonb blob"),
+		constants: words("null true false"),
+	},
+	"lua": {
+		mode: modeCode, lineComments: dashes, blockComments: [][2]string{{"--[[", "]]"}},
+		multilineStrings: [][2]string{{"[[", "]]"}}, quotes: `"'`,
+		keywords:  words("and break do else elseif end for function goto if in local not or repeat return then until while"),
+		constants: words("true false nil"),
+	},
+	"haskell": {
+		mode: modeCode, lineComments: dashes, blockComments: [][2]string{{"{-", "-}"}}, quotes: `"`,
+		keywords:  words("case class data default deriving do else foreign if import in infix infixl infixr instance let module newtype of then type where qualified as hiding"),
+		types:     words("Int Integer Float Double Bool Char String Maybe Either IO"),
+		constants: words("True False Nothing Just"),
+	},
+	"elixir": {
+		mode: modeCode, lineComments: hashes,
+		multilineStrings: [][2]string{{`"""`, `"""`}}, quotes: `"'`,
+		keywords:  words("def defp defmodule defmacro defstruct defprotocol defimpl do end if else unless case cond fn when with import alias require use quote unquote receive try rescue catch after raise in and or not"),
+		constants: words("true false nil"),
+	},
+	"erlang": {
+		mode: modeCode, lineComments: percents, quotes: `"`,
+		keywords:  words("after and andalso band begin bnot bor bsl bsr bxor case catch cond div end fun if let not of or orelse receive rem try when xor"),
+		constants: words("true false undefined"),
+	},
+	"clojure": {
+		mode: modeCode, lineComments: semicolons, quotes: `"`,
+		keywords:  words("def defn defn- defmacro defmulti defmethod defprotocol defrecord deftype let fn if if-not do when when-not cond case loop recur ns require import try catch finally throw"),
+		constants: words("true false nil"),
+	},
+	"fsharp": {
+		mode: modeCode, lineComments: slashes, blockComments: [][2]string{{"(*", "*)"}},
+		multilineStrings: [][2]string{{`"""`, `"""`}}, quotes: `"`,
+		keywords:  words("abstract and as assert base begin class default delegate do done downcast downto elif else end exception extern for fun function if in inherit inline interface internal lazy let match member module mutable namespace new of open or override private public rec return static struct then to type upcast use val void when while with yield"),
+		constants: words("true false null"),
+	},
+	"perl": {
+		mode: modeCode, lineComments: hashes, quotes: `"'`,
+		keywords: words("my our local sub if elsif else unless while until for foreach do last next redo return use require package print and or not eq ne lt gt le ge"),
+	},
+	"r": {
+		mode: modeCode, lineComments: hashes, quotes: `"'`,
+		keywords:  words("if else repeat while function for in next break return library require"),
+		constants: words("TRUE FALSE NULL NA Inf NaN"),
+	},
+	"julia": {
+		mode: modeCode, lineComments: hashes, blockComments: [][2]string{{"#=", "=#"}},
+		multilineStrings: [][2]string{{`"""`, `"""`}}, quotes: `"`,
+		keywords:  words("abstract baremodule begin break catch const continue do else elseif end export finally for function global if import let local macro module mutable quote return struct try type using where while"),
+		constants: words("true false nothing missing"),
+	},
+	"latex": {
+		mode: modeCode, lineComments: percents, commandPrefix: '\\',
+	},
+	"yaml": {
+		mode: modeCode, lineComments: hashes, quotes: `"'`, keySeparators: ":", commentNeedsSpace: true,
+		constants: words("true false null yes no on off ~"),
+	},
+	"toml": {
+		mode: modeCode, lineComments: hashes, quotes: `"'`, keySeparators: "=", sections: true,
+		multilineStrings: [][2]string{{`"""`, `"""`}, {"'''", "'''"}},
+		constants:        words("true false"),
+	},
+	"ini": {
+		mode: modeCode, lineComments: []string{";", "#"}, quotes: `"'`, keySeparators: "=:", sections: true,
+		constants: words("true false yes no on off"),
+	},
+	"json": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock, quotes: `"`, keySeparators: ":",
+		constants: words("true false null"),
+	},
+	"hcl": {
+		mode: modeCode, lineComments: []string{"#", "//"}, blockComments: cBlock, quotes: `"`, keySeparators: "=",
+		keywords:  words("resource variable output module data provider locals terraform for_each count depends_on dynamic for in if"),
+		constants: words("true false null"),
+	},
+	"dockerfile": {
+		mode: modeCode, lineComments: hashes, quotes: `"'`, ignoreCase: true,
+		keywords: words("from run cmd label expose env add copy entrypoint volume user workdir arg onbuild stopsignal healthcheck shell as maintainer"),
+	},
+	"makefile": {
+		mode: modeCode, lineComments: hashes, quotes: `"'`, keySeparators: ":=", commentNeedsSpace: true,
+		keywords: words("ifeq ifneq ifdef ifndef else endif include define endef export unexport override"),
+	},
+	"css": {
+		mode: modeCode, blockComments: cBlock, quotes: `"'`, keySeparators: ":",
+		keywords: words("@import @media @font-face @keyframes @supports @charset"),
+	},
+	"scss": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock, quotes: `"'`, keySeparators: ":",
+		keywords: words("@import @use @forward @media @mixin @include @extend @if @else @each @for @function @return"),
+	},
+	"graphql": {
+		mode: modeCode, lineComments: hashes, multilineStrings: [][2]string{{`"""`, `"""`}}, quotes: `"`,
+		keywords:  words("query mutation subscription fragment on type input enum interface union scalar schema extend implements directive"),
+		types:     words("Int Float String Boolean ID"),
+		constants: words("true false null"),
+	},
+	"protobuf": {
+		mode: modeCode, lineComments: slashes, blockComments: cBlock, quotes: `"'`,
+		keywords:  words("syntax package import option message enum service rpc returns repeated optional required oneof map reserved extend stream"),
+		types:     words("double float int32 int64 uint32 uint64 sint32 sint64 fixed32 fixed64 sfixed32 sfixed64 bool string bytes"),
+		constants: words("true false"),
+	},
+	"html":     markupSyntax,
+	"markdown": markdownSyntax,
+	"diff":     diffSyntax,
+}
+
+// aliases maps languages to the syntax of a closely related language
+var aliases = map[string]string{
+	"jsx":         "javascript",
+	"tsx":         "typescript",
+	"objectivec":  "c",
+	"d":           "cpp",
+	"groovy":      "java",
+	"gradle":      "java",
+	"elm":         "haskell",
+	"crystal":     "ruby",
+	"terraform":   "hcl",
+	"properties":  "ini",
+	"conf":        "ini",
+	"gitignore":   "ini",
+	"dotenv":      "ini",
+	"sass":        "scss",
+	"less":        "scss",
+	"xml":         "html",
+	"svg":         "html",
+	"vue":         "html",
+	"svelte":      "html",
+	"awk":         "perl",
+	"tcl":         "bash",
+	"nim":         "python",
+	"zig":         "rust",
+	"v":           "go",
+	"jenkinsfile": "java",
+	"vagrantfile": "ruby",
+}
+
+// ForLanguage returns the syntax for a language name as returned by
+// generator.DetermineLanguage. Unknown languages are not highlighted.
+func ForLanguage(language string) *Syntax {
+	if alias, ok := aliases[language]; ok {
+		language = alias
+	}
+	if syntax, ok := syntaxes[language]; ok {
+		return syntax
+	}
+	return plainSyntax
+}
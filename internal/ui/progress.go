@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/epilande/codegrab/internal/ui/themes"
+)
+
+const (
+	progressFilled = "█"
+	progressEmpty  = "░"
+)
+
+// progressCells returns how many of width cells are filled for done out of total
+func progressCells(done, total, width int) int {
+	if total <= 0 || width <= 0 {
+		return 0
+	}
+	filled := done * width / total
+	if filled > width {
+		filled = width
+	}
+	return filled
+}
+
+// ProgressBar returns a bar of width cells, filled in proportion to done out of total
+func ProgressBar(done, total, width int) string {
+	filled := progressCells(done, total, width)
+	return strings.Repeat(progressFilled, filled) + strings.Repeat(progressEmpty, max(width-filled, 0))
+}
+
+// RenderProgressBar returns a ProgressBar styled with the current theme
+func RenderProgressBar(done, total, width int) string {
+	colors := themes.CurrentTheme.Colors()
+	filled := progressCells(done, total, width)
+	return lipgloss.NewStyle().Foreground(colors.Primary).Render(strings.Repeat(progressFilled, filled)) +
+		lipgloss.NewStyle().Foreground(colors.Muted).Render(strings.Repeat(progressEmpty, max(width-filled, 0)))
+}
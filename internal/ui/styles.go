@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/epilande/codegrab/internal/git"
 	"github.com/epilande/codegrab/internal/ui/themes"
 	"strings"
 )
@@ -113,10 +114,12 @@ func StyleFileLine(
 	icon string,
 	iconColor string,
 	name string,
+	gitStatus git.FileStatus,
 	rawSuffix string,
 	isDir bool,
 	isSelected bool,
 	isCursor bool,
+	isInRange bool,
 	isPartialDir bool,
 	viewportWidth int,
 ) string {
@@ -127,6 +130,12 @@ func StyleFileLine(
 	nameStyle := lipgloss.NewStyle()
 	suffixStyle := lipgloss.NewStyle().Foreground(colors.Muted)
 	iconStyle := lipgloss.NewStyle()
+	markerStyle := lipgloss.NewStyle().Foreground(GitStatusColor(gitStatus))
+
+	rawMarker := ""
+	if marker := gitStatus.Marker(); marker != "" {
+		rawMarker = " " + marker
+	}
 
 	// Style checkbox based on selection state
 	switch rawCheckbox {
@@ -194,7 +203,7 @@ func StyleFileLine(
 	}
 
 	// Truncate name if needed
-	suffixWidth := lipgloss.Width(rawSuffix)
+	suffixWidth := lipgloss.Width(rawMarker) + lipgloss.Width(rawSuffix)
 	nameWidth := lipgloss.Width(name)
 	maxNameWidth := availableWidth - suffixWidth
 
@@ -221,6 +230,7 @@ func StyleFileLine(
 		renderedIcon = iconStyle.Render(icon + " ")
 	}
 	renderedName := nameStyle.Render(truncatedName)
+	renderedMarker := markerStyle.Render(rawMarker)
 	renderedSuffix := suffixStyle.Render(rawSuffix)
 
 	// Handle cursor highlighting
@@ -243,14 +253,16 @@ func StyleFileLine(
 			renderedIcon = ""
 		}
 		renderedName = cursorBaseStyle.Inherit(nameStyle).Render(truncatedName)
+		renderedMarker = cursorBaseStyle.Inherit(markerStyle).Render(rawMarker)
 		renderedSuffix = cursorBaseStyle.Inherit(suffixStyle).Render(rawSuffix)
 
 		// Build the full line with cursor highlight
-		cursorLineContent := fmt.Sprintf("%s%s%s%s%s",
+		cursorLineContent := fmt.Sprintf("%s%s%s%s%s%s",
 			renderedCheckbox,
 			renderedPrefix,
 			renderedIcon,
 			renderedName,
+			renderedMarker,
 			renderedSuffix,
 		)
 
@@ -269,19 +281,42 @@ func StyleFileLine(
 		return cursorIndicator + cursorLineContent + paddingWithHighlight
 	} else {
 		// Build the non-cursor line with consistent spacing
-		lineContent := fmt.Sprintf("%s%s%s%s%s",
+		lineContent := fmt.Sprintf("%s%s%s%s%s%s",
 			renderedCheckbox,
 			renderedPrefix,
 			renderedIcon,
 			renderedName,
+			renderedMarker,
 			renderedSuffix,
 		)
 
+		// Mark lines in a visual range in place of the left padding
+		if isInRange {
+			return lipgloss.NewStyle().Foreground(colors.Primary).Bold(true).Render(" ┃ ") + lineContent
+		}
+
 		// Ensure consistent left padding (matches cursor indicator width)
 		return "   " + lineContent
 	}
 }
 
+// GitStatusColor returns the theme color used for the git status marker of a file
+func GitStatusColor(status git.FileStatus) lipgloss.Color {
+	colors := themes.CurrentTheme.Colors()
+	switch status {
+	case git.StatusConflicted:
+		return colors.Error
+	case git.StatusModified:
+		return colors.Warning
+	case git.StatusAdded, git.StatusStaged:
+		return colors.Success
+	case git.StatusUntracked:
+		return colors.Info
+	default:
+		return colors.Muted
+	}
+}
+
 // StylePreviewContent styles the preview content when the preview panel is focused
 func StylePreviewContent(content string, isFocused bool, viewportWidth int) string {
 	if !isFocused {
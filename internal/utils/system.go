@@ -161,3 +161,19 @@ func ParseSizeString(sizeStr string) (int64, error) {
 
 	return resultInt.Int64(), nil
 }
+
+// FormatSize converts a size in bytes into a human-readable string (e.g., "512 B", "1.5 KB").
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / unit
+	units := []string{"KB", "MB", "GB", "TB"}
+	i := 0
+	for value >= unit && i < len(units)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[i])
+}
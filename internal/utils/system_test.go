@@ -78,3 +78,24 @@ func TestParseSizeString(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatSize(t *testing.T) {
+	testCases := []struct {
+		expected string
+		input    int64
+	}{
+		{"0 B", 0},
+		{"512 B", 512},
+		{"1.0 KB", 1024},
+		{"1.5 KB", 1536},
+		{"2.0 MB", 2 * 1024 * 1024},
+		{"3.0 GB", 3 * 1024 * 1024 * 1024},
+		{"2048.0 TB", 2048 * 1024 * 1024 * 1024 * 1024},
+	}
+
+	for _, tc := range testCases {
+		if result := FormatSize(tc.input); result != tc.expected {
+			t.Errorf("FormatSize(%d) = %q, want %q", tc.input, result, tc.expected)
+		}
+	}
+}
@@ -11,3 +11,9 @@ func EstimateTokens(text string) int {
 	cleanText := strings.ReplaceAll(text, "\n", " ")
 	return len(cleanText) / 4
 }
+
+// EstimateTokensForSize estimates the number of tokens in a text of the given size in bytes.
+// It matches EstimateTokens, without having to read the text.
+func EstimateTokensForSize(size int64) int64 {
+	return size / 4
+}
@@ -0,0 +1,305 @@
+// Package codegrab bundles the files of a project into a single document for LLMs,
+// with the same filtering, dependency resolution and secret redaction as the grab CLI.
+//
+// A typical use walks a project, selects its files, adds their dependencies and renders
+// the selection:
+//
+//	g, err := codegrab.New(codegrab.Options{Root: ".", Globs: []string{"*.go", "!*_test.go"}})
+//	if err != nil {
+//		return err
+//	}
+//	sel, err := g.SelectAll(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	if _, err := g.ResolveDependencies(ctx, sel, 1); err != nil {
+//		return err
+//	}
+//	result, err := g.Render(ctx, os.Stdout, sel)
+//
+// Functions of this package return errors instead of exiting, and a Grabber is safe
+// for concurrent use.
+package codegrab
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/epilande/codegrab/internal/dependencies"
+	"github.com/epilande/codegrab/internal/filesystem"
+	"github.com/epilande/codegrab/internal/generator"
+	"github.com/epilande/codegrab/internal/generator/formats"
+	"github.com/epilande/codegrab/internal/project"
+	"github.com/epilande/codegrab/internal/symbols"
+	"github.com/epilande/codegrab/internal/utils"
+)
+
+// Options configure a Grabber. Every field but Root has a usable zero value.
+type Options struct {
+	// Root is the project directory
+	Root string
+	// Format is the name of a registered output format (default: "markdown")
+	Format string
+	// Globs include files, or exclude them when prefixed with "!" (e.g. "*.go", "!*_test.go")
+	Globs []string
+	// Scanners are the names of the registered secret scanners to use (default: "gitleaks")
+	Scanners []string
+	// MaxFileSize skips files larger than this many bytes (default: no limit)
+	MaxFileSize int64
+	// IncludeHidden includes hidden files and directories
+	IncludeHidden bool
+	// IncludeIgnored includes files ignored by .gitignore
+	IncludeIgnored bool
+	// SkipRedaction keeps the secrets found in the rendered output
+	SkipRedaction bool
+	// IncludeMetadata adds a header with the repository state and settings to the output
+	IncludeMetadata bool
+	// LineNumbers prefixes each line of file content with its line number
+	LineNumbers bool
+}
+
+// Grabber walks, selects and renders the files of a project
+type Grabber struct {
+	project *project.Project
+	opts    Options
+}
+
+// File is a file or directory of the project
+type File struct {
+	ModTime time.Time
+	// Path is relative to the project root, with forward slashes
+	Path  string
+	Size  int64
+	Level int
+	IsDir bool
+}
+
+// Dependency is a file that a selected file depends on
+type Dependency struct {
+	Path       string
+	RequiredBy string
+	Depth      int
+}
+
+// Skipped is a path that could not be selected, with the reason why
+type Skipped struct {
+	Path   string
+	Reason string
+}
+
+// FileTokens is the estimated number of tokens a file contributes to the output
+type FileTokens struct {
+	Path   string
+	Tokens int
+}
+
+// Result describes a rendered output
+type Result struct {
+	Files   []FileTokens
+	Tokens  int
+	Secrets int
+}
+
+// New returns a Grabber for the project at opts.Root
+func New(opts Options) (*Grabber, error) {
+	if opts.Root == "" {
+		return nil, fmt.Errorf("no project root given")
+	}
+	root, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %q: %w", opts.Root, err)
+	}
+	if stat, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("failed to access %q: %w", root, err)
+	} else if !stat.IsDir() {
+		return nil, fmt.Errorf("%q is not a directory", root)
+	}
+	opts.Root = root
+
+	if opts.Format == "" {
+		opts.Format = "markdown"
+	}
+	if !formats.IsRegistered(opts.Format) {
+		return nil, fmt.Errorf("unknown format %q", opts.Format)
+	}
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = math.MaxInt64
+	}
+
+	scanner, err := newScanner(opts.Scanners)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Grabber{
+		project: &project.Project{
+			Scanner:       scanner,
+			Root:          root,
+			ModuleName:    dependencies.ReadGoModFile(root),
+			MaxFileSize:   opts.MaxFileSize,
+			RedactSecrets: !opts.SkipRedaction,
+		},
+		opts: opts,
+	}, nil
+}
+
+// Root returns the absolute path of the project directory
+func (g *Grabber) Root() string {
+	return g.opts.Root
+}
+
+// Walk returns the files and directories of the project that pass the filters,
+// sorted by path
+func (g *Grabber) Walk(ctx context.Context) ([]File, error) {
+	items, err := g.project.Walk(ctx, g.filters())
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]File, 0, len(items))
+	for _, item := range items {
+		files = append(files, File{
+			ModTime: item.ModTime,
+			Path:    item.Path,
+			Size:    item.Size,
+			Level:   item.Level,
+			IsDir:   item.IsDir,
+		})
+	}
+	return files, nil
+}
+
+// SelectAll returns a selection of every file that passes the filters
+func (g *Grabber) SelectAll(ctx context.Context) (*Selection, error) {
+	files, err := g.Walk(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sel := NewSelection()
+	for _, f := range files {
+		if !f.IsDir {
+			sel.Add(f.Path)
+		}
+	}
+	return sel, nil
+}
+
+// Select returns a selection of the given files, relative to the project root or
+// absolute. As with the --files-from flag, an entry may be narrowed to line ranges
+// (path:120-180,200-210) or to a named symbol (path#FuncName), and paths that are
+// missing, outside of the project, directories, hidden, ignored or too large are
+// skipped, as are entries whose lines cannot be resolved.
+func (g *Grabber) Select(entries ...string) (*Selection, []Skipped, error) {
+	gitIgnoreMgr, err := filesystem.NewGitIgnoreManager(g.opts.Root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+
+	var skipped []Skipped
+	paths, lineRanges, skippedEntries := symbols.ResolveEntries(g.opts.Root, entries)
+	for _, s := range skippedEntries {
+		skipped = append(skipped, Skipped{Path: s.Entry, Reason: s.Reason})
+	}
+
+	accepted, skippedPaths, err := filesystem.ValidateFileList(context.Background(), g.opts.Root, paths, gitIgnoreMgr, !g.opts.IncludeIgnored, g.opts.IncludeHidden, g.opts.MaxFileSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, s := range skippedPaths {
+		skipped = append(skipped, Skipped{Path: s.Path, Reason: s.Reason})
+	}
+
+	sel := NewSelection(accepted...)
+	for _, path := range accepted {
+		if ranges, ok := lineRanges[path]; ok {
+			sel.lineRanges[path] = ranges
+		}
+	}
+	return sel, skipped, nil
+}
+
+// ResolveDependencies adds the project-local dependencies of the selected Go, JS/TS and
+// Python files to the selection, up to maxDepth levels (-1 for unlimited), and returns
+// the files it added. Dependencies that are hidden, ignored or too large are left out
+// as with Select, whatever the globs.
+func (g *Grabber) ResolveDependencies(ctx context.Context, sel *Selection, maxDepth int) ([]Dependency, error) {
+	resolved, err := g.project.Dependencies(ctx, sel.Paths(), maxDepth, g.filters())
+	if err != nil {
+		return nil, err
+	}
+
+	deps := make([]Dependency, 0, len(resolved))
+	for _, dep := range resolved {
+		sel.Add(dep.Path)
+		deps = append(deps, Dependency{Path: dep.Path, RequiredBy: dep.RequiredBy, Depth: dep.Depth})
+	}
+	return deps, nil
+}
+
+// Render writes the selected files to w in the configured format, with a directory
+// tree of the selection and its secrets redacted unless SkipRedaction is set. The
+// output is streamed one file at a time; if Render fails, w may hold part of it.
+func (g *Grabber) Render(ctx context.Context, w io.Writer, sel *Selection) (*Result, error) {
+	if sel.Len() == 0 {
+		return nil, fmt.Errorf("no files selected")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	gitIgnoreMgr, err := filesystem.NewGitIgnoreManager(g.opts.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+	filterMgr := filesystem.NewFilterManager()
+	for _, pattern := range g.opts.Globs {
+		if normalizedPattern, isValid := utils.NormalizeGlobPattern(pattern, g.opts.Root); isValid {
+			filterMgr.AddGlobPattern(normalizedPattern)
+		}
+	}
+
+	gen, err := generator.NewGenerator(g.opts.Root, gitIgnoreMgr, filterMgr, "", false)
+	if err != nil {
+		return nil, err
+	}
+	gen.SetFormatFunc(formats.Constructor(g.opts.Format))
+	gen.SetRedactionMode(!g.opts.SkipRedaction)
+	gen.SecretScanner = g.project.Scanner
+	gen.UseGitIgnore = !g.opts.IncludeIgnored
+	gen.ShowHidden = g.opts.IncludeHidden
+	gen.IncludeMetadata = g.opts.IncludeMetadata
+	gen.LineNumbers = g.opts.LineNumbers
+	gen.SelectedFiles = sel.toMap()
+	gen.LineRanges = sel.lineRangesMap()
+
+	tokens, secretCount, err := gen.Stream(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Tokens: tokens, Secrets: secretCount}
+	for _, count := range gen.FileTokenCounts() {
+		result.Files = append(result.Files, FileTokens{Path: count.Path, Tokens: count.Tokens})
+	}
+	return result, nil
+}
+
+func (g *Grabber) filters() project.Filters {
+	return project.Filters{
+		Glob:           g.opts.Globs,
+		IncludeHidden:  g.opts.IncludeHidden,
+		IncludeIgnored: g.opts.IncludeIgnored,
+	}
+}
+
+// EstimateTokens returns the estimated number of LLM tokens of text, as used for the
+// token counts of the output. Custom formats can use it to count their output.
+func EstimateTokens(text string) int {
+	return utils.EstimateTokens(text)
+}
@@ -0,0 +1,131 @@
+package codegrab
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/epilande/codegrab/internal/generator"
+	"github.com/epilande/codegrab/internal/generator/formats"
+	"github.com/epilande/codegrab/internal/git"
+	"github.com/epilande/codegrab/internal/secrets"
+)
+
+// Format writes the selected files into an output document. The output is streamed:
+// WriteHeader is called first, then WriteFile for each file in order, then WriteFooter.
+type Format = generator.Format
+
+// Header is what a Format writes before the files: the metadata, the directory tree of
+// the selection and the paths of the files that follow
+type Header = generator.Header
+
+// RenderFormat renders the whole output at once from a TemplateData. Render returns the
+// output and its estimated number of tokens. AdaptRenderFormat turns it into a Format.
+type RenderFormat = generator.RenderFormat
+
+// TemplateData is what a RenderFormat renders: the directory tree of the selection and the
+// content of each file, already redacted
+type TemplateData = generator.TemplateData
+
+// FileData is the content of a selected file
+type FileData = generator.FileData
+
+// Metadata describes the repository and settings the output was generated from
+type Metadata = generator.Metadata
+
+// MetadataField is a labelled metadata value
+type MetadataField = generator.MetadataField
+
+// Commit is a commit of the history of a file
+type Commit = git.Commit
+
+// Scanner finds secrets in file contents and redacts them
+type Scanner = secrets.Scanner
+
+// Finding is a secret found by a Scanner. Redaction replaces Secret, wherever it
+// appears within Match.
+type Finding = secrets.Finding
+
+// scannerRegistry maps scanner names to their constructors
+var scannerRegistry = map[string]func() (Scanner, error){
+	"gitleaks": func() (Scanner, error) {
+		scanner, err := secrets.NewGitleaksScanner()
+		if err != nil {
+			return nil, err
+		}
+		return scanner, nil
+	},
+}
+
+var scannerMu sync.RWMutex
+
+// AdaptRenderFormat returns a Format that renders with f, holding all the files in
+// memory until the output is rendered
+func AdaptRenderFormat(f RenderFormat) Format {
+	return generator.AdaptRenderFormat(f)
+}
+
+// RegisterFormat makes a format available by name, to Options.Format and to the
+// formats of the CLI. Formats are usually registered from an init function.
+func RegisterFormat(name string, newFormat func() Format) error {
+	return formats.Register(name, newFormat)
+}
+
+// FormatNames returns the names of the registered formats, sorted
+func FormatNames() []string {
+	return formats.GetFormatNames()
+}
+
+// RegisterScanner makes a secret scanner available by name, to Options.Scanners
+func RegisterScanner(name string, newScanner func() (Scanner, error)) error {
+	if name == "" || newScanner == nil {
+		return fmt.Errorf("a scanner needs a name and a constructor")
+	}
+
+	scannerMu.Lock()
+	defer scannerMu.Unlock()
+	if _, exists := scannerRegistry[name]; exists {
+		return fmt.Errorf("scanner %q is already registered", name)
+	}
+	scannerRegistry[name] = newScanner
+	return nil
+}
+
+// ScannerNames returns the names of the registered scanners, sorted
+func ScannerNames() []string {
+	scannerMu.RLock()
+	names := make([]string, 0, len(scannerRegistry))
+	for name := range scannerRegistry {
+		names = append(names, name)
+	}
+	scannerMu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// newScanner builds the named scanners, combining them if there are several
+func newScanner(names []string) (Scanner, error) {
+	if len(names) == 0 {
+		names = []string{"gitleaks"}
+	}
+
+	combined := make(secrets.MultiScanner, 0, len(names))
+	for _, name := range names {
+		scannerMu.RLock()
+		constructor, exists := scannerRegistry[name]
+		scannerMu.RUnlock()
+		if !exists {
+			return nil, fmt.Errorf("unknown scanner %q", name)
+		}
+		scanner, err := constructor()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize scanner %q: %w", name, err)
+		}
+		combined = append(combined, scanner)
+	}
+
+	if len(combined) == 1 {
+		return combined[0], nil
+	}
+	return combined, nil
+}
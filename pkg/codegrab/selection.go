@@ -0,0 +1,96 @@
+package codegrab
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/epilande/codegrab/internal/symbols"
+)
+
+// Selection is a set of files of a project, by path relative to its root, some of
+// which may be narrowed to line ranges by Grabber.Select. It is safe for concurrent use.
+type Selection struct {
+	paths      map[string]bool
+	lineRanges map[string][]symbols.LineRange
+	mu         sync.RWMutex
+}
+
+// NewSelection returns a selection of the given paths
+func NewSelection(paths ...string) *Selection {
+	sel := &Selection{paths: make(map[string]bool), lineRanges: make(map[string][]symbols.LineRange)}
+	sel.Add(paths...)
+	return sel
+}
+
+// Add adds whole files to the selection
+func (s *Selection) Add(paths ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, path := range paths {
+		s.paths[normalize(path)] = true
+		delete(s.lineRanges, normalize(path))
+	}
+}
+
+// Remove removes paths from the selection
+func (s *Selection) Remove(paths ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, path := range paths {
+		delete(s.paths, normalize(path))
+		delete(s.lineRanges, normalize(path))
+	}
+}
+
+// Contains reports whether a path is selected
+func (s *Selection) Contains(path string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.paths[normalize(path)]
+}
+
+// Len returns the number of selected files
+func (s *Selection) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.paths)
+}
+
+// Paths returns the selected paths, sorted
+func (s *Selection) Paths() []string {
+	s.mu.RLock()
+	paths := make([]string, 0, len(s.paths))
+	for path := range s.paths {
+		paths = append(paths, path)
+	}
+	s.mu.RUnlock()
+	sort.Strings(paths)
+	return paths
+}
+
+// toMap returns a copy of the selection in the form the generator expects
+func (s *Selection) toMap() map[string]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	copied := make(map[string]bool, len(s.paths))
+	for path := range s.paths {
+		copied[path] = true
+	}
+	return copied
+}
+
+// lineRangesMap returns a copy of the line ranges of the partially selected files
+func (s *Selection) lineRangesMap() map[string][]symbols.LineRange {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	copied := make(map[string][]symbols.LineRange, len(s.lineRanges))
+	for path, ranges := range s.lineRanges {
+		copied[path] = append([]symbols.LineRange(nil), ranges...)
+	}
+	return copied
+}
+
+func normalize(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}